@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"babytracker/internal/auth"
+	"babytracker/internal/config"
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// command is an administrative subcommand run instead of the server,
+// e.g. `api bootstrap-owner -name Mum`.
+type command struct {
+	summary string
+	run     func(cfg *config.Config, args []string) error
+}
+
+var commands = map[string]command{
+	"backup":          {"back up the data directory now", cmdBackup},
+	"backups":         {"list the data directory backups", cmdBackups},
+	"bootstrap-owner": {"create the first owner account and print its token", cmdBootstrapOwner},
+	"config":          {"show the effective settings, secrets redacted (config print)", cmdConfig},
+	"encrypt":         {"encrypt a plaintext data directory in place (run offline)", cmdEncrypt},
+	"export":          {"write every entry to a JSON bundle", cmdExport},
+	"fsck":            {"check the data files for damage (-repair to salvage them)", cmdFsck},
+	"import":          {"add the entries of a JSON bundle that are not here yet", cmdImport},
+	"migrate":         {"upgrade the data directory schema (-dry-run to preview)", cmdMigrate},
+	"rekey":           {"change the data directory passphrase", cmdRekey},
+	"restore":         {"replace the data files with a backup (run offline)", cmdRestore},
+}
+
+// runCommand dispatches a subcommand. Storage is already initialised, except for config.
+func runCommand(cfg *config.Config, name string, args []string) error {
+	cmd, ok := commands[name]
+	if !ok {
+		usage()
+		return fmt.Errorf("unknown command %q", name)
+	}
+	return cmd.run(cfg, args)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: api [flags] [command] [command flags]")
+	fmt.Fprintln(os.Stderr, "\nWith no command the API server starts. Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nFlags (override the environment and the config file):")
+	settingsFlags.PrintDefaults()
+}
+
+func cmdConfig(cfg *config.Config, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return fmt.Errorf("usage: api config print")
+	}
+	cfg.Print(os.Stdout)
+	return nil
+}
+
+func cmdBootstrapOwner(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("bootstrap-owner", flag.ContinueOnError)
+	name := fs.String("name", "", "owner name (also used as their caregiver name)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("-name is required")
+	}
+	if err := unlockStorage(cfg); err != nil {
+		return err
+	}
+	u, token, err := auth.BootstrapOwner(storage.WithActor(context.Background(), storage.Actor{Address: "cli"}), *name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created owner %q (ID %d) in %s\n", u.Name, u.ID, cfg.DataDir)
+	fmt.Println("API token (shown once, store it safely):")
+	fmt.Println(token)
+	return nil
+}
+
+func cmdEncrypt(cfg *config.Config, args []string) error {
+	var passphrase string
+	var err error
+	if storage.Encrypted() {
+		// Resuming an interrupted migration: reuse the existing passphrase
+		passphrase, err = currentPassphrase(cfg)
+	} else {
+		fmt.Printf("Encrypting %s. Stop the API server and desktop app first.\n", cfg.DataDir)
+		fmt.Println("There is no recovery without the passphrase — keep it somewhere safe.")
+		passphrase, err = newPassphrase()
+	}
+	if err != nil {
+		return err
+	}
+	n, err := storage.EncryptDataDir(passphrase)
+	if err != nil {
+		return fmt.Errorf("encryption stopped after %d files (rerun to resume): %w", n, err)
+	}
+	fmt.Printf("Encrypted %d files. Start the server with DATA_PASSPHRASE_FILE set or interactively.\n", n)
+	if backups, err := storage.ListBackups(); err == nil && len(backups) > 0 {
+		fmt.Printf("The %d backups in %s predate encryption and are still plaintext; delete them once you're happy with the encrypted data.\n",
+			len(backups), filepath.Join(cfg.DataDir, "backups"))
+	}
+	return nil
+}
+
+func cmdRekey(cfg *config.Config, args []string) error {
+	if !storage.Encrypted() {
+		return fmt.Errorf("%s is not encrypted; run the encrypt command first", cfg.DataDir)
+	}
+	current, err := promptPassphrase("Current passphrase: ")
+	if err != nil {
+		return err
+	}
+	next, err := newPassphrase()
+	if err != nil {
+		return err
+	}
+	if err := storage.Rekey(current, next); err != nil {
+		return err
+	}
+	fmt.Println("Passphrase changed. Update DATA_PASSPHRASE_FILE if you use one.")
+	return nil
+}
+
+func cmdExport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("o", "", "bundle file to write (default: standard output)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := unlockStorage(cfg); err != nil {
+		return err
+	}
+	logMigrations()
+	b, err := storage.Export()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+	if err := os.WriteFile(*out, data, 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d feeds, %d sleep, %d growth and %d diaper entries to %s\n",
+		len(b.Feeds), len(b.Sleep), len(b.Growth), len(b.Diapers), *out)
+	return nil
+}
+
+func cmdImport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: api import <bundle.json>")
+	}
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var b models.Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", fs.Arg(0), err)
+	}
+	if err := unlockStorage(cfg); err != nil {
+		return err
+	}
+	logMigrations()
+	res, err := storage.Import(storage.WithActor(context.Background(), storage.Actor{Address: "cli"}), &b)
+	if err != nil {
+		return fmt.Errorf("nothing was imported: %w", err)
+	}
+	for _, resource := range models.AllResources {
+		fmt.Printf("%-8s %d added, %d already here\n", resource, res.Added[resource], res.Skipped[resource])
+	}
+	return nil
+}
+
+func cmdMigrate(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would change without touching the data directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := unlockStorage(cfg); err != nil {
+		return err
+	}
+	results, err := storage.Migrate(*dryRun)
+	verb := "Migrated"
+	if *dryRun {
+		verb = "Would migrate"
+	}
+	for _, m := range results {
+		fmt.Printf("%s to schema %d (%s): %d records changed\n", verb, m.Version, m.Name, m.Changed)
+	}
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Printf("%s is already at schema %d\n", cfg.DataDir, storage.SchemaVersion())
+	} else if !*dryRun {
+		fmt.Printf("A copy of the data from before the migration is in %s\n", filepath.Join(cfg.DataDir, "backups"))
+	}
+	return nil
+}
+
+func cmdBackup(cfg *config.Config, args []string) error {
+	if err := unlockStorage(cfg); err != nil {
+		return err
+	}
+	b, err := storage.CreateBackup(models.BackupManual)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		fmt.Printf("%s has no data to back up yet\n", cfg.DataDir)
+		return nil
+	}
+	fmt.Printf("Backed up %d files to %s\n", len(b.Files), filepath.Join(cfg.DataDir, "backups", b.Name))
+	return nil
+}
+
+func cmdBackups(cfg *config.Config, args []string) error {
+	if err := unlockStorage(cfg); err != nil {
+		return err
+	}
+	backups, err := storage.ListBackups()
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Printf("No backups in %s\n", filepath.Join(cfg.DataDir, "backups"))
+		return nil
+	}
+	for _, b := range backups {
+		fmt.Printf("%-52s %-20s schema %d  %d files  %d bytes\n", b.Name, b.Label, b.Schema, len(b.Files), b.Size)
+	}
+	return nil
+}
+
+func cmdRestore(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: api restore <backup name> (see the backups command)")
+	}
+	if err := unlockStorage(cfg); err != nil {
+		return err
+	}
+	b, err := storage.RestoreBackup(filepath.Base(fs.Arg(0)))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d files from the %s backup taken %s.\n", len(b.Files), b.Label, b.Created.Local().Format("2006-01-02 15:04"))
+	fmt.Println("The data from before the restore was backed up first; see the backups command.")
+	logMigrations()
+	return nil
+}
+
+func cmdFsck(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	repair := fs.Bool("repair", false, "back up the data directory, then rewrite damaged files from what can be salvaged")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := unlockStorage(cfg); err != nil {
+		return err
+	}
+	report, err := storage.Fsck(*repair)
+	if err != nil {
+		return err
+	}
+	for _, f := range report.Files {
+		status := "ok"
+		if f.Repaired {
+			status = "repaired"
+		} else if len(f.Problems) > 0 {
+			status = "PROBLEMS"
+		}
+		fmt.Printf("%-22s %6d records  %s\n", f.File, f.Records, status)
+		for _, p := range f.Problems {
+			fmt.Printf("    %s\n", p)
+		}
+	}
+	if report.Backup != "" {
+		fmt.Printf("The files as they were before the repair are in %s\n", filepath.Join(cfg.DataDir, "backups", report.Backup))
+	}
+	if !report.Healthy() && !*repair {
+		return fmt.Errorf("problems found; rerun with -repair to salvage damaged files (values are reported, never changed)")
+	}
+	return nil
+}
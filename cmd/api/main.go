@@ -1,26 +1,85 @@
 package main
 
 import (
+	"flag"
 	"log"
-	"net/http"
+	"net"
+	"os"
 
 	"babytracker/internal/api"
 	"babytracker/internal/config"
+	"babytracker/internal/models"
 	"babytracker/internal/storage"
 )
 
+// settingsFlags are the flags before the command, overriding the environment and config file.
+var settingsFlags = flag.NewFlagSet("api", flag.ExitOnError)
+
 func main() {
-	cfg, err := config.Load()
+	flags := config.RegisterFlags(settingsFlags)
+	settingsFlags.Usage = usage
+	settingsFlags.Parse(os.Args[1:])
+	args := settingsFlags.Args()
+
+	cfg, err := config.LoadFlags(flags)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	if err := storage.Init(cfg.DataDir); err != nil {
+	models.SetLocation(cfg.Location)
+
+	// The config command only reports settings, so it leaves the data directory alone
+	if len(args) > 0 && args[0] == "config" {
+		if err := runCommand(cfg, args[0], args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// The migrate command runs migrations itself, so it can dry-run them; fsck
+	// must be able to open a directory whose damage would stop a migration
+	opts := storage.Options{ManualMigrations: len(args) > 0 && (args[0] == "migrate" || args[0] == "fsck")}
+	if err := storage.InitWithOptions(cfg.DataDir, opts); err != nil {
 		log.Fatalf("Failed to initialize storage at %s: %v", cfg.DataDir, err)
 	}
 
-	r := api.SetupRouter(cfg)
-	log.Printf("Baby Tracker API server running on http://localhost:%s", cfg.APIPort)
+	if len(args) > 0 {
+		if err := runCommand(cfg, args[0], args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		if err := storage.Close(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := unlockStorage(cfg); err != nil {
+		log.Fatalf("Failed to unlock data directory: %v", err)
+	}
+	logMigrations()
+
+	srv := api.NewServer(cfg)
 	log.Printf("Data directory: %s", cfg.DataDir)
-	log.Fatal(http.ListenAndServe(":"+cfg.APIPort, r))
+
+	certFile, keyFile, err := tlsFiles(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up TLS: %v", err)
+	}
+	if certFile == "" {
+		log.Printf("WARNING: serving plain HTTP; tokens cross the network unencrypted (set TLS_SELF_SIGNED=true or TLS_CERT_FILE/TLS_KEY_FILE)")
+		log.Printf("Baby Tracker API server running on http://%s", net.JoinHostPort(cfg.BindAddr, cfg.APIPort))
+	} else {
+		log.Printf("Baby Tracker API server running on https://%s", net.JoinHostPort(cfg.BindAddr, cfg.APIPort))
+	}
+	reload := func() (*config.Config, error) { return config.LoadFlags(flags) }
+	if err := runServer(cfg, srv, reload, certFile, keyFile); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// logMigrations reports the schema migrations storage ran on open or unlock.
+func logMigrations() {
+	for _, m := range storage.AppliedMigrations() {
+		log.Printf("Migrated data directory to schema %d (%s): %d records changed", m.Version, m.Name, m.Changed)
+	}
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"babytracker/internal/config"
+	"babytracker/internal/storage"
+)
+
+// unlockStorage opens an encrypted data directory, reading the passphrase from
+// DATA_PASSPHRASE_FILE or, when run interactively, from the terminal.
+func unlockStorage(cfg *config.Config) error {
+	if !storage.Locked() {
+		return nil
+	}
+	passphrase, err := currentPassphrase(cfg)
+	if err != nil {
+		return err
+	}
+	return storage.Unlock(passphrase)
+}
+
+func currentPassphrase(cfg *config.Config) (string, error) {
+	if cfg.PassphraseFile != "" {
+		data, err := os.ReadFile(cfg.PassphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read DATA_PASSPHRASE_FILE: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errors.New("data directory is encrypted: set DATA_PASSPHRASE_FILE or run interactively")
+	}
+	return promptPassphrase("Passphrase: ")
+}
+
+// promptPassphrase reads a passphrase from the terminal without echoing it.
+func promptPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(b), nil
+}
+
+// newPassphrase prompts twice for a new passphrase.
+func newPassphrase() (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errors.New("a new passphrase can only be entered interactively")
+	}
+	first, err := promptPassphrase("New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if len(first) < 8 {
+		return "", errors.New("passphrase must be at least 8 characters")
+	}
+	second, err := promptPassphrase("Repeat new passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if first != second {
+		return "", errors.New("passphrases do not match")
+	}
+	return first, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"babytracker/internal/api"
+	"babytracker/internal/config"
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// runServer serves until SIGINT/SIGTERM, then drains in-flight requests for up to
+// ShutdownTimeout and closes storage so no write is cut off between its temp-file
+// write and rename (FINDING-31). Meanwhile SIGHUP or an edit of the config file
+// reloads the settings through load.
+func runServer(cfg *config.Config, handler *api.Server, load func() (*config.Config, error), certFile, keyFile string) error {
+	srv := &http.Server{
+		Addr:              net.JoinHostPort(cfg.BindAddr, cfg.APIPort),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go purgeExpired(ctx, cfg.TrashRetention(), cfg.IdempotencyRetention())
+	go backUpOnSchedule(ctx, cfg.BackupInterval, cfg.BackupPolicy())
+	go watchConfig(ctx, handler, load, cfg.File)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if certFile != "" {
+			serveErr <- srv.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	case <-ctx.Done():
+		stop() // a second signal kills the process immediately
+		log.Printf("Shutting down, waiting up to %s for in-flight requests...", cfg.ShutdownTimeout)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	shutdownErr := srv.Shutdown(shutdownCtx)
+	if shutdownErr != nil {
+		log.Printf("Shutdown did not complete cleanly: %v", shutdownErr)
+	}
+	// Handlers still running after the timeout hold the storage lock; Close waits for them
+	if err := storage.Close(); err != nil {
+		return err
+	}
+	log.Println("Baby Tracker API server stopped.")
+	return nil
+}
+
+// purgeExpired removes trashed entries and idempotency keys that have outlived
+// their retention, at startup and then hourly until ctx is cancelled. A zero
+// retention keeps that kind forever.
+func purgeExpired(ctx context.Context, trashRetention, keyRetention time.Duration) {
+	if trashRetention <= 0 && keyRetention <= 0 {
+		return
+	}
+	purgeCtx := storage.WithActor(context.Background(), storage.Actor{Address: "trash-retention"})
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if n, err := storage.PurgeTrash(purgeCtx, trashRetention); err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d entries deleted more than %s ago", n, trashRetention)
+		}
+		if n, err := storage.PurgeIdempotencyKeys(keyRetention); err != nil {
+			log.Printf("Failed to purge idempotency keys: %v", err)
+		} else if n > 0 {
+			log.Printf("Forgot %d idempotency keys older than %s", n, keyRetention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// backUpOnSchedule backs up the data directory at startup and then every
+// interval until ctx is cancelled, deleting the scheduled backups policy no
+// longer keeps. A zero interval turns scheduled backups off.
+func backUpOnSchedule(ctx context.Context, interval time.Duration, policy models.BackupPolicy) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if b, err := storage.CreateBackup(models.BackupScheduled); err != nil {
+			log.Printf("Scheduled backup failed: %v", err)
+		} else if b != nil {
+			log.Printf("Backed up %d files to %s", len(b.Files), b.Name)
+		}
+		if removed, err := storage.PruneBackups(policy); err != nil {
+			log.Printf("Failed to prune backups: %v", err)
+		} else if len(removed) > 0 {
+			log.Printf("Deleted %d scheduled backups past their retention", len(removed))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reloadDelay lets an editor finish writing the config file, which can take
+// several events, before it is read.
+const reloadDelay = 250 * time.Millisecond
+
+// watchConfig reloads the settings into srv on SIGHUP and, when there is a
+// config file, whenever it changes, until ctx is cancelled. The directory is
+// watched rather than the file, so editors that save by renaming a new file
+// over it are seen too.
+func watchConfig(ctx context.Context, srv *api.Server, load func() (*config.Config, error), file string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events chan fsnotify.Event
+	var watchErrs chan error
+	if file != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err == nil {
+			err = watcher.Add(filepath.Dir(file))
+		}
+		if err != nil {
+			log.Printf("Not watching %s for changes (reload with SIGHUP instead): %v", file, err)
+		} else {
+			defer watcher.Close()
+			events, watchErrs = watcher.Events, watcher.Errors
+		}
+	}
+
+	debounce := time.NewTimer(reloadDelay)
+	debounce.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Println("SIGHUP received, reloading config")
+			reloadConfig(srv, load)
+		case ev := <-events:
+			if filepath.Clean(ev.Name) == filepath.Clean(file) && !ev.Has(fsnotify.Chmod) {
+				debounce.Reset(reloadDelay)
+			}
+		case err := <-watchErrs:
+			log.Printf("Config file watch error: %v", err)
+		case <-debounce.C:
+			log.Printf("%s changed, reloading config", file)
+			reloadConfig(srv, load)
+		}
+	}
+}
+
+// reloadConfig loads the settings again and applies them to srv, logging each
+// change. Settings that fail to load or need a restart leave srv as it was.
+func reloadConfig(srv *api.Server, load func() (*config.Config, error)) {
+	next, err := load()
+	if err != nil {
+		log.Printf("Config reload failed, keeping the current settings: %v", err)
+		return
+	}
+	changes, err := srv.Reload(next)
+	if err != nil {
+		log.Printf("Config reload rejected: %v", err)
+		for _, c := range changes {
+			if !c.Reloadable {
+				log.Printf("  needs a restart: %s", c)
+			}
+		}
+		return
+	}
+	if len(changes) == 0 {
+		log.Println("Config reloaded, nothing changed")
+		return
+	}
+	for _, c := range changes {
+		log.Printf("Config reloaded: %s", c)
+	}
+}
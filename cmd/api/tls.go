@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+
+	"babytracker/internal/certs"
+	"babytracker/internal/config"
+)
+
+// tlsFiles returns the certificate and key to serve, generating a self-signed
+// pair when requested. Empty paths mean plain HTTP.
+func tlsFiles(cfg *config.Config) (certFile, keyFile string, err error) {
+	switch {
+	case cfg.TLSCertFile != "":
+		certFile, keyFile = cfg.TLSCertFile, cfg.TLSKeyFile
+	case cfg.TLSSelfSigned:
+		certFile, keyFile, err = certs.EnsureSelfSigned(cfg.TLSDir(), cfg.TLSHosts)
+		if err != nil {
+			return "", "", err
+		}
+		caFile := filepath.Join(cfg.TLSDir(), certs.CAFile)
+		caPrint, err := certs.Fingerprint(caFile)
+		if err != nil {
+			return "", "", err
+		}
+		log.Printf("Self-signed CA: %s (install on phones to trust the server)", caFile)
+		log.Printf("CA SHA-256 fingerprint: %s", caPrint)
+	default:
+		return "", "", nil
+	}
+	fingerprint, err := certs.Fingerprint(certFile)
+	if err != nil {
+		return "", "", err
+	}
+	log.Printf("Server certificate SHA-256 fingerprint: %s", fingerprint)
+	return certFile, keyFile, nil
+}
@@ -16,6 +16,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"babytracker/internal/models"
@@ -46,35 +47,61 @@ func main() {
 	growths := genGrowth(r, start)
 	diapers := genDiapers(r, start)
 
-	writeJSON("feeds.json", feeds)
-	writeJSON("sleep.json", sleeps)
-	writeJSON("growth.json", growths)
-	writeJSON("diapers.json", diapers)
+	writeEntries(models.ResourceFeeds, feeds, func(e models.FeedEntry) (int, string) { return e.ID, e.Date })
+	writeEntries(models.ResourceSleep, sleeps, func(e models.SleepEntry) (int, string) { return e.ID, e.Date })
+	writeEntries(models.ResourceGrowth, growths, func(e models.GrowthEntry) (int, string) { return e.ID, e.Date })
+	writeEntries(models.ResourceDiapers, diapers, func(e models.DiaperEntry) (int, string) { return e.ID, e.Date })
 
 	fmt.Printf("\n✅ Generated %d feeds, %d sleep, %d growth, %d diapers\n", len(feeds), len(sleeps), len(growths), len(diapers))
 	fmt.Println("   Run `make bench-restore` to restore original data")
 }
 
+// backup moves each resource's month directory aside, so the bench data
+// replaces it rather than mixing with it.
 func backup() error {
 	os.MkdirAll(backupDir, 0700)
-	files := []string{"feeds.json", "sleep.json", "growth.json", "diapers.json"}
-	for _, f := range files {
-		src := filepath.Join(dataDir, f)
-		dst := filepath.Join(backupDir, f)
-		data, err := os.ReadFile(src)
-		if err != nil {
-			if os.IsNotExist(err) {
-				continue
-			}
+	for _, resource := range models.AllResources {
+		src := filepath.Join(dataDir, resource)
+		dst := filepath.Join(backupDir, resource)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.RemoveAll(dst); err != nil {
 			return err
 		}
-		if err := os.WriteFile(dst, data, 0600); err != nil {
+		if err := os.Rename(src, dst); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// writeEntries writes entries the way storage keeps them: one file per month
+// of their date under the resource's directory, newest first, plus index.json mapping each
+// ID to its month and holding the next ID (the entries have no UIDs yet).
+func writeEntries[T any](resource string, entries []T, key func(T) (int, string)) {
+	if err := os.MkdirAll(filepath.Join(dataDir, resource), 0700); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Create %s: %v\n", resource, err)
+		os.Exit(1)
+	}
+	months := map[string][]T{}
+	index := struct {
+		NextID int            `json:"next_id"`
+		Months map[int]string `json:"months"`
+		UIDs   map[string]int `json:"uids"`
+	}{NextID: 1, Months: map[int]string{}, UIDs: map[string]int{}}
+	for _, e := range slices.Backward(entries) {
+		id, date := key(e)
+		months[date[:7]] = append(months[date[:7]], e)
+		index.Months[id] = date[:7]
+		index.NextID = max(index.NextID, id+1)
+	}
+	for month, items := range months {
+		writeJSON(filepath.Join(resource, month+".json"), items)
+	}
+	writeJSON(filepath.Join(resource, "index.json"), index)
+}
+
 func writeJSON(name string, v any) {
 	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
@@ -102,7 +129,7 @@ func genFeeds(r *rand.Rand, start time.Time) []models.FeedEntry {
 		ft := feedTypes[r.Intn(len(feedTypes))]
 		var qty float64
 		if ft == "Bottle" {
-			qty = float64(60+r.Intn(140)) // 60-200ml
+			qty = float64(60 + r.Intn(140)) // 60-200ml
 		}
 		var dur int
 		if ft != "Bottle" && ft != "Solid Food" {
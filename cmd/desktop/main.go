@@ -1,29 +1,38 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	"babytracker/internal/config"
 	"babytracker/internal/desktop"
+	"babytracker/internal/models"
 	"babytracker/internal/storage"
 )
 
 func main() {
-	cfg, err := config.Load()
+	flags := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	cfg, err := config.LoadFlags(flags)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	models.SetLocation(cfg.Location)
+
 	if err := storage.Init(cfg.DataDir); err != nil {
 		log.Fatalf("Failed to initialize storage at %s: %v", cfg.DataDir, err)
 	}
 
-	app := desktop.NewApp()
+	app := desktop.NewApp(cfg)
 	if app == nil {
 		log.Fatal("Failed to initialize Baby Tracker application")
 	}
 
 	log.Printf("Starting Baby Tracker (data: %s)", cfg.DataDir)
 	app.Run()
+	if err := storage.Close(); err != nil {
+		log.Printf("Failed to close storage: %v", err)
+	}
 	log.Println("Baby Tracker closed.")
 }
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// auditResources are the values accepted by ?resource= on the audit log.
+var auditResources = append(slices.Clone(models.AllResources),
+	models.ResourceCaregivers, models.ResourceUsers, models.ResourceTokens)
+
+// auditView is an audit entry as returned by the API; updates also list the changed fields.
+type auditView struct {
+	models.AuditEntry
+	Changes []models.FieldChange `json:"changes,omitempty"`
+}
+
+// parseAuditFilter reads the audit log filters: ?resource=, ?id=, ?actor=, ?action=
+// and the inclusive ?from= / ?to= days (YYYY-MM-DD, household time zone).
+func parseAuditFilter(r *http.Request) (models.AuditFilter, error) {
+	q := r.URL.Query()
+	f := models.AuditFilter{Resource: q.Get("resource"), Actor: q.Get("actor"), Action: q.Get("action")}
+	if f.Resource != "" && !slices.Contains(auditResources, f.Resource) {
+		return f, fmt.Errorf("unknown resource %q", f.Resource)
+	}
+	switch f.Action {
+	case "", models.AuditCreate, models.AuditUpdate, models.AuditDelete, models.AuditRestore, models.AuditPurge:
+	default:
+		return f, fmt.Errorf("unknown action %q (expected create, update, delete, restore or purge)", f.Action)
+	}
+	if v := q.Get("id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return f, fmt.Errorf("invalid id %q", v)
+		}
+		f.EntityID = id
+	}
+	if v := q.Get("from"); v != "" {
+		start, _, err := models.DayBounds(v)
+		if err != nil {
+			return f, err
+		}
+		f.Since = start
+	}
+	if v := q.Get("to"); v != "" {
+		_, end, err := models.DayBounds(v)
+		if err != nil {
+			return f, err
+		}
+		f.Until = end
+	}
+	return f, nil
+}
+
+// handleListAudit returns audit log entries (newest-first, paginated).
+func handleListAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	entries, err := storage.LoadAudit(filter)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	limit, offset := parsePagination(r)
+	page, total := paginateReverse(entries, limit, offset)
+	items := make([]auditView, len(page))
+	for i, e := range page {
+		items[i] = auditView{AuditEntry: e}
+		if e.Action == models.AuditUpdate {
+			items[i].Changes = e.Changes()
+		}
+	}
+	jsonResponse(w, http.StatusOK, PaginatedResponse{Items: items, Total: total, Limit: limit, Offset: offset})
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// handleListBackups lists the data directory backups, newest first.
+func handleListBackups(w http.ResponseWriter, r *http.Request) {
+	backups, err := storage.ListBackups()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	jsonResponse(w, http.StatusOK, backups)
+}
+
+// handleCreateBackup takes a manual backup, which the retention policy never deletes.
+func handleCreateBackup(w http.ResponseWriter, r *http.Request) {
+	b, err := storage.CreateBackup(models.BackupManual)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if b == nil {
+		jsonResponse(w, http.StatusConflict, map[string]string{"error": "there is no data to back up yet"})
+		return
+	}
+	log.Printf("Backup: %s (%d files)\n", b.Name, len(b.Files))
+	jsonResponse(w, http.StatusCreated, b)
+}
+
+// handleRestoreBackup replaces the data files with a verified backup, after
+// backing up the current ones.
+func handleRestoreBackup(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	b, err := storage.RestoreBackup(name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, storage.ErrBackupNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, storage.ErrBadBackup), errors.Is(err, storage.ErrBackupKeyMismatch), errors.Is(err, storage.ErrSchemaTooNew):
+			status = http.StatusUnprocessableEntity
+		}
+		jsonResponse(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("Restore: %s by %s\n", name, caregiverFrom(r))
+	jsonResponse(w, http.StatusOK, b)
+}
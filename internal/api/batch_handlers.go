@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// maxBatchOps caps the operations in one POST /api/batch.
+const maxBatchOps = 500
+
+// batchOperation is one write in a POST /api/batch request.
+type batchOperation struct {
+	Key       string          `json:"key,omitempty"`        // Client-generated idempotency key
+	Op        string          `json:"op"`                   // create, update or delete
+	Resource  string          `json:"resource"`             // feeds, sleep, growth or diapers
+	ID        int             `json:"id,omitempty"`         // Entry to update or delete
+	Ref       string          `json:"ref,omitempty"`        // Or: the key of an earlier create
+	IfVersion int             `json:"if_version,omitempty"` // Optional, like If-Match
+	Entry     json.RawMessage `json:"entry,omitempty"`      // The entry, for create and update
+}
+
+// batchResult is the outcome of one operation, in request order.
+type batchResult struct {
+	Key      string `json:"key,omitempty"`
+	Op       string `json:"op"`
+	Resource string `json:"resource"`
+	ID       int    `json:"id"`                 // Server-assigned for creates
+	Version  int    `json:"version,omitempty"`  // Entry version after a create or update
+	Replayed bool   `json:"replayed,omitempty"` // The key was applied before; nothing was written
+}
+
+// batchFailure is returned when a batch is rejected; nothing in it was applied.
+type batchFailure struct {
+	Error string `json:"error"`
+	Index int    `json:"index"` // The operation that failed
+}
+
+// handleBatch applies an ordered list of creates, updates and deletes across
+// resources, all or nothing. Operations carrying a key that was applied before
+// are skipped, so an offline queue can be replayed until it gets a response.
+func handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Operations []batchOperation `json:"operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOps {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("a batch holds 1 to %d operations", maxBatchOps)})
+		return
+	}
+	ops := make([]storage.BatchOp, len(req.Operations))
+	for i, o := range req.Operations {
+		op, err := batchOp(o, caregiverFrom(r), idempotencyActor(r))
+		if err != nil {
+			jsonResponse(w, http.StatusBadRequest, batchFailure{Error: err.Error(), Index: i})
+			return
+		}
+		ops[i] = op
+	}
+	log.Printf("Batch of %d operations\n", len(ops))
+
+	applied, err := storage.ApplyBatch(r.Context(), ops)
+	var batchErr *storage.BatchError
+	if errors.As(err, &batchErr) {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, storage.ErrVersionMismatch):
+			status = http.StatusPreconditionFailed
+		case errors.Is(err, storage.ErrKeyReused):
+			status = http.StatusConflict
+		case errors.Is(err, storage.ErrUnknownRef):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, storage.ErrInvalidUID):
+			status = http.StatusBadRequest
+		case errors.Is(err, storage.ErrDuplicateUID):
+			status = http.StatusConflict
+		}
+		jsonResponse(w, status, batchFailure{Error: batchErr.Err.Error(), Index: batchErr.Index})
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	results := make([]batchResult, len(ops))
+	for i, res := range applied {
+		o := req.Operations[i]
+		results[i] = batchResult{Key: o.Key, Op: o.Op, Resource: o.Resource, ID: res.ID, Replayed: res.Replayed}
+		if !res.Replayed {
+			results[i].Version = entryVersion(ops[i].Entry)
+		}
+	}
+	jsonResponse(w, http.StatusOK, map[string][]batchResult{"results": results})
+}
+
+// batchOp checks one requested operation and decodes and validates its entry.
+// actor scopes its key and ref, as it does an Idempotency-Key.
+func batchOp(o batchOperation, loggedBy, actor string) (storage.BatchOp, error) {
+	op := storage.BatchOp{Key: o.Key, Actor: actor, Action: o.Op, Resource: o.Resource, ID: o.ID, Ref: o.Ref, IfVersion: o.IfVersion}
+	if !slices.Contains(models.AllResources, o.Resource) {
+		return op, fmt.Errorf("unknown resource %q", o.Resource)
+	}
+	switch o.Op {
+	case models.AuditCreate:
+		if o.ID != 0 || o.Ref != "" {
+			return op, errors.New("create takes no id or ref; the server assigns the ID")
+		}
+	case models.AuditUpdate, models.AuditDelete:
+		if (o.ID == 0) == (o.Ref == "") {
+			return op, fmt.Errorf("%s needs exactly one of id or ref", o.Op)
+		}
+	default:
+		return op, fmt.Errorf("unknown op %q (expected create, update or delete)", o.Op)
+	}
+	if o.Op == models.AuditDelete {
+		return op, nil
+	}
+	entry, err := decodeEntry(o.Resource, o.Entry)
+	if err != nil {
+		return op, err
+	}
+	setLoggedBy(entry, loggedBy)
+	op.Entry = entry
+	return op, nil
+}
+
+// decodeEntry decodes and validates an entry of the given resource, as POST and PUT do.
+func decodeEntry(resource string, raw json.RawMessage) (any, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("missing entry")
+	}
+	switch resource {
+	case models.ResourceFeeds:
+		var e models.FeedEntry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, errors.New("invalid JSON")
+		}
+		return &e, validateFeed(&e)
+	case models.ResourceSleep:
+		var e models.SleepEntry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, errors.New("invalid JSON")
+		}
+		return &e, validateSleep(&e)
+	case models.ResourceGrowth:
+		var e models.GrowthEntry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, errors.New("invalid JSON")
+		}
+		return &e, validateGrowth(&e)
+	case models.ResourceDiapers:
+		var e models.DiaperEntry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, errors.New("invalid JSON")
+		}
+		return &e, validateDiaper(&e)
+	}
+	return nil, fmt.Errorf("unknown resource %q", resource)
+}
+
+// setLoggedBy attributes a new entry to the caregiver making the request.
+// Updates keep the original attribution regardless.
+func setLoggedBy(entry any, name string) {
+	switch e := entry.(type) {
+	case *models.FeedEntry:
+		e.LoggedBy = name
+	case *models.SleepEntry:
+		e.LoggedBy = name
+	case *models.GrowthEntry:
+		e.LoggedBy = name
+	case *models.DiaperEntry:
+		e.LoggedBy = name
+	}
+}
+
+// entryVersion returns the version storage assigned to an entry pointer.
+func entryVersion(entry any) int {
+	switch e := entry.(type) {
+	case *models.FeedEntry:
+		return e.Version
+	case *models.SleepEntry:
+		return e.Version
+	case *models.GrowthEntry:
+		return e.Version
+	case *models.DiaperEntry:
+		return e.Version
+	}
+	return 0
+}
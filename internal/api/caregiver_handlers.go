@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+func handleListCaregivers(w http.ResponseWriter, r *http.Request) {
+	entries, err := storage.LoadCaregivers()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	jsonResponse(w, http.StatusOK, entries)
+}
+
+func handleAddCaregiver(w http.ResponseWriter, r *http.Request) {
+	var c models.Caregiver
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "missing required field (name)"})
+		return
+	}
+	log.Printf("Add Caregiver: %s\n", c.Name)
+	if err := storage.SaveCaregiver(r.Context(), &c); err != nil {
+		jsonResponse(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	jsonResponse(w, http.StatusCreated, c)
+}
+
+func handleDeleteCaregiver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+		return
+	}
+	log.Printf("Delete Caregiver ID %d\n", id)
+	if err := storage.DeleteCaregiver(r.Context(), id); err != nil {
+		jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	jsonResponse(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
@@ -2,25 +2,20 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"strconv"
-
-	"github.com/gorilla/mux"
 
 	"babytracker/internal/models"
 	"babytracker/internal/storage"
 )
 
 func handleListDiapers(w http.ResponseWriter, r *http.Request) {
-	entries, err := storage.LoadDiapers()
-	if err != nil {
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	page, total, q, ok := listEntries(w, r, models.ResourceDiapers, func(e models.DiaperEntry) (string, string) { return e.Date, e.LoggedBy })
+	if !ok {
 		return
 	}
-	limit, offset := parsePagination(r)
-	page, total := paginateReverse(entries, limit, offset)
-	jsonResponse(w, http.StatusOK, PaginatedResponse{Items: page, Total: total, Limit: limit, Offset: offset})
+	conditionalJSON(w, r, PaginatedResponse{Items: page, Total: total, Limit: q.Limit, Offset: q.Offset})
 }
 
 func handleLogDiaper(w http.ResponseWriter, r *http.Request) {
@@ -29,42 +24,45 @@ func handleLogDiaper(w http.ResponseWriter, r *http.Request) {
 		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
 		return
 	}
-	if entry.Date == "" || entry.Type == "" {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "missing required fields (date, type)"})
+	if err := validateDiaper(&entry); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
+	entry.LoggedBy = caregiverFrom(r)
 	log.Printf("Log Diaper: %+v\n", entry)
-	if err := storage.SaveDiaper(&entry); err != nil {
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := storage.SaveDiaper(r.Context(), &entry); err != nil {
+		writeCreateError(w, err)
 		return
 	}
 	jsonResponse(w, http.StatusCreated, entry)
 }
 
 func handleGetDiaper(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
-	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+	id, ok := entryID(w, r, models.ResourceDiapers)
+	if !ok {
 		return
 	}
-	entries, err := storage.LoadDiapers()
+	e, found, err := storage.FindEntry[models.DiaperEntry](models.ResourceDiapers, id)
 	if err != nil {
 		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	for _, e := range entries {
-		if e.ID == id {
-			jsonResponse(w, http.StatusOK, e)
-			return
-		}
+	if !found {
+		jsonResponse(w, http.StatusNotFound, map[string]string{"error": "diaper entry not found"})
+		return
 	}
-	jsonResponse(w, http.StatusNotFound, map[string]string{"error": "diaper entry not found"})
+	w.Header().Set("ETag", entityTag(e.Version))
+	jsonResponse(w, http.StatusOK, e)
 }
 
 func handleUpdateDiaper(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	id, ok := entryID(w, r, models.ResourceDiapers)
+	if !ok {
+		return
+	}
+	ifVersion, err := parseIfMatch(r)
 	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	var entry models.DiaperEntry
@@ -72,29 +70,53 @@ func handleUpdateDiaper(w http.ResponseWriter, r *http.Request) {
 		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
 		return
 	}
-	if entry.Date == "" || entry.Type == "" {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "missing required fields (date, type)"})
+	if err := validateDiaper(&entry); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	log.Printf("Update Diaper ID %d: %+v\n", id, entry)
-	if err := storage.UpdateDiaper(id, &entry); err != nil {
-		jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	if err := storage.UpdateDiaper(r.Context(), id, ifVersion, &entry); err != nil {
+		writeChangeError(w, err)
 		return
 	}
 	entry.ID = id
+	w.Header().Set("ETag", entityTag(entry.Version))
+	jsonResponse(w, http.StatusOK, entry)
+}
+
+func handlePatchDiaper(w http.ResponseWriter, r *http.Request) {
+	entry, ok := patchEntry(w, r, models.ResourceDiapers, nil,
+		func(e models.DiaperEntry) (int, int) { return e.ID, e.Version }, validateDiaper, storage.UpdateDiaper)
+	if !ok {
+		return
+	}
+	w.Header().Set("ETag", entityTag(entry.Version))
 	jsonResponse(w, http.StatusOK, entry)
 }
 
 func handleDeleteDiaper(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	id, ok := entryID(w, r, models.ResourceDiapers)
+	if !ok {
+		return
+	}
+	ifVersion, err := parseIfMatch(r)
 	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	log.Printf("Delete Diaper ID %d\n", id)
-	if err := storage.DeleteDiaper(id); err != nil {
-		jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	if err := storage.DeleteDiaper(r.Context(), id, ifVersion); err != nil {
+		writeChangeError(w, err)
 		return
 	}
-	jsonResponse(w, http.StatusOK, map[string]string{"status": "deleted"})
+	jsonResponse(w, http.StatusOK, deletedResponse(models.ResourceDiapers, id))
+}
+
+// validateDiaper normalizes a diaper entry from a request and checks its required fields.
+func validateDiaper(entry *models.DiaperEntry) error {
+	entry.SyncDate()
+	if entry.Date == "" || entry.Type == "" {
+		return errors.New("missing required fields (date, type)")
+	}
+	return nil
 }
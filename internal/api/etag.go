@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// entityTag is the ETag of an entry: its version, quoted.
+func entityTag(version int) string {
+	return strconv.Quote(strconv.Itoa(models.EntryVersion(version)))
+}
+
+// parseIfMatch reads the If-Match header of a PUT or DELETE as the entry version
+// the client last saw. Returns 0 (no precondition) when the header is absent or "*".
+func parseIfMatch(r *http.Request) (int, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+	tag, err := strconv.Unquote(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid If-Match %s (expected a single ETag such as \"3\")", v)
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid If-Match %s (expected a single ETag such as \"3\")", v)
+	}
+	return version, nil
+}
+
+// writeChangeError reports a failed update or delete: 412 when the If-Match
+// version is stale, 404 otherwise.
+func writeChangeError(w http.ResponseWriter, err error) {
+	status := http.StatusNotFound
+	if errors.Is(err, storage.ErrVersionMismatch) {
+		status = http.StatusPreconditionFailed
+	}
+	jsonResponse(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeCreateError reports a failed create: 400 or 409 for an unusable client
+// UID, 500 otherwise.
+func writeCreateError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, storage.ErrInvalidUID):
+		status = http.StatusBadRequest
+	case errors.Is(err, storage.ErrDuplicateUID):
+		status = http.StatusConflict
+	}
+	jsonResponse(w, status, map[string]string{"error": err.Error()})
+}
+
+// conditionalJSON writes a 200 JSON response tagged with a hash of its body, or
+// 304 Not Modified when the request's If-None-Match already names that tag.
+func conditionalJSON(w http.ResponseWriter, r *http.Request, payload interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	sum := sha256.Sum256(body.Bytes())
+	etag := strconv.Quote(hex.EncodeToString(sum[:16]))
+	w.Header().Set("ETag", etag)
+	if noneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body.Bytes())
+}
+
+// noneMatch reports whether an If-None-Match header lists etag (weak comparison).
+func noneMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
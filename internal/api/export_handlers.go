@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// handleExport downloads every entry as a bundle that POST /api/import (or the
+// import command) can load into another data directory.
+func handleExport(w http.ResponseWriter, r *http.Request) {
+	b, err := storage.Export()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="babytracker-`+models.Now().Format("2006-01-02")+`.json"`)
+	jsonResponse(w, http.StatusOK, b)
+}
+
+// handleImport adds the entries of an exported bundle that are not here yet,
+// matched by UID. Nothing is imported if any entry fails.
+func handleImport(w http.ResponseWriter, r *http.Request) {
+	var b models.Bundle
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	res, err := storage.Import(r.Context(), &b)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrUnsupportedBundle) || errors.Is(err, storage.ErrInvalidUID) {
+			status = http.StatusBadRequest
+		}
+		jsonResponse(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("Import: added %v, skipped %v\n", res.Added, res.Skipped)
+	jsonResponse(w, http.StatusOK, res)
+}
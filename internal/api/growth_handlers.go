@@ -2,69 +2,90 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"strconv"
-
-	"github.com/gorilla/mux"
 
 	"babytracker/internal/models"
 	"babytracker/internal/storage"
 )
 
 func handleListGrowth(w http.ResponseWriter, r *http.Request) {
-	entries, err := storage.LoadGrowth()
+	units, err := parseUnits(r)
 	if err != nil {
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	page, total, q, ok := listEntries(w, r, models.ResourceGrowth, func(e models.GrowthEntry) (string, string) { return e.Date, e.LoggedBy })
+	if !ok {
 		return
 	}
-	limit, offset := parsePagination(r)
-	page, total := paginateReverse(entries, limit, offset)
-	jsonResponse(w, http.StatusOK, PaginatedResponse{Items: page, Total: total, Limit: limit, Offset: offset})
+	for i := range page {
+		page[i] = page[i].InUnits(units)
+	}
+	conditionalJSON(w, r, PaginatedResponse{Items: page, Total: total, Limit: q.Limit, Offset: q.Offset})
 }
 
 func handleLogGrowth(w http.ResponseWriter, r *http.Request) {
+	units, err := parseUnits(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
 	var entry models.GrowthEntry
 	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
 		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
 		return
 	}
-	if entry.Date == "" {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "missing required field (date)"})
+	if err := validateGrowth(&entry); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
+	entry.LoggedBy = caregiverFrom(r)
 	log.Printf("Log Growth: %+v\n", entry)
-	if err := storage.SaveGrowth(&entry); err != nil {
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := storage.SaveGrowth(r.Context(), &entry); err != nil {
+		writeCreateError(w, err)
 		return
 	}
-	jsonResponse(w, http.StatusCreated, entry)
+	jsonResponse(w, http.StatusCreated, entry.InUnits(units))
 }
 
 func handleGetGrowth(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	id, ok := entryID(w, r, models.ResourceGrowth)
+	if !ok {
+		return
+	}
+	units, err := parseUnits(r)
 	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	entries, err := storage.LoadGrowth()
+	e, found, err := storage.FindEntry[models.GrowthEntry](models.ResourceGrowth, id)
 	if err != nil {
 		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	for _, e := range entries {
-		if e.ID == id {
-			jsonResponse(w, http.StatusOK, e)
-			return
-		}
+	if !found {
+		jsonResponse(w, http.StatusNotFound, map[string]string{"error": "growth entry not found"})
+		return
 	}
-	jsonResponse(w, http.StatusNotFound, map[string]string{"error": "growth entry not found"})
+	w.Header().Set("ETag", entityTag(e.Version))
+	jsonResponse(w, http.StatusOK, e.InUnits(units))
 }
 
 func handleUpdateGrowth(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	id, ok := entryID(w, r, models.ResourceGrowth)
+	if !ok {
+		return
+	}
+	ifVersion, err := parseIfMatch(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	units, err := parseUnits(r)
 	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	var entry models.GrowthEntry
@@ -72,29 +93,61 @@ func handleUpdateGrowth(w http.ResponseWriter, r *http.Request) {
 		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
 		return
 	}
-	if entry.Date == "" {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "missing required field (date)"})
+	if err := validateGrowth(&entry); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	log.Printf("Update Growth ID %d: %+v\n", id, entry)
-	if err := storage.UpdateGrowth(id, &entry); err != nil {
-		jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	if err := storage.UpdateGrowth(r.Context(), id, ifVersion, &entry); err != nil {
+		writeChangeError(w, err)
 		return
 	}
 	entry.ID = id
-	jsonResponse(w, http.StatusOK, entry)
+	w.Header().Set("ETag", entityTag(entry.Version))
+	jsonResponse(w, http.StatusOK, entry.InUnits(units))
+}
+
+func handlePatchGrowth(w http.ResponseWriter, r *http.Request) {
+	units, err := parseUnits(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	entry, ok := patchEntry(w, r, models.ResourceGrowth,
+		[]unitGroup{
+			{"weight_unit", []string{"weight"}, units.Weight, models.UnitKg},
+			{"length_unit", []string{"height", "head_circ"}, units.Length, models.UnitCm},
+		},
+		func(e models.GrowthEntry) (int, int) { return e.ID, e.Version }, validateGrowth, storage.UpdateGrowth)
+	if !ok {
+		return
+	}
+	w.Header().Set("ETag", entityTag(entry.Version))
+	jsonResponse(w, http.StatusOK, entry.InUnits(units))
 }
 
 func handleDeleteGrowth(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	id, ok := entryID(w, r, models.ResourceGrowth)
+	if !ok {
+		return
+	}
+	ifVersion, err := parseIfMatch(r)
 	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	log.Printf("Delete Growth ID %d\n", id)
-	if err := storage.DeleteGrowth(id); err != nil {
-		jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	if err := storage.DeleteGrowth(r.Context(), id, ifVersion); err != nil {
+		writeChangeError(w, err)
 		return
 	}
-	jsonResponse(w, http.StatusOK, map[string]string{"status": "deleted"})
+	jsonResponse(w, http.StatusOK, deletedResponse(models.ResourceGrowth, id))
+}
+
+// validateGrowth checks a growth entry from a request and converts it to metric.
+func validateGrowth(entry *models.GrowthEntry) error {
+	if entry.Date == "" {
+		return errors.New("missing required field (date)")
+	}
+	return entry.Canonicalize()
 }
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -31,6 +32,32 @@ func jsonResponse(w http.ResponseWriter, status int, payload interface{}) {
 	}
 }
 
+// entryIDPattern matches an entry's {id} path segment: its integer ID or its UID.
+const entryIDPattern = "{id:[0-9]+|[0-9A-Za-z]{26}}"
+
+// entryID reads the {id} path segment, resolving a UID to the entry's integer ID.
+// On failure it writes the error response and returns false.
+func entryID(w http.ResponseWriter, r *http.Request, resource string) (int, bool) {
+	v := mux.Vars(r)["id"]
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, true
+	}
+	if _, valid := models.NormalizeUID(v); !valid {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+		return 0, false
+	}
+	id, found, err := storage.ResolveUID(resource, v)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return 0, false
+	}
+	if !found {
+		jsonResponse(w, http.StatusNotFound, map[string]string{"error": "entry not found"})
+		return 0, false
+	}
+	return id, true
+}
+
 // parsePagination extracts limit and offset from query params.
 // Returns limit (default 10, 0 = all), offset (default 0).
 func parsePagination(r *http.Request) (limit, offset int) {
@@ -48,6 +75,21 @@ func parsePagination(r *http.Request) (limit, offset int) {
 	return
 }
 
+// parseEntryQuery reads the ?from=, ?to= and ?logged_by= filters and the
+// pagination of an entry list.
+func parseEntryQuery(r *http.Request) (models.EntryQuery, error) {
+	limit, offset := parsePagination(r)
+	query := r.URL.Query()
+	q := models.EntryQuery{
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+		LoggedBy: query.Get("logged_by"),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	return q, q.Validate()
+}
+
 // paginateReverse reverses a slice in-place and applies offset/limit.
 // Returns the page slice, total count, and clamped limit/offset.
 func paginateReverse[T any](items []T, limit, offset int) ([]T, int) {
@@ -68,63 +110,91 @@ func paginateReverse[T any](items []T, limit, offset int) ([]T, int) {
 	return items, total
 }
 
+// parseUnits reads ?units=metric|imperial for response conversion.
+// Defaults to metric, the units entries are stored in.
+func parseUnits(r *http.Request) (models.Units, error) {
+	return models.ParseUnitSystem(r.URL.Query().Get("units"))
+}
+
 // handleListFeeds returns feed entries (newest-first, paginated).
 func handleListFeeds(w http.ResponseWriter, r *http.Request) {
-	feeds, err := storage.LoadFeeds()
+	units, err := parseUnits(r)
 	if err != nil {
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	limit, offset := parsePagination(r)
-	page, total := paginateReverse(feeds, limit, offset)
-	jsonResponse(w, http.StatusOK, PaginatedResponse{Items: page, Total: total, Limit: limit, Offset: offset})
+	page, total, q, ok := listEntries(w, r, models.ResourceFeeds, func(f models.FeedEntry) (string, string) { return f.Date, f.LoggedBy })
+	if !ok {
+		return
+	}
+	for i := range page {
+		page[i] = page[i].InUnits(units)
+	}
+	conditionalJSON(w, r, PaginatedResponse{Items: page, Total: total, Limit: q.Limit, Offset: q.Offset})
 }
 
 // handleLogFeed logs a new feed entry.
 func handleLogFeed(w http.ResponseWriter, r *http.Request) {
+	units, err := parseUnits(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
 	var feed models.FeedEntry
 	if err := json.NewDecoder(r.Body).Decode(&feed); err != nil {
 		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
 		return
 	}
-	if feed.Type == "" || feed.Date == "" {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "missing required fields"})
+	if err := validateFeed(&feed); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
+	feed.LoggedBy = caregiverFrom(r)
 	log.Printf("Log Feed: %+v\n", feed)
-	if err := storage.SaveFeed(&feed); err != nil {
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := storage.SaveFeed(r.Context(), &feed); err != nil {
+		writeCreateError(w, err)
 		return
 	}
-	jsonResponse(w, http.StatusCreated, feed)
+	jsonResponse(w, http.StatusCreated, feed.InUnits(units))
 }
 
 // handleGetFeed returns a single feed entry by ID.
 func handleGetFeed(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	id, ok := entryID(w, r, models.ResourceFeeds)
+	if !ok {
+		return
+	}
+	units, err := parseUnits(r)
 	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid feed ID"})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	feeds, err := storage.LoadFeeds()
+	feed, found, err := storage.FindEntry[models.FeedEntry](models.ResourceFeeds, id)
 	if err != nil {
 		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	for _, feed := range feeds {
-		if feed.ID == id {
-			jsonResponse(w, http.StatusOK, feed)
-			return
-		}
+	if !found {
+		jsonResponse(w, http.StatusNotFound, map[string]string{"error": "feed not found"})
+		return
 	}
-	jsonResponse(w, http.StatusNotFound, map[string]string{"error": "feed not found"})
+	w.Header().Set("ETag", entityTag(feed.Version))
+	jsonResponse(w, http.StatusOK, feed.InUnits(units))
 }
 
 func handleUpdateFeed(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	id, ok := entryID(w, r, models.ResourceFeeds)
+	if !ok {
+		return
+	}
+	ifVersion, err := parseIfMatch(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	units, err := parseUnits(r)
 	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid feed ID"})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	var feed models.FeedEntry
@@ -132,29 +202,60 @@ func handleUpdateFeed(w http.ResponseWriter, r *http.Request) {
 		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
 		return
 	}
-	if feed.Type == "" || feed.Date == "" {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "missing required fields"})
+	if err := validateFeed(&feed); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	log.Printf("Update Feed ID %d: %+v\n", id, feed)
-	if err := storage.UpdateFeed(id, &feed); err != nil {
-		jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	if err := storage.UpdateFeed(r.Context(), id, ifVersion, &feed); err != nil {
+		writeChangeError(w, err)
 		return
 	}
 	feed.ID = id
-	jsonResponse(w, http.StatusOK, feed)
+	w.Header().Set("ETag", entityTag(feed.Version))
+	jsonResponse(w, http.StatusOK, feed.InUnits(units))
+}
+
+// handlePatchFeed applies a JSON Merge Patch to a feed entry.
+func handlePatchFeed(w http.ResponseWriter, r *http.Request) {
+	units, err := parseUnits(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	feed, ok := patchEntry(w, r, models.ResourceFeeds,
+		[]unitGroup{{"quantity_unit", []string{"quantity"}, units.Volume, models.UnitML}},
+		func(e models.FeedEntry) (int, int) { return e.ID, e.Version }, validateFeed, storage.UpdateFeed)
+	if !ok {
+		return
+	}
+	w.Header().Set("ETag", entityTag(feed.Version))
+	jsonResponse(w, http.StatusOK, feed.InUnits(units))
 }
 
 func handleDeleteFeed(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	id, ok := entryID(w, r, models.ResourceFeeds)
+	if !ok {
+		return
+	}
+	ifVersion, err := parseIfMatch(r)
 	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid feed ID"})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	log.Printf("Delete Feed ID %d\n", id)
-	if err := storage.DeleteFeed(id); err != nil {
-		jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	if err := storage.DeleteFeed(r.Context(), id, ifVersion); err != nil {
+		writeChangeError(w, err)
 		return
 	}
-	jsonResponse(w, http.StatusOK, map[string]string{"status": "deleted"})
+	jsonResponse(w, http.StatusOK, deletedResponse(models.ResourceFeeds, id))
+}
+
+// validateFeed normalizes a feed from a request and checks its required fields.
+func validateFeed(feed *models.FeedEntry) error {
+	feed.SyncDate()
+	if feed.Type == "" || feed.Date == "" {
+		return errors.New("missing required fields")
+	}
+	return feed.Canonicalize()
 }
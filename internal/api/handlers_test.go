@@ -2,19 +2,26 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"babytracker/internal/auth"
 	"babytracker/internal/config"
 	"babytracker/internal/models"
 	"babytracker/internal/storage"
-
 )
 
 func testConfig() *config.Config {
-	return &config.Config{APIPort: "8080", CORSOrigin: "http://localhost:3000"}
+	return &config.Config{APIPort: "8080", CORSOrigins: []string{"http://localhost:3000", "http://localhost:3005"}}
 }
 
 func testRouter(t *testing.T) http.Handler {
@@ -119,12 +126,46 @@ func TestHandleGetFeed_NotFound(t *testing.T) {
 func TestCORSHeaders(t *testing.T) {
 	router := testRouter(t)
 	// Test CORS on a regular GET request (OPTIONS routing depends on mux config)
+	for origin, want := range map[string]string{
+		"http://localhost:3000": "http://localhost:3000",
+		"http://localhost:3005": "http://localhost:3005",
+		"http://localhost:9999": "",
+		"https://evil.example":  "",
+	} {
+		req := httptest.NewRequest("GET", "/api/feeds", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("Origin %s: expected CORS header %q, got %q", origin, want, got)
+		}
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	router := testRouter(t)
 	req := httptest.NewRequest("GET", "/api/feeds", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
+	for header, want := range map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Referrer-Policy":         "no-referrer",
+		"Content-Security-Policy": "default-src 'none'; frame-ancestors 'none'",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("HSTS must not be sent over plain HTTP, got %q", got)
+	}
 
-	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:3000" {
-		t.Errorf("expected CORS header http://localhost:3000, got %s", got)
+	req = httptest.NewRequest("GET", "https://localhost/api/feeds", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get("Strict-Transport-Security"); got == "" {
+		t.Error("expected HSTS over HTTPS")
 	}
 }
 
@@ -145,3 +186,924 @@ func TestListEndpoints_ReturnJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestDailySummary(t *testing.T) {
+	router := testRouter(t)
+	body := `{"date":"2026-04-06","time":"2026-04-06T09:00:00","type":"Bottle","quantity":100}`
+	req := httptest.NewRequest("POST", "/api/feeds", bytes.NewBufferString(body))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("GET", "/api/summary?date=2026-04-06", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var s models.DailySummary
+	if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+		t.Fatalf("failed to decode summary: %v", err)
+	}
+	if s.Feeds != 1 || s.FeedQuantity != 100 {
+		t.Errorf("expected 1 feed of 100, got %d of %v", s.Feeds, s.FeedQuantity)
+	}
+
+	req = httptest.NewRequest("GET", "/api/summary?date=banana", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid date, got %d", w.Code)
+	}
+}
+
+func TestFeedUnits_ImperialRoundTrip(t *testing.T) {
+	router := testRouter(t)
+	body := `{"date":"2026-04-06","type":"Bottle","quantity":4,"quantity_unit":"oz"}`
+	req := httptest.NewRequest("POST", "/api/feeds", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+
+	stored, err := storage.LoadFeeds()
+	if err != nil {
+		t.Fatalf("LoadFeeds failed: %v", err)
+	}
+	if stored[0].QuantityUnit != models.UnitML || stored[0].EnteredUnit != models.UnitOz {
+		t.Errorf("stored units = %s/%s, want ml/oz", stored[0].QuantityUnit, stored[0].EnteredUnit)
+	}
+
+	req = httptest.NewRequest("GET", "/api/feeds/1?units=imperial", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var feed models.FeedEntry
+	if err := json.NewDecoder(w.Body).Decode(&feed); err != nil {
+		t.Fatalf("failed to decode feed: %v", err)
+	}
+	if feed.Quantity != 4 || feed.QuantityUnit != models.UnitOz {
+		t.Errorf("expected 4 oz, got %v %s", feed.Quantity, feed.QuantityUnit)
+	}
+
+	req = httptest.NewRequest("GET", "/api/feeds?units=furlongs", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unknown units, got %d", w.Code)
+	}
+}
+
+func TestHandleLogFeed_UnknownUnit(t *testing.T) {
+	router := testRouter(t)
+	body := `{"date":"2026-04-06","type":"Bottle","quantity":1,"quantity_unit":"cup"}`
+	req := httptest.NewRequest("POST", "/api/feeds", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestCaregiverAttribution(t *testing.T) {
+	router := testRouter(t)
+	req := httptest.NewRequest("POST", "/api/caregivers", bytes.NewBufferString(`{"name":"Asha","relation":"Mum"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+
+	body := `{"date":"2026-04-06","type":"Wet"}`
+	req = httptest.NewRequest("POST", "/api/diapers", bytes.NewBufferString(body))
+	req.Header.Set("X-Caregiver", "asha")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var diaper models.DiaperEntry
+	if err := json.NewDecoder(w.Body).Decode(&diaper); err != nil {
+		t.Fatalf("failed to decode diaper: %v", err)
+	}
+	if diaper.LoggedBy != "Asha" {
+		t.Errorf("LoggedBy = %q, want Asha", diaper.LoggedBy)
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/diapers", bytes.NewBufferString(body)))
+
+	req = httptest.NewRequest("GET", "/api/diapers?logged_by=Asha", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp PaginatedResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 1 {
+		t.Errorf("expected 1 entry logged by Asha, got %d", resp.Total)
+	}
+
+	req = httptest.NewRequest("POST", "/api/diapers", bytes.NewBufferString(body))
+	req.Header.Set("X-Caregiver", "Stranger")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unknown caregiver, got %d", w.Code)
+	}
+}
+
+func TestListDateRange(t *testing.T) {
+	router := testRouter(t)
+	for _, date := range []string{"2026-04-05", "2026-04-06", "2026-04-07"} {
+		body := `{"date":"` + date + `","type":"Nap","duration":45}`
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/sleep", bytes.NewBufferString(body)))
+	}
+
+	req := httptest.NewRequest("GET", "/api/sleep?from=2026-04-06&to=2026-04-07&limit=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp struct {
+		Items []models.SleepEntry `json:"items"`
+		Total int                 `json:"total"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Items) != 1 || resp.Items[0].Date != "2026-04-07" {
+		t.Errorf("got %+v of %d, want the 2026-04-07 nap of 2", resp.Items, resp.Total)
+	}
+
+	for _, q := range []string{"from=April", "from=2026-04-07&to=2026-04-06"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/sleep?"+q, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", q, w.Code)
+		}
+	}
+}
+
+// tokenFor creates a user with the given role and returns a bearer token for them.
+func tokenFor(t *testing.T, name, role string) string {
+	t.Helper()
+	u, err := auth.CreateUser(context.Background(), name, role)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	token, _, err := auth.IssueToken(context.Background(), u.ID, "test")
+	if err != nil {
+		t.Fatalf("IssueToken failed: %v", err)
+	}
+	return token
+}
+
+func TestRoleAuthorization(t *testing.T) {
+	router := testRouter(t)
+	owner := tokenFor(t, "Mum", models.RoleOwner)
+	viewer := tokenFor(t, "Nani", models.RoleViewer)
+
+	do := func(method, path, body, token string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := do("GET", "/api/feeds", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("no token once accounts exist: expected 401, got %d", code)
+	}
+	feed := `{"date":"2026-04-06","type":"Bottle","quantity":100}`
+	if code := do("POST", "/api/feeds", feed, owner); code != http.StatusCreated {
+		t.Fatalf("owner POST: expected 201, got %d", code)
+	}
+	if code := do("GET", "/api/feeds/1", "", viewer); code != http.StatusOK {
+		t.Errorf("viewer GET: expected 200, got %d", code)
+	}
+	for _, tc := range []struct{ method, path, body string }{
+		{"POST", "/api/feeds", feed},
+		{"PUT", "/api/feeds/1", feed},
+		{"DELETE", "/api/feeds/1", ""},
+		{"GET", "/api/users", ""},
+	} {
+		if code := do(tc.method, tc.path, tc.body, viewer); code != http.StatusForbidden {
+			t.Errorf("viewer %s %s: expected 403, got %d", tc.method, tc.path, code)
+		}
+	}
+}
+
+func TestFeedLoggedByTokenUser(t *testing.T) {
+	router := testRouter(t)
+	token := tokenFor(t, "Ravi", models.RoleCaregiver)
+	body := `{"date":"2026-04-06","type":"Bottle","quantity":100}`
+	req := httptest.NewRequest("POST", "/api/feeds", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Caregiver", "Someone Else")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var feed models.FeedEntry
+	if err := json.NewDecoder(w.Body).Decode(&feed); err != nil {
+		t.Fatalf("failed to decode feed: %v", err)
+	}
+	if feed.LoggedBy != "Ravi" {
+		t.Errorf("LoggedBy = %q, want the token's user Ravi", feed.LoggedBy)
+	}
+}
+
+func TestTokenCreateAndRevoke(t *testing.T) {
+	router := testRouter(t)
+	caregiver := tokenFor(t, "Ravi", models.RoleCaregiver)
+
+	req := httptest.NewRequest("POST", "/api/tokens", bytes.NewBufferString(`{"label":"tablet"}`))
+	req.Header.Set("Authorization", "Bearer "+caregiver)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	var issued issuedToken
+	if err := json.NewDecoder(w.Body).Decode(&issued); err != nil {
+		t.Fatalf("failed to decode token: %v", err)
+	}
+	if issued.Token == "" || issued.Hash != "" {
+		t.Errorf("expected plaintext token and no hash, got %+v", issued)
+	}
+
+	req = httptest.NewRequest("DELETE", "/api/tokens/"+strconv.Itoa(issued.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+caregiver)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("revoke: expected status 200, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/feeds", nil)
+	req.Header.Set("Authorization", "Bearer "+issued.Token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token: expected 401, got %d", w.Code)
+	}
+}
+
+func TestLegacyAPIKey(t *testing.T) {
+	if err := storage.Init(t.TempDir()); err != nil {
+		t.Fatalf("failed to init test storage: %v", err)
+	}
+	cfg := testConfig()
+	cfg.APIKey = "shared-secret"
+	router := SetupRouter(cfg)
+
+	req := httptest.NewRequest("GET", "/api/feeds", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("missing key: expected 401, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/users", bytes.NewBufferString(`{"name":"Nani","role":"viewer"}`))
+	req.Header.Set("Authorization", "Bearer shared-secret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("shared key acts as owner: expected 201, got %d", w.Code)
+	}
+}
+
+func TestShareLink(t *testing.T) {
+	router := testRouter(t)
+	feed := `{"date":"2026-04-06","time":"2026-04-06T09:00:00","type":"Bottle","quantity":120,"notes":"<script>alert(1)</script>"}`
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/feeds", bytes.NewBufferString(feed)))
+	sleep := `{"date":"2026-04-06","type":"Nap","duration":45,"notes":"secret nap"}`
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/sleep", bytes.NewBufferString(sleep)))
+
+	body := `{"resources":["feeds"],"to":"2026-04-10","days":30}`
+	req := httptest.NewRequest("POST", "/api/shares", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	var created struct {
+		URL    string             `json:"url"`
+		Claims models.ShareClaims `json:"claims"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode share: %v", err)
+	}
+	if created.Claims.From != "2026-03-12" {
+		t.Errorf("from = %s, want 2026-03-12", created.Claims.From)
+	}
+
+	req = httptest.NewRequest("GET", created.URL, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	page := w.Body.String()
+	if !strings.Contains(page, "&lt;script&gt;") || strings.Contains(page, "<script>alert") {
+		t.Error("expected feed notes to be HTML-escaped in the report")
+	}
+	if strings.Contains(page, "secret nap") {
+		t.Error("report leaked a resource the link does not grant")
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Error("expected Cache-Control: no-store on share report")
+	}
+
+	req = httptest.NewRequest("GET", created.URL+"x", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("tampered link: expected 404, got %d", w.Code)
+	}
+
+	for _, body := range []string{`{"from":"0001-01-01","to":"9999-12-31"}`, `{"to":"2026-04-10","days":400}`} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/shares", bytes.NewBufferString(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("share of %s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	router := testRouter(t)
+	caregiver := tokenFor(t, "Ravi", models.RoleCaregiver)
+	viewer := tokenFor(t, "Nani", models.RoleViewer)
+
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("POST", "/api/feeds", `{"date":"2026-04-06","type":"Bottle","quantity":90}`, caregiver); w.Code != http.StatusCreated {
+		t.Fatalf("log feed: expected 201, got %d", w.Code)
+	}
+	if w := do("PUT", "/api/feeds/1", `{"date":"2026-04-06","type":"Bottle","quantity":120}`, caregiver); w.Code != http.StatusOK {
+		t.Fatalf("update feed: expected 200, got %d", w.Code)
+	}
+	if w := do("DELETE", "/api/feeds/1", "", caregiver); w.Code != http.StatusOK {
+		t.Fatalf("delete feed: expected 200, got %d", w.Code)
+	}
+
+	w := do("GET", "/api/audit?resource=feeds&id=1", "", caregiver)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp struct {
+		Total int         `json:"total"`
+		Items []auditView `json:"items"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 3 || len(resp.Items) != 3 {
+		t.Fatalf("expected 3 audit entries, got %d", resp.Total)
+	}
+	// Newest first: delete, update, create
+	del, update := resp.Items[0], resp.Items[1]
+	if del.Action != models.AuditDelete || del.Actor != "Ravi" || del.Address != "192.0.2.1" {
+		t.Errorf("unexpected delete entry: %+v", del.AuditEntry)
+	}
+	if len(update.Changes) != 1 || update.Changes[0].Field != "quantity" {
+		t.Errorf("expected the update to list the quantity change, got %+v", update.Changes)
+	}
+
+	if w := do("GET", "/api/audit?action=delete&actor=ravi", "", caregiver); !strings.Contains(w.Body.String(), `"total":1`) {
+		t.Errorf("expected one delete by Ravi, got %s", w.Body.String())
+	}
+	if w := do("GET", "/api/audit?resource=pets", "", caregiver); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown resource: expected 400, got %d", w.Code)
+	}
+	if w := do("GET", "/api/audit", "", viewer); w.Code != http.StatusForbidden {
+		t.Errorf("viewer: expected 403, got %d", w.Code)
+	}
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	router := testRouter(t)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	do("POST", "/api/sleep", `{"date":"2026-04-06","type":"Nap","duration":45}`)
+	w := do("DELETE", "/api/sleep/1", "")
+	var deleted map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&deleted); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if deleted["restore"] != "/api/trash/sleep/1/restore" {
+		t.Errorf("expected a restore link, got %v", deleted)
+	}
+
+	w = do("GET", "/api/trash", "")
+	var trash struct {
+		Total int                `json:"total"`
+		Items []models.TrashItem `json:"items"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&trash); err != nil {
+		t.Fatalf("failed to decode trash: %v", err)
+	}
+	if trash.Total != 1 || trash.Items[0].Resource != models.ResourceSleep || trash.Items[0].ID != 1 {
+		t.Fatalf("unexpected trash: %+v", trash)
+	}
+
+	if w := do("POST", deleted["restore"], ""); w.Code != http.StatusOK {
+		t.Fatalf("restore: expected 200, got %d", w.Code)
+	}
+	if w := do("GET", "/api/sleep/1", ""); w.Code != http.StatusOK {
+		t.Errorf("restored entry: expected 200, got %d", w.Code)
+	}
+	if w := do("POST", deleted["restore"], ""); w.Code != http.StatusNotFound {
+		t.Errorf("second restore: expected 404, got %d", w.Code)
+	}
+	if w := do("POST", "/api/trash/users/1/restore", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown resource: expected 404, got %d", w.Code)
+	}
+}
+
+func TestEntryHistoryRevertAndAsOf(t *testing.T) {
+	router := testRouter(t)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	do("POST", "/api/sleep", `{"date":"2026-04-06","type":"Nap","duration":45}`)
+	before := time.Now().UTC().Format(time.RFC3339Nano)
+	time.Sleep(time.Millisecond)
+	do("PUT", "/api/sleep/1", `{"date":"2026-04-06","type":"Nap","duration":90}`)
+
+	w := do("GET", "/api/sleep/1/history", "")
+	var revs []models.Revision
+	if err := json.NewDecoder(w.Body).Decode(&revs); err != nil {
+		t.Fatalf("failed to decode history: %v", err)
+	}
+	if len(revs) != 2 || revs[1].Changes[0].Field != "duration" {
+		t.Fatalf("unexpected history: %+v", revs)
+	}
+
+	w = do("GET", "/api/sleep?as_of="+url.QueryEscape(before), "")
+	var page struct {
+		Items []models.SleepEntry `json:"items"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Duration != 45 {
+		t.Errorf("as_of list = %+v, want the original 45-minute nap", page.Items)
+	}
+	if w := do("GET", "/api/sleep?as_of=yesterday", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid as_of: expected 400, got %d", w.Code)
+	}
+
+	if w := do("POST", "/api/sleep/1/history/1/revert", ""); w.Code != http.StatusOK {
+		t.Fatalf("revert: expected 200, got %d: %s", w.Code, w.Body)
+	}
+	w = do("GET", "/api/sleep/1", "")
+	var entry models.SleepEntry
+	if err := json.NewDecoder(w.Body).Decode(&entry); err != nil {
+		t.Fatalf("failed to decode entry: %v", err)
+	}
+	if entry.Duration != 45 {
+		t.Errorf("after revert duration = %d, want 45", entry.Duration)
+	}
+	if w := do("POST", "/api/sleep/1/history/9/revert", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown revision: expected 404, got %d", w.Code)
+	}
+	if w := do("GET", "/api/feeds/7/history", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown entry: expected 404, got %d", w.Code)
+	}
+}
+
+func TestHistoryAfterPurge(t *testing.T) {
+	router := testRouter(t)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	do("POST", "/api/feeds", `{"date":"2026-04-06","type":"Bottle","quantity":90}`)
+	do("POST", "/api/feeds", `{"date":"2026-04-06","type":"Bottle","quantity":120}`)
+	if w := do("DELETE", "/api/feeds/2", ""); w.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d", w.Code)
+	}
+	time.Sleep(time.Millisecond)
+	if n, err := storage.PurgeTrash(context.Background(), time.Nanosecond); err != nil || n != 1 {
+		t.Fatalf("PurgeTrash = %d, %v; want 1", n, err)
+	}
+
+	// The purged entry's ID, and with it its history, is not handed on
+	w := do("POST", "/api/feeds", `{"date":"2026-04-07","type":"Bottle","quantity":60}`)
+	var created models.FeedEntry
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode feed: %v", err)
+	}
+	if created.ID != 3 {
+		t.Errorf("new feed after a purge got ID %d, want 3", created.ID)
+	}
+	var revs []models.Revision
+	if err := json.NewDecoder(do("GET", "/api/feeds/3/history", "").Body).Decode(&revs); err != nil {
+		t.Fatalf("failed to decode history: %v", err)
+	}
+	if len(revs) != 1 || revs[0].Action != models.AuditCreate {
+		t.Errorf("history of the new feed = %+v, want its create alone", revs)
+	}
+	if err := json.NewDecoder(do("GET", "/api/feeds/2/history", "").Body).Decode(&revs); err != nil {
+		t.Fatalf("failed to decode history: %v", err)
+	}
+	if len(revs) != 3 || revs[2].Action != models.AuditPurge {
+		t.Errorf("history of the purged feed = %+v, want create, delete, purge", revs)
+	}
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	router := testRouter(t)
+	do := func(method, path, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	do("POST", "/api/diapers", `{"date":"2026-04-06","type":"Wet"}`)
+	etag := do("GET", "/api/diapers/1", "").Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("ETag = %s, want \"1\"", etag)
+	}
+
+	// First parent saves; the second, still holding the old ETag, is refused
+	w := do("PUT", "/api/diapers/1", `{"date":"2026-04-06","type":"Dirty"}`, "If-Match", etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PUT: expected 200 with ETag \"2\", got %d %s", w.Code, w.Header().Get("ETag"))
+	}
+	if w := do("PUT", "/api/diapers/1", `{"date":"2026-04-06","type":"Mixed"}`, "If-Match", etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale PUT: expected 412, got %d", w.Code)
+	}
+	if w := do("DELETE", "/api/diapers/1", "", "If-Match", etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale DELETE: expected 412, got %d", w.Code)
+	}
+	if w := do("DELETE", "/api/diapers/1", "", "If-Match", "3"); w.Code != http.StatusBadRequest {
+		t.Errorf("unquoted If-Match: expected 400, got %d", w.Code)
+	}
+
+	// Conditional list GET
+	w = do("GET", "/api/diapers", "")
+	listTag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || listTag == "" {
+		t.Fatalf("list: expected 200 with an ETag, got %d %q", w.Code, listTag)
+	}
+	if w := do("GET", "/api/diapers", "", "If-None-Match", listTag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("unchanged list: expected an empty 304, got %d", w.Code)
+	}
+	do("POST", "/api/diapers", `{"date":"2026-04-06","type":"Wet"}`)
+	if w := do("GET", "/api/diapers", "", "If-None-Match", listTag); w.Code != http.StatusOK {
+		t.Errorf("changed list: expected 200, got %d", w.Code)
+	}
+
+	if w := do("DELETE", "/api/diapers/1", "", "If-Match", `"2"`); w.Code != http.StatusOK {
+		t.Errorf("DELETE at the current version: expected 200, got %d", w.Code)
+	}
+}
+
+func TestPatchEntry(t *testing.T) {
+	router := testRouter(t)
+	do := func(method, path, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	mergePatch := "application/merge-patch+json"
+
+	do("POST", "/api/feeds", `{"date":"2026-04-06","type":"Bottle","quantity":120,"notes":"fussy"}`)
+	w := do("PATCH", "/api/feeds/1", `{"notes":"settled after burping"}`, "Content-Type", mergePatch)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH: expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var feed models.FeedEntry
+	if err := json.NewDecoder(w.Body).Decode(&feed); err != nil {
+		t.Fatalf("failed to decode feed: %v", err)
+	}
+	if feed.Notes != "settled after burping" || feed.Quantity != 120 || feed.Type != models.FeedTypeBottle || feed.Version != 2 {
+		t.Errorf("PATCH should change only the notes, got %+v", feed)
+	}
+
+	// Setting the unit alongside the quantity converts it like PUT does
+	w = do("PATCH", "/api/feeds/1", `{"quantity":4,"quantity_unit":"oz"}`, "Content-Type", mergePatch)
+	feed = models.FeedEntry{}
+	if err := json.NewDecoder(w.Body).Decode(&feed); err != nil {
+		t.Fatalf("failed to decode feed: %v", err)
+	}
+	if feed.Quantity < 118 || feed.Quantity > 119 || feed.EnteredUnit != models.UnitOz {
+		t.Errorf("4 oz should be stored as ~118 ml, got %+v", feed)
+	}
+
+	if w := do("PATCH", "/api/feeds/1", `{"type":null}`, "Content-Type", mergePatch); w.Code != http.StatusBadRequest {
+		t.Errorf("removing a required field: expected 400, got %d", w.Code)
+	}
+	if w := do("PATCH", "/api/feeds/1", `{"notes":"x"}`, "Content-Type", mergePatch, "If-Match", `"1"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale If-Match: expected 412, got %d", w.Code)
+	}
+	if w := do("PATCH", "/api/feeds/1", `{"notes":"x"}`, "Content-Type", "text/plain"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("wrong content type: expected 415, got %d", w.Code)
+	}
+	if w := do("PATCH", "/api/feeds/1", `["notes"]`, "Content-Type", mergePatch); w.Code != http.StatusBadRequest {
+		t.Errorf("non-object patch: expected 400, got %d", w.Code)
+	}
+	if w := do("PATCH", "/api/diapers/9", `{"notes":"x"}`, "Content-Type", mergePatch); w.Code != http.StatusNotFound {
+		t.Errorf("unknown entry: expected 404, got %d", w.Code)
+	}
+
+	do("POST", "/api/growth", `{"date":"2026-04-06","weight":4.2,"height":55}`)
+	w = do("PATCH", "/api/growth/1", `{"head_circ":38}`, "Content-Type", mergePatch)
+	var growth models.GrowthEntry
+	if err := json.NewDecoder(w.Body).Decode(&growth); err != nil {
+		t.Fatalf("failed to decode growth: %v", err)
+	}
+	if growth.Weight != 4.2 || growth.Height != 55 || growth.HeadCircumference != 38 {
+		t.Errorf("growth PATCH should keep the other measurements, got %+v", growth)
+	}
+
+	// Values without a unit are in the request's units; a unit needs its values
+	w = do("PATCH", "/api/growth/1?units=imperial", `{"weight":10}`, "Content-Type", mergePatch)
+	growth = models.GrowthEntry{}
+	if err := json.NewDecoder(w.Body).Decode(&growth); err != nil {
+		t.Fatalf("failed to decode growth: %v", err)
+	}
+	if growth.Weight != 10 || growth.WeightUnit != models.UnitLb || growth.Height != 21.65 {
+		t.Errorf("10 lb patched in imperial should read back as 10 lb, got %+v", growth)
+	}
+	if w := do("PATCH", "/api/growth/1?units=imperial", `{"height":22}`, "Content-Type", mergePatch); w.Code != http.StatusBadRequest {
+		t.Errorf("height in inches without head_circ: expected 400, got %d", w.Code)
+	}
+	if w := do("PATCH", "/api/feeds/1", `{"quantity_unit":"oz"}`, "Content-Type", mergePatch); w.Code != http.StatusBadRequest {
+		t.Errorf("unit without quantity: expected 400, got %d", w.Code)
+	}
+	w = do("PATCH", "/api/feeds/1?units=imperial", `{"quantity":5}`, "Content-Type", mergePatch)
+	if stored, _, _ := storage.FindEntry[models.FeedEntry](models.ResourceFeeds, 1); w.Code != http.StatusOK || stored.Quantity < 147 || stored.Quantity > 148 {
+		t.Errorf("5 oz patched in imperial should be stored as ~148 ml, got %d, %+v", w.Code, stored)
+	}
+
+	// The date follows the time
+	do("POST", "/api/sleep", `{"date":"2026-04-06","start_time":"2026-04-06T13:00:00","type":"Nap","duration":30}`)
+	if w := do("PATCH", "/api/sleep/1", `{"date":"2026-04-07"}`, "Content-Type", mergePatch); w.Code != http.StatusBadRequest {
+		t.Errorf("date disagreeing with the time: expected 400, got %d: %s", w.Code, w.Body)
+	}
+	if w := do("PATCH", "/api/sleep/1", `{"date":"2026-04-07","start_time":"2026-04-07T13:00:00"}`, "Content-Type", mergePatch); w.Code != http.StatusOK {
+		t.Errorf("date with a matching time: expected 200, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	router := testRouter(t)
+	do := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/batch", bytes.NewBufferString(body)))
+		return w
+	}
+
+	queue := `{"operations":[
+		{"key":"q1","op":"create","resource":"feeds","entry":{"date":"2026-04-06","type":"Bottle","quantity":90}},
+		{"key":"q2","op":"update","resource":"feeds","ref":"q1","entry":{"date":"2026-04-06","type":"Bottle","quantity":110}},
+		{"key":"q3","op":"create","resource":"sleep","entry":{"date":"2026-04-06","type":"Nap","duration":30}}
+	]}`
+	w := do(queue)
+	if w.Code != http.StatusOK {
+		t.Fatalf("batch: expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Results []batchResult `json:"results"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode results: %v", err)
+	}
+	if len(resp.Results) != 3 || resp.Results[0].ID != 1 || resp.Results[1].Version != 2 || resp.Results[2].Resource != "sleep" {
+		t.Fatalf("unexpected results: %+v", resp.Results)
+	}
+
+	// The client never saw the response and sends the queue again
+	w = do(queue)
+	resp.Results = nil
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode results: %v", err)
+	}
+	if w.Code != http.StatusOK || !resp.Results[0].Replayed || resp.Results[0].ID != 1 {
+		t.Errorf("replay: expected replayed results, got %d %+v", w.Code, resp.Results)
+	}
+
+	// One bad operation rejects the whole batch
+	w = do(`{"operations":[
+		{"op":"create","resource":"diapers","entry":{"date":"2026-04-06","type":"Wet"}},
+		{"op":"delete","resource":"feeds","id":99}
+	]}`)
+	var failure batchFailure
+	if err := json.NewDecoder(w.Body).Decode(&failure); err != nil {
+		t.Fatalf("failed to decode failure: %v", err)
+	}
+	if w.Code != http.StatusNotFound || failure.Index != 1 {
+		t.Errorf("expected 404 for operation 1, got %d %+v", w.Code, failure)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/diapers", nil))
+	if !strings.Contains(w.Body.String(), `"total":0`) {
+		t.Errorf("diaper from the failed batch was kept: %s", w.Body)
+	}
+
+	if w := do(`{"operations":[{"op":"create","resource":"feeds","entry":{"date":"2026-04-06"}}]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid entry: expected 400, got %d", w.Code)
+	}
+	if w := do(`{"operations":[{"key":"q1","op":"create","resource":"sleep","entry":{"date":"2026-04-06","type":"Nap"}}]}`); w.Code != http.StatusConflict {
+		t.Errorf("reused key: expected 409, got %d", w.Code)
+	}
+	if w := do(`{"operations":[]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("empty batch: expected 400, got %d", w.Code)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	router := testRouter(t)
+	post := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set(idempotencyHeader, key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	feed := `{"date":"2026-04-06","type":"Bottle","quantity":90}`
+	first := post("/api/feeds", "k1", feed)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", first.Code, first.Body)
+	}
+	retry := post("/api/feeds", "k1", feed)
+	if retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "true" || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry: expected the original response replayed, got %d %q", retry.Code, retry.Body)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/feeds", nil))
+	if !strings.Contains(w.Body.String(), `"total":1`) {
+		t.Errorf("retry created a duplicate: %s", w.Body)
+	}
+
+	if w := post("/api/feeds", "k1", `{"date":"2026-04-06","type":"Bottle","quantity":120}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body: expected 422, got %d", w.Code)
+	}
+	if w := post("/api/sleep", "k1", feed); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different path: expected 422, got %d", w.Code)
+	}
+
+	// Failures are not remembered, so the same key can be retried once fixed
+	if w := post("/api/diapers", "k2", `{"date":"2026-04-06"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid diaper: expected 400, got %d", w.Code)
+	}
+	if w := post("/api/diapers", "k2", `{"date":"2026-04-06","type":"Wet"}`); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("fixed retry: expected a fresh 201, got %d", w.Code)
+	}
+
+	// A response carrying a one-time token is never replayed
+	if w := post("/api/shares", "k3", `{"resources":["feeds"],"to":"2026-04-10","days":30}`); w.Code != http.StatusCreated {
+		t.Fatalf("share: expected 201, got %d: %s", w.Code, w.Body)
+	}
+	if w := post("/api/shares", "k3", `{"resources":["feeds"],"to":"2026-04-10","days":30}`); w.Code != http.StatusConflict || strings.Contains(w.Body.String(), "token") {
+		t.Errorf("share retry: expected 409 without the token, got %d %s", w.Code, w.Body)
+	}
+
+	if w := post("/api/feeds", strings.Repeat("k", maxIdempotencyKey+1), feed); w.Code != http.StatusBadRequest {
+		t.Errorf("long key: expected 400, got %d", w.Code)
+	}
+
+	// Keys belong to their sender: another caregiver's k1 is a new request
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/caregivers", bytes.NewBufferString(`{"name":"Asha"}`)))
+	req := httptest.NewRequest("POST", "/api/feeds", bytes.NewBufferString(feed))
+	req.Header.Set(idempotencyHeader, "k1")
+	req.Header.Set("X-Caregiver", "Asha")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("another caregiver's k1: expected a fresh 201, got %d", w.Code)
+	}
+
+	// A create whose key was recorded with it, but whose response never was
+	// (the server stopped in between), is answered from the entry
+	sum := sha256.Sum256([]byte(feed))
+	rec := models.IdempotencyKey{Key: "k4", Actor: "caregiver:", Request: "POST /api/feeds", Digest: hex.EncodeToString(sum[:])}
+	applied := models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Quantity: 90}
+	if err := storage.SaveFeed(storage.WithIdempotencyKey(context.Background(), rec), &applied); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	w = post("/api/feeds?units=imperial", "k4", feed)
+	var replayed models.FeedEntry
+	if err := json.NewDecoder(w.Body).Decode(&replayed); err != nil {
+		t.Fatalf("failed to decode replay: %v", err)
+	}
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" || replayed.ID != applied.ID || replayed.QuantityUnit != models.UnitOz {
+		t.Errorf("retry after a lost response: got %d %+v, want the applied feed in oz", w.Code, replayed)
+	}
+	if feeds, _ := storage.LoadFeeds(); len(feeds) != 3 {
+		t.Errorf("retry after a lost response created a duplicate: %d feeds", len(feeds))
+	}
+}
+
+func TestEntryUIDsAndExport(t *testing.T) {
+	router := testRouter(t)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/diapers", bytes.NewBufferString(`{"date":"2026-04-06","type":"Wet"}`)))
+	var created models.DiaperEntry
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil || created.UID == "" {
+		t.Fatalf("expected a UID on the created diaper, got %s (%v)", w.Body, err)
+	}
+
+	// The UID works wherever the integer ID does, in either case
+	for _, path := range []string{"/api/diapers/" + created.UID, "/api/diapers/" + strings.ToLower(created.UID), "/api/diapers/" + created.UID + "/history"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d", path, w.Code)
+		}
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/diapers/"+models.NewUID(), nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown UID: expected 404, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/diapers", bytes.NewBufferString(`{"date":"2026-04-06","type":"Dirty","uid":"`+created.UID+`"}`)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("duplicate UID: expected 409, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/export", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Content-Disposition"), "attachment") {
+		t.Fatalf("export: expected a 200 attachment, got %d", w.Code)
+	}
+	bundle := w.Body.String()
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/import", bytes.NewBufferString(bundle)))
+	var res models.ImportResult
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("failed to decode import result: %v", err)
+	}
+	if w.Code != http.StatusOK || res.Skipped[models.ResourceDiapers] != 1 || res.Added[models.ResourceDiapers] != 0 {
+		t.Errorf("re-import: expected the diaper skipped, got %d %+v", w.Code, res)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/import", bytes.NewBufferString(`{"format":2}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown bundle format: expected 400, got %d", w.Code)
+	}
+}
+
+func TestBackupEndpoints(t *testing.T) {
+	router := testRouter(t)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/backups", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("backup of an empty directory: expected 409, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/feeds", bytes.NewBufferString(`{"date":"2026-04-06","type":"Bottle","quantity":90}`)))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/backups", nil))
+	var b models.Backup
+	if err := json.NewDecoder(w.Body).Decode(&b); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("create backup: expected 201, got %d (%v)", w.Code, err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/feeds", bytes.NewBufferString(`{"date":"2026-04-07","type":"Bottle","quantity":120}`)))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/backups/"+b.Name+"/restore", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore: expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if feeds, _ := storage.LoadFeeds(); len(feeds) != 1 {
+		t.Errorf("expected one feed after restore, got %d", len(feeds))
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/backups", nil))
+	var backups []models.Backup
+	if err := json.NewDecoder(w.Body).Decode(&backups); err != nil || len(backups) != 2 {
+		t.Errorf("list: expected the manual and pre-restore backups, got %d (%v)", len(backups), err)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/backups/missing.tar.gz/restore", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown backup: expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// parseAsOf reads ?as_of= (RFC3339, or a time without zone in the household
+// time zone). ok is false when the parameter is absent.
+func parseAsOf(r *http.Request) (t time.Time, ok bool, err error) {
+	v := r.URL.Query().Get("as_of")
+	if v == "" {
+		return time.Time{}, false, nil
+	}
+	var ft models.FlexTime
+	if err := ft.UnmarshalJSON([]byte(strconv.Quote(v))); err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid as_of %q (expected RFC3339 or 2006-01-02T15:04:05)", v)
+	}
+	return ft.Time, true, nil
+}
+
+// listEntries returns the page of a resource's entries the request asks for,
+// newest first, and how many match ?from=, ?to= and ?logged_by= in all. With
+// ?as_of= it pages the entries as they were at that instant; key gives the
+// date and caregiver of those for filtering. On failure it writes the error
+// response and returns false.
+func listEntries[T any](w http.ResponseWriter, r *http.Request, resource string, key func(T) (date, loggedBy string)) ([]T, int, models.EntryQuery, bool) {
+	q, err := parseEntryQuery(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return nil, 0, q, false
+	}
+	at, ok, err := parseAsOf(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return nil, 0, q, false
+	}
+	if !ok {
+		page, total, err := storage.QueryEntries[T](resource, q)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return nil, 0, q, false
+		}
+		return page, total, q, true
+	}
+	entries, err := storage.LoadAsOf[T](resource, at)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return nil, 0, q, false
+	}
+	matched := entries[:0]
+	for _, e := range entries {
+		if q.Matches(key(e)) {
+			matched = append(matched, e)
+		}
+	}
+	page, total := paginateReverse(matched, q.Limit, q.Offset)
+	return page, total, q, true
+}
+
+// handleHistory returns every revision of an entry (oldest first) with the
+// fields each one changed, including entries that have since been deleted.
+func handleHistory(resource string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := entryID(w, r, resource)
+		if !ok {
+			return
+		}
+		revs, err := storage.LoadHistory(resource, id)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if len(revs) == 0 {
+			jsonResponse(w, http.StatusNotFound, map[string]string{"error": "entry not found"})
+			return
+		}
+		jsonResponse(w, http.StatusOK, revs)
+	}
+}
+
+// handleRevert overwrites an entry with one of its earlier revisions. The revert
+// is itself an update, so it shows up as the newest revision and can be undone;
+// like PUT it honours If-Match. Deleted entries must be restored from the trash first.
+func handleRevert[T any](resource string, update func(ctx context.Context, id, ifVersion int, entry *T) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, ok := entryID(w, r, resource)
+		if !ok {
+			return
+		}
+		ifVersion, err := parseIfMatch(r)
+		if err != nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		n, err := strconv.Atoi(vars["revision"])
+		if err != nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid revision"})
+			return
+		}
+		revs, err := storage.LoadHistory(resource, id)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if n < 1 || n > len(revs) {
+			jsonResponse(w, http.StatusNotFound, map[string]string{"error": "revision not found"})
+			return
+		}
+		rev := revs[n-1]
+		if rev.Entry == nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "revision " + vars["revision"] + " is a deletion; restore the entry from the trash instead"})
+			return
+		}
+		var entry T
+		if err := json.Unmarshal(rev.Entry, &entry); err != nil {
+			jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("Revert %s ID %d to revision %d\n", resource, id, n)
+		if err := update(r.Context(), id, ifVersion, &entry); err != nil {
+			writeChangeError(w, err)
+			return
+		}
+		jsonResponse(w, http.StatusOK, entry)
+	}
+}
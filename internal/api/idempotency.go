@@ -0,0 +1,214 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// idempotencyHeader carries a client-generated key on a POST. A retry with the
+// same key gets the first response back instead of creating a second entry.
+const idempotencyHeader = "Idempotency-Key"
+
+// maxIdempotencyKey caps the key length; UUIDs and ULIDs fit comfortably.
+const maxIdempotencyKey = 255
+
+// idempotency holds the Idempotency-Keys of requests still being served. It
+// is created once and shared across reloads, like the rate limiter, so a retry
+// that arrives while a reload swaps the handler chain still finds the
+// original in flight.
+type idempotency struct {
+	inFlight sync.Map // inFlightKey -> struct{}
+}
+
+// inFlightKey is a request's entry in idempotency.inFlight.
+func inFlightKey(actor, key string) string {
+	return actor + "\x00" + key
+}
+
+// middleware replays the stored response to a POST whose Idempotency-Key was
+// seen before, for the IDEMPOTENCY_RETENTION_HOURS window. Keys belong to the
+// caller that sent them: another caller's same key is a different key. Reusing
+// one for another path or body is 422. A retry that arrives while the first
+// request is still running is 409, as is one whose original response held a
+// secret (see withholdReplay). Storage records the key in the same write as
+// the request's changes (see storage.WithIdempotencyKey), and the response
+// once it is sent; only 2xx responses are stored, so a failed request can be
+// retried with the same key.
+func (idem *idempotency) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(idempotencyHeader)
+		if req.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("%s is longer than %d characters", idempotencyHeader, maxIdempotencyKey)})
+			return
+		}
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "failed to read request body"})
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		rec := models.IdempotencyKey{
+			Key:     key,
+			Actor:   idempotencyActor(req),
+			Request: req.Method + " " + req.URL.Path,
+			Digest:  hex.EncodeToString(sum[:]),
+		}
+
+		flight := inFlightKey(rec.Actor, key)
+		if _, busy := idem.inFlight.LoadOrStore(flight, struct{}{}); busy {
+			jsonResponse(w, http.StatusConflict, map[string]string{"error": "a request with this " + idempotencyHeader + " is still being processed"})
+			return
+		}
+		defer idem.inFlight.Delete(flight)
+
+		stored, found, err := storage.LoadIdempotencyKey(rec.Actor, key)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if found {
+			replayResponse(w, req, stored, rec)
+			return
+		}
+
+		rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, req.WithContext(storage.WithIdempotencyKey(req.Context(), rec)))
+		if rw.status < 200 || rw.status > 299 {
+			return
+		}
+		rec.Status = rw.status
+		rec.Withheld = rw.withheld
+		if !rw.withheld {
+			rec.Response = bytes.TrimSpace(rw.body.Bytes())
+		}
+		if err := storage.SaveIdempotencyKey(rec); err != nil && !errors.Is(err, storage.ErrKeyReused) {
+			log.Printf("Failed to remember %s %q: %v", idempotencyHeader, key, err)
+		}
+	})
+}
+
+// replayResponse answers a request whose key is already stored.
+func replayResponse(w http.ResponseWriter, r *http.Request, stored, req models.IdempotencyKey) {
+	if stored.Request != req.Request || stored.Digest != req.Digest {
+		jsonResponse(w, http.StatusUnprocessableEntity, map[string]string{"error": idempotencyHeader + " was already used for a different request"})
+		return
+	}
+	if stored.Withheld {
+		jsonResponse(w, http.StatusConflict, map[string]string{"error": "the original response held a secret and cannot be replayed; send a new " + idempotencyHeader})
+		return
+	}
+	if stored.Status == 0 {
+		replayApplied(w, r, stored)
+		return
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	if len(stored.Response) == 0 {
+		w.WriteHeader(stored.Status)
+		return
+	}
+	// The data file stores the body indented; send it back as jsonResponse wrote it
+	var body bytes.Buffer
+	if err := json.Compact(&body, stored.Response); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	body.WriteByte('\n')
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(stored.Status)
+	_, _ = w.Write(body.Bytes())
+}
+
+// replayApplied answers a retry of a request whose write was recorded but whose
+// response was not, as when the server stopped in between. A create is
+// answered with the entry as it now stands; anything else can't be rebuilt.
+func replayApplied(w http.ResponseWriter, r *http.Request, stored models.IdempotencyKey) {
+	if stored.Action == models.AuditCreate {
+		units, err := parseUnits(r)
+		if err != nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		entry, found, err := createdEntry(stored.Resource, stored.ID, units)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if found {
+			w.Header().Set("Idempotent-Replayed", "true")
+			jsonResponse(w, http.StatusCreated, entry)
+			return
+		}
+	}
+	jsonResponse(w, http.StatusConflict, map[string]string{"error": "the request with this " + idempotencyHeader + " was applied, but its response was not kept; check the result rather than resending"})
+}
+
+// createdEntry loads an entry for a replayed create, in units as the create's
+// own response was. Resources other than entries are not found.
+func createdEntry(resource string, id int, units models.Units) (any, bool, error) {
+	switch resource {
+	case models.ResourceFeeds:
+		e, found, err := storage.FindEntry[models.FeedEntry](resource, id)
+		return e.InUnits(units), found, err
+	case models.ResourceSleep:
+		e, found, err := storage.FindEntry[models.SleepEntry](resource, id)
+		return e, found, err
+	case models.ResourceGrowth:
+		e, found, err := storage.FindEntry[models.GrowthEntry](resource, id)
+		return e.InUnits(units), found, err
+	case models.ResourceDiapers:
+		e, found, err := storage.FindEntry[models.DiaperEntry](resource, id)
+		return e, found, err
+	}
+	return nil, false, nil
+}
+
+// idempotencyActor identifies who sent a request, so a key can't be used to
+// read back someone else's response.
+func idempotencyActor(r *http.Request) string {
+	if u := userFrom(r); u.ID != 0 {
+		return fmt.Sprintf("user:%d", u.ID)
+	}
+	return "caregiver:" + caregiverFrom(r)
+}
+
+// withholdReplay marks the response being written as holding a secret (a token
+// shown only once), so it is not stored for Idempotency-Key replays.
+func withholdReplay(w http.ResponseWriter) {
+	if rw, ok := w.(*recordingWriter); ok {
+		rw.withheld = true
+	}
+}
+
+// recordingWriter passes a response through while keeping a copy of it.
+type recordingWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	withheld bool
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"babytracker/internal/auth"
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+type contextKey string
+
+const (
+	caregiverKey contextKey = "caregiver"
+	userKey      contextKey = "user"
+)
+
+// caregiverHeader names the caregiver making the request. Only honoured for the
+// shared API key and open mode — a personal token already says who is calling.
+const caregiverHeader = "X-Caregiver"
+
+// sharedOwner is the principal for the legacy shared API_KEY and for open mode
+// (no API_KEY and no accounts). It has no ID and no name.
+var sharedOwner = models.User{Role: models.RoleOwner}
+
+// authenticate resolves the bearer token to a user and stores it on the request
+// context. Personal tokens are checked first, then the legacy API_KEY. With no
+// API_KEY and no accounts the API stays open, as it was before accounts existed.
+// Failed attempts count towards the client's lockout.
+func authenticate(apiKey string, limiter *rateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == "OPTIONS" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+			user, err := auth.Authenticate(token)
+			if err != nil && !errors.Is(err, auth.ErrInvalidToken) {
+				jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			if user == nil {
+				switch {
+				case apiKey != "":
+					if subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) == 1 {
+						user = &sharedOwner
+					}
+				default:
+					hasUsers, err := auth.HasUsers()
+					if err != nil {
+						jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+						return
+					}
+					if !hasUsers {
+						user = &sharedOwner
+					}
+				}
+			}
+			if user == nil {
+				limiter.authFailed(clientIP(req))
+				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+			if token != "" {
+				limiter.authSucceeded(clientIP(req))
+			}
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), userKey, user)))
+		})
+	}
+}
+
+// userFrom returns the authenticated user for this request.
+func userFrom(r *http.Request) *models.User {
+	if u, ok := r.Context().Value(userKey).(*models.User); ok {
+		return u
+	}
+	return &sharedOwner
+}
+
+// requireRole wraps a handler so it only runs for users with at least the given role.
+func requireRole(min string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !userFrom(r).HasRole(min) {
+			jsonResponse(w, http.StatusForbidden, map[string]string{"error": "forbidden: requires " + min + " role"})
+			return
+		}
+		h(w, r)
+	}
+}
+
+// identifyCaregiver stores the caregiver name for the request on the context.
+// Account holders are identified by their user name; for the shared key the
+// X-Caregiver header is resolved against the caregiver registry, and unknown
+// names are rejected so a typo can't silently create a new "person".
+// The same name and the client address attribute storage changes in the audit log.
+func identifyCaregiver(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name := ""
+		if u := userFrom(req); u.ID != 0 {
+			name = u.Name
+		} else if header := req.Header.Get(caregiverHeader); header != "" {
+			c, err := storage.FindCaregiver(header)
+			if err != nil {
+				jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "unknown caregiver " + header})
+				return
+			}
+			name = c.Name
+		}
+		ctx := storage.WithActor(req.Context(), storage.Actor{Name: name, Address: clientIP(req)})
+		if name != "" {
+			ctx = context.WithValue(ctx, caregiverKey, name)
+		}
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// caregiverFrom returns the caregiver identified for this request ("" if anonymous).
+func caregiverFrom(r *http.Request) string {
+	name, _ := r.Context().Value(caregiverKey).(string)
+	return name
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// patchAttempts bounds how often a PATCH without If-Match is re-merged when
+// another write lands between reading the entry and saving it.
+const patchAttempts = 3
+
+// unitGroup is a unit field of an entry and the values measured in it, which
+// are stored in the canonical unit.
+type unitGroup struct {
+	unit      string   // e.g. "quantity_unit"
+	values    []string // e.g. "quantity"
+	requested string   // The unit ?units= reads and writes values in
+	canonical string   // The stored unit
+}
+
+// applyUnits makes a patch's measurements mean what the client meant. A value
+// sent without its unit is in the request's ?units=, so the unit is added to
+// the patch. A patch in any unit but the stored one must then carry every
+// recorded value measured in it: the others are stored in the canonical unit
+// and would be misread, e.g. 120 ml turned into 120 oz by {"quantity_unit":"oz"}.
+func applyUnits(patch, current []byte, groups []unitGroup) ([]byte, error) {
+	var fields, stored map[string]json.RawMessage
+	if len(groups) == 0 || json.Unmarshal(patch, &fields) != nil || json.Unmarshal(current, &stored) != nil {
+		return patch, nil // MergePatch reports a patch that isn't an object
+	}
+	changed := false
+	for _, g := range groups {
+		var unit string
+		if raw, ok := fields[g.unit]; ok {
+			if err := json.Unmarshal(raw, &unit); err != nil && string(raw) != "null" {
+				return nil, fmt.Errorf("%s must be a string", g.unit)
+			}
+		} else if slices.ContainsFunc(g.values, func(v string) bool { _, ok := fields[v]; return ok }) {
+			unit = g.requested
+			if unit != g.canonical {
+				fields[g.unit], _ = json.Marshal(unit)
+				changed = true
+			}
+		}
+		if unit == "" || unit == g.canonical {
+			continue
+		}
+		for _, v := range g.values {
+			if _, ok := fields[v]; !ok && string(stored[v]) != "0" {
+				return nil, fmt.Errorf("a patch in %s must set %s", unit, strings.Join(g.values, " and "))
+			}
+		}
+	}
+	if !changed {
+		return patch, nil
+	}
+	return json.Marshal(fields)
+}
+
+// patchEntry applies the request's JSON Merge Patch (RFC 7386) to a stored entry,
+// validates the merged entry and saves it. Measurements are read in the unit the
+// patch sets, else the request's ?units= (see applyUnits); a date must agree
+// with the entry's time, which it is derived from. If-Match works as for PUT.
+// Without it, the version merged against is still pinned, so a concurrent write
+// is merged again instead of being clobbered. On failure it writes the error
+// response and returns false.
+func patchEntry[T any](w http.ResponseWriter, r *http.Request, resource string, groups []unitGroup,
+	key func(T) (id, version int),
+	validate func(*T) error, update func(ctx context.Context, id, ifVersion int, entry *T) error) (T, bool) {
+	var merged T
+	id, ok := entryID(w, r, resource)
+	if !ok {
+		return merged, false
+	}
+	ifVersion, err := parseIfMatch(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return merged, false
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mt, _, _ := mime.ParseMediaType(ct); mt != "application/merge-patch+json" && mt != "application/json" {
+			jsonResponse(w, http.StatusUnsupportedMediaType, map[string]string{"error": "PATCH expects application/merge-patch+json"})
+			return merged, false
+		}
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "failed to read request body"})
+		return merged, false
+	}
+	log.Printf("Patch %s ID %d: %s\n", resource, id, patch)
+
+	for attempt := 1; ; attempt++ {
+		stored, found, err := storage.FindEntry[T](resource, id)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return merged, false
+		}
+		if !found {
+			jsonResponse(w, http.StatusNotFound, map[string]string{"error": "entry not found"})
+			return merged, false
+		}
+		_, version := key(stored)
+		pinned := ifVersion
+		if pinned == 0 {
+			pinned = models.EntryVersion(version)
+		}
+		current, err := json.Marshal(stored)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return merged, false
+		}
+		unitPatch, err := applyUnits(patch, current, groups)
+		if err != nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return merged, false
+		}
+		doc, err := models.MergePatch(current, unitPatch)
+		if err != nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return merged, false
+		}
+		merged = *new(T)
+		if err := json.Unmarshal(doc, &merged); err != nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+			return merged, false
+		}
+		if err := validate(&merged); err != nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return merged, false
+		}
+		// Validation derives the date from the time, which would quietly undo the patch
+		if err := checkPatchedDate(patch, merged); err != nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return merged, false
+		}
+		err = update(r.Context(), id, pinned, &merged)
+		if err == nil {
+			return merged, true
+		}
+		if ifVersion != 0 || !errors.Is(err, storage.ErrVersionMismatch) || attempt == patchAttempts {
+			writeChangeError(w, err)
+			return merged, false
+		}
+	}
+}
+
+// checkPatchedDate rejects a patch whose date differs from the one the merged
+// entry's time gives it.
+func checkPatchedDate[T any](patch []byte, merged T) error {
+	var sent struct {
+		Date *string `json:"date"`
+	}
+	if json.Unmarshal(patch, &sent) != nil || sent.Date == nil {
+		return nil
+	}
+	doc, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	var kept struct {
+		Date string `json:"date"`
+	}
+	json.Unmarshal(doc, &kept)
+	if kept.Date != *sent.Date {
+		return fmt.Errorf("date %s disagrees with the entry's time, which falls on %s; patch the time instead", *sent.Date, kept.Date)
+	}
+	return nil
+}
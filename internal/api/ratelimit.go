@@ -0,0 +1,192 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"babytracker/internal/config"
+)
+
+// pruneInterval is how often idle buckets and expired lockouts are dropped.
+const pruneInterval = time.Minute
+
+// bucket is a token bucket: it refills at rate tokens per second up to burst.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// lockout tracks consecutive authentication failures from one client.
+type lockout struct {
+	failures int
+	until    time.Time
+}
+
+// rateLimiter limits requests per client IP and per bearer token (FINDING-15),
+// and locks out clients after repeated authentication failures with an
+// exponentially growing lockout.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // tokens per second; 0 disables request limiting
+	burst     float64
+	failLimit int           // failures before lockout; 0 disables lockout
+	lockBase  time.Duration // first lockout, doubled for each further failure
+	lockMax   time.Duration
+	buckets   map[string]*bucket
+	lockouts  map[string]*lockout
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+func newRateLimiter(cfg *config.Config) *rateLimiter {
+	rl := &rateLimiter{
+		buckets:  map[string]*bucket{},
+		lockouts: map[string]*lockout{},
+		now:      time.Now,
+	}
+	rl.configure(cfg)
+	return rl
+}
+
+// configure sets the limits from cfg, keeping the clients' buckets and lockouts.
+func (rl *rateLimiter) configure(cfg *config.Config) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.rate = cfg.RateLimit
+	rl.burst = float64(cfg.RateBurst)
+	rl.failLimit = cfg.AuthFailLimit
+	rl.lockBase = cfg.AuthLockout
+	rl.lockMax = cfg.AuthLockoutMax
+}
+
+// allow takes a token from the bucket for key. When empty it returns how long
+// until the next token is available. Callers hold rl.mu.
+func (rl *rateLimiter) allow(key string) (bool, time.Duration) {
+	now := rl.now()
+	rl.pruneLocked(now)
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rl.burst, last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = math.Min(rl.burst, b.tokens+now.Sub(b.last).Seconds()*rl.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rl.rate * float64(time.Second))
+}
+
+// lockedFor returns the remaining lockout for a client, or 0.
+func (rl *rateLimiter) lockedFor(ip string) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if l, ok := rl.lockouts[ip]; ok {
+		if d := l.until.Sub(rl.now()); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// authFailed records a failed authentication. From the failLimit-th consecutive
+// failure on, the client is locked out for lockBase, doubling each time up to lockMax.
+func (rl *rateLimiter) authFailed(ip string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.failLimit <= 0 {
+		return
+	}
+	l, ok := rl.lockouts[ip]
+	if !ok {
+		l = &lockout{}
+		rl.lockouts[ip] = l
+	}
+	l.failures++
+	if over := l.failures - rl.failLimit; over >= 0 {
+		d := rl.lockBase << min(over, 30)
+		if d > rl.lockMax || d <= 0 {
+			d = rl.lockMax
+		}
+		l.until = rl.now().Add(d)
+	}
+}
+
+// authSucceeded clears a client's failure count.
+func (rl *rateLimiter) authSucceeded(ip string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	delete(rl.lockouts, ip)
+}
+
+// pruneLocked drops full buckets and expired lockouts with no recent failures.
+// Callers hold rl.mu.
+func (rl *rateLimiter) pruneLocked(now time.Time) {
+	if now.Sub(rl.lastPrune) < pruneInterval {
+		return
+	}
+	rl.lastPrune = now
+	for k, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rl.rate >= rl.burst {
+			delete(rl.buckets, k)
+		}
+	}
+	for k, l := range rl.lockouts {
+		if now.Sub(l.until) > rl.lockMax {
+			delete(rl.lockouts, k)
+		}
+	}
+}
+
+// middleware rejects clients that are locked out or over their request rate
+// with 429 and a Retry-After header. Requests are charged to the client IP and,
+// when present, to the bearer token, so one token can't be spread across IPs.
+func (rl *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ip := clientIP(req)
+		if d := rl.lockedFor(ip); d > 0 {
+			tooManyRequests(w, d, "too many failed sign-in attempts")
+			return
+		}
+		keys := []string{"ip:" + ip}
+		if token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "); token != "" {
+			sum := sha256.Sum256([]byte(token))
+			keys = append(keys, "token:"+hex.EncodeToString(sum[:8]))
+		}
+		rl.mu.Lock()
+		if rl.rate > 0 { // Read under the lock, since a reload can change it
+			for _, key := range keys {
+				if ok, wait := rl.allow(key); !ok {
+					rl.mu.Unlock()
+					tooManyRequests(w, wait, "rate limit exceeded")
+					return
+				}
+			}
+		}
+		rl.mu.Unlock()
+		next.ServeHTTP(w, req)
+	})
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration, msg string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	jsonResponse(w, http.StatusTooManyRequests, map[string]string{"error": msg})
+}
+
+// clientIP returns the connection's remote IP. Forwarding headers are ignored
+// because they are trivially spoofed by the client being limited.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"babytracker/internal/config"
+	"babytracker/internal/storage"
+)
+
+// fakeClock is a controllable time source for the limiter.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func testLimiter(cfg *config.Config) (*rateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 4, 6, 12, 0, 0, 0, time.UTC)}
+	rl := newRateLimiter(cfg)
+	rl.now = clock.now
+	return rl, clock
+}
+
+func TestTokenBucket(t *testing.T) {
+	rl, clock := testLimiter(&config.Config{RateLimit: 2, RateBurst: 3})
+	for i := 0; i < 3; i++ {
+		if ok, _ := rl.allow("ip:a"); !ok {
+			t.Fatalf("request %d within burst was rejected", i+1)
+		}
+	}
+	ok, wait := rl.allow("ip:a")
+	if ok {
+		t.Fatal("expected request beyond burst to be rejected")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("wait = %v, want 500ms at 2 req/s", wait)
+	}
+	if ok, _ := rl.allow("ip:b"); !ok {
+		t.Error("another client should have its own bucket")
+	}
+	clock.advance(500 * time.Millisecond)
+	if ok, _ := rl.allow("ip:a"); !ok {
+		t.Error("expected a token after refilling")
+	}
+}
+
+func TestAuthLockoutGrows(t *testing.T) {
+	rl, clock := testLimiter(&config.Config{AuthFailLimit: 3, AuthLockout: time.Minute, AuthLockoutMax: 5 * time.Minute})
+	rl.authFailed("10.0.0.1")
+	rl.authFailed("10.0.0.1")
+	if d := rl.lockedFor("10.0.0.1"); d != 0 {
+		t.Fatalf("locked out after 2 failures (limit 3): %v", d)
+	}
+	rl.authFailed("10.0.0.1")
+	if d := rl.lockedFor("10.0.0.1"); d != time.Minute {
+		t.Errorf("first lockout = %v, want 1m", d)
+	}
+	clock.advance(time.Minute)
+	rl.authFailed("10.0.0.1")
+	if d := rl.lockedFor("10.0.0.1"); d != 2*time.Minute {
+		t.Errorf("second lockout = %v, want 2m", d)
+	}
+	for i := 0; i < 5; i++ {
+		rl.authFailed("10.0.0.1")
+	}
+	if d := rl.lockedFor("10.0.0.1"); d != 5*time.Minute {
+		t.Errorf("lockout = %v, want capped at 5m", d)
+	}
+	rl.authSucceeded("10.0.0.1")
+	if d := rl.lockedFor("10.0.0.1"); d != 0 {
+		t.Errorf("lockout after success = %v, want 0", d)
+	}
+}
+
+func TestRepeatedUnauthorizedLocksOut(t *testing.T) {
+	if err := storage.Init(t.TempDir()); err != nil {
+		t.Fatalf("failed to init test storage: %v", err)
+	}
+	cfg := testConfig()
+	cfg.APIKey = "shared-secret"
+	cfg.AuthFailLimit = 3
+	cfg.AuthLockout = time.Minute
+	cfg.AuthLockoutMax = time.Hour
+	router := SetupRouter(cfg)
+
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest("GET", "/api/feeds", nil)
+		req.Header.Set("Authorization", "Bearer guess")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 after repeated failures, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "60" {
+		t.Errorf("Retry-After = %q, want 60", w.Header().Get("Retry-After"))
+	}
+
+	// Even the right key is refused while locked out
+	req := httptest.NewRequest("GET", "/api/feeds", nil)
+	req.Header.Set("Authorization", "Bearer shared-secret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 during lockout, got %d", w.Code)
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"babytracker/internal/config"
+)
+
+// Server is the API's handler chain, rebuilt and swapped in whole when its
+// settings are reloaded, so every request is served under one set of them.
+// The rate limiter is shared across reloads, keeping its buckets and lockouts,
+// as are the Idempotency-Keys of requests still running.
+type Server struct {
+	mu      sync.Mutex // Serializes Reload
+	cfg     *config.Config
+	limiter *rateLimiter
+	idem    *idempotency
+	handler atomic.Pointer[http.Handler]
+}
+
+// NewServer builds the API for cfg.
+func NewServer(cfg *config.Config) *Server {
+	s := &Server{cfg: cfg, limiter: newRateLimiter(cfg), idem: &idempotency{}}
+	h := newRouter(cfg, s.limiter, s.idem)
+	s.handler.Store(&h)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	(*s.handler.Load()).ServeHTTP(w, req)
+}
+
+// Reload applies next's settings to the running server and returns what
+// changed. If any changed setting needs a restart (the port, TLS, the data
+// directory, ...) nothing is applied and the error names them; requests keep
+// being served under the current settings either way.
+func (s *Server) Reload(next *config.Config) ([]config.Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := config.Diff(s.cfg, next)
+	var restart []string
+	for _, c := range changes {
+		if !c.Reloadable {
+			restart = append(restart, c.Setting)
+		}
+	}
+	if len(restart) > 0 {
+		return changes, fmt.Errorf("%s can only change on a restart; nothing was applied", strings.Join(restart, ", "))
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	// Keep the settings that can't change, such as the resolved Location
+	applied := *s.cfg
+	applied.CORSOrigins = next.CORSOrigins
+	applied.APIKey = next.APIKey
+	applied.ChildName = next.ChildName
+	applied.RateLimit, applied.RateBurst = next.RateLimit, next.RateBurst
+	applied.AuthFailLimit, applied.AuthLockout, applied.AuthLockoutMax = next.AuthFailLimit, next.AuthLockout, next.AuthLockoutMax
+	applied.VolumeUnit, applied.WeightUnit, applied.LengthUnit = next.VolumeUnit, next.WeightUnit, next.LengthUnit
+	applied.AppTitle, applied.DesktopProfile = next.AppTitle, next.DesktopProfile
+
+	s.limiter.configure(&applied)
+	h := newRouter(&applied, s.limiter, s.idem)
+	s.handler.Store(&h)
+	s.cfg = &applied
+	return changes, nil
+}
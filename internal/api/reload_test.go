@@ -0,0 +1,129 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"babytracker/internal/config"
+	"babytracker/internal/storage"
+)
+
+func TestServerReload(t *testing.T) {
+	if err := storage.Init(t.TempDir()); err != nil {
+		t.Fatalf("failed to init test storage: %v", err)
+	}
+	cfg := testConfig()
+	cfg.APIKey = "old-secret"
+	cfg.AuthFailLimit = 1
+	cfg.AuthLockout = time.Minute
+	cfg.AuthLockoutMax = time.Hour
+	srv := NewServer(cfg)
+
+	get := func(key, origin, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/feeds", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("Origin", origin)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	// Locked out under the old settings
+	get("guess", "", "10.0.0.9:1234")
+
+	next := *cfg
+	next.APIKey = "new-secret"
+	next.CORSOrigins = []string{"https://tracker.example"}
+	changes, err := srv.Reload(&next)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Setting != "CORS_ORIGINS" || changes[1].Setting != "API_KEY" {
+		t.Fatalf("changes = %v, want CORS_ORIGINS and API_KEY", changes)
+	}
+	if strings.Contains(changes[1].String(), "secret") {
+		t.Errorf("change revealed the API key: %s", changes[1])
+	}
+
+	if w := get("old-secret", "", "10.0.0.1:1234"); w.Code != http.StatusUnauthorized {
+		t.Errorf("old key after reload: status %d, want 401", w.Code)
+	}
+	w := get("new-secret", "https://tracker.example", "10.0.0.2:1234")
+	if w.Code != http.StatusOK {
+		t.Errorf("new key after reload: status %d, want 200", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://tracker.example" {
+		t.Errorf("new origin not allowed: %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if w := get("new-secret", "", "10.0.0.9:1234"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("lockout did not survive the reload: status %d", w.Code)
+	}
+
+	if changes, err := srv.Reload(&next); err != nil || len(changes) != 0 {
+		t.Errorf("reloading the same settings = %v, %v", changes, err)
+	}
+}
+
+func TestServerReload_NeedsRestart(t *testing.T) {
+	if err := storage.Init(t.TempDir()); err != nil {
+		t.Fatalf("failed to init test storage: %v", err)
+	}
+	cfg := testConfig()
+	cfg.APIKey = "old-secret"
+	srv := NewServer(cfg)
+
+	next := *cfg
+	next.APIKey = "new-secret"
+	next.APIPort = "9090"
+	changes, err := srv.Reload(&next)
+	if err == nil || !strings.Contains(err.Error(), "PORT") {
+		t.Fatalf("expected the port change to be rejected, got %v", err)
+	}
+	var restart []config.Change
+	for _, c := range changes {
+		if !c.Reloadable {
+			restart = append(restart, c)
+		}
+	}
+	if len(restart) != 1 || restart[0].Setting != "PORT" {
+		t.Errorf("restart-only changes = %v, want PORT", restart)
+	}
+
+	// Nothing was applied, not even the key
+	req := httptest.NewRequest("GET", "/api/feeds", nil)
+	req.Header.Set("Authorization", "Bearer old-secret")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("old key after a rejected reload: status %d, want 200", w.Code)
+	}
+}
+
+func TestServerReload_KeepsIdempotencyKeysInFlight(t *testing.T) {
+	if err := storage.Init(t.TempDir()); err != nil {
+		t.Fatalf("failed to init test storage: %v", err)
+	}
+	cfg := testConfig()
+	srv := NewServer(cfg)
+	// The first request with k1 is still running when the settings are reloaded
+	srv.idem.inFlight.Store(inFlightKey("caregiver:", "k1"), struct{}{})
+
+	next := *cfg
+	next.ChildName = "Ada"
+	if _, err := srv.Reload(&next); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	req := httptest.NewRequest("POST", "/api/feeds", strings.NewReader(`{"date":"2026-04-06","type":"Bottle","quantity":90}`))
+	req.Header.Set(idempotencyHeader, "k1")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("retry during a reload: status %d, want 409", w.Code)
+	}
+	if feeds, _ := storage.LoadFeeds(); len(feeds) != 0 {
+		t.Errorf("retry during a reload created %d feeds", len(feeds))
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"babytracker/internal/config"
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
 
 	"github.com/gorilla/mux"
 )
@@ -12,7 +14,14 @@ import (
 // SetupRouter sets up the mux router, CORS, auth, and all API endpoints.
 // Returns an http.Handler (not *mux.Router) because CORS wraps the router
 // to intercept OPTIONS preflight before mux's method matching rejects it.
+// Use NewServer instead to be able to reload the settings.
 func SetupRouter(cfg *config.Config) http.Handler {
+	return NewServer(cfg)
+}
+
+// newRouter builds the handler chain for cfg. The rate limiter and the
+// in-flight Idempotency-Keys are passed in because they must outlive a reload.
+func newRouter(cfg *config.Config, limiter *rateLimiter, idem *idempotency) http.Handler {
 	r := mux.NewRouter()
 
 	// Request body size limit — 1MB max (FINDING-08)
@@ -23,69 +32,131 @@ func SetupRouter(cfg *config.Config) http.Handler {
 		})
 	})
 
-	// API key auth middleware — skip if no key configured, skip OPTIONS
-	if cfg.APIKey != "" {
-		r.Use(func(next http.Handler) http.Handler {
-			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-				if req.Method == "OPTIONS" {
-					next.ServeHTTP(w, req)
-					return
-				}
-				token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
-				if token != cfg.APIKey {
-					http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
-					return
-				}
-				next.ServeHTTP(w, req)
-			})
-		})
-	}
+	// Rate limiting and sign-in lockout, ahead of any auth work (FINDING-15)
+	r.Use(limiter.middleware)
+
+	// Read-only share links carry their own signed authorization, so they live
+	// outside the /api subrouter and its bearer-token middleware
+	share := r.PathPrefix("/share/{token}").Subrouter()
+	share.Use(requireShare(cfg.ChildName))
+	share.HandleFunc("", handleShareReport(cfg)).Methods("GET")
+
+	api := r.PathPrefix("/api").Subrouter()
+
+	// Authentication — personal tokens, then the legacy shared API_KEY (FINDING-02)
+	api.Use(authenticate(cfg.APIKey, limiter))
+
+	// Caregiver attribution — runs after auth so only authenticated requests are resolved
+	api.Use(identifyCaregiver)
+
+	// Idempotency-Key replays — after attribution, since a key belongs to its caller
+	api.Use(idem.middleware)
+
+	// Per-route authorization: viewers read, caregivers also write, owners manage accounts
+	view := func(h http.HandlerFunc) http.HandlerFunc { return requireRole(models.RoleViewer, h) }
+	edit := func(h http.HandlerFunc) http.HandlerFunc { return requireRole(models.RoleCaregiver, h) }
+	own := func(h http.HandlerFunc) http.HandlerFunc { return requireRole(models.RoleOwner, h) }
 
 	// Feed endpoints
-	r.HandleFunc("/api/feeds", handleListFeeds).Methods("GET")
-	r.HandleFunc("/api/feeds", handleLogFeed).Methods("POST")
-	r.HandleFunc("/api/feeds/{id:[0-9]+}", handleGetFeed).Methods("GET")
-	r.HandleFunc("/api/feeds/{id:[0-9]+}", handleUpdateFeed).Methods("PUT")
-	r.HandleFunc("/api/feeds/{id:[0-9]+}", handleDeleteFeed).Methods("DELETE")
+	api.HandleFunc("/feeds", view(handleListFeeds)).Methods("GET")
+	api.HandleFunc("/feeds", edit(handleLogFeed)).Methods("POST")
+	api.HandleFunc("/feeds/"+entryIDPattern, view(handleGetFeed)).Methods("GET")
+	api.HandleFunc("/feeds/"+entryIDPattern, edit(handleUpdateFeed)).Methods("PUT")
+	api.HandleFunc("/feeds/"+entryIDPattern, edit(handlePatchFeed)).Methods("PATCH")
+	api.HandleFunc("/feeds/"+entryIDPattern, edit(handleDeleteFeed)).Methods("DELETE")
+	api.HandleFunc("/feeds/"+entryIDPattern+"/history", view(handleHistory(models.ResourceFeeds))).Methods("GET")
+	api.HandleFunc("/feeds/"+entryIDPattern+"/history/{revision:[0-9]+}/revert", edit(handleRevert(models.ResourceFeeds, storage.UpdateFeed))).Methods("POST")
 
 	// Sleep endpoints
-	r.HandleFunc("/api/sleep", handleListSleep).Methods("GET")
-	r.HandleFunc("/api/sleep", handleLogSleep).Methods("POST")
-	r.HandleFunc("/api/sleep/{id:[0-9]+}", handleGetSleep).Methods("GET")
-	r.HandleFunc("/api/sleep/{id:[0-9]+}", handleUpdateSleep).Methods("PUT")
-	r.HandleFunc("/api/sleep/{id:[0-9]+}", handleDeleteSleep).Methods("DELETE")
+	api.HandleFunc("/sleep", view(handleListSleep)).Methods("GET")
+	api.HandleFunc("/sleep", edit(handleLogSleep)).Methods("POST")
+	api.HandleFunc("/sleep/"+entryIDPattern, view(handleGetSleep)).Methods("GET")
+	api.HandleFunc("/sleep/"+entryIDPattern, edit(handleUpdateSleep)).Methods("PUT")
+	api.HandleFunc("/sleep/"+entryIDPattern, edit(handlePatchSleep)).Methods("PATCH")
+	api.HandleFunc("/sleep/"+entryIDPattern, edit(handleDeleteSleep)).Methods("DELETE")
+	api.HandleFunc("/sleep/"+entryIDPattern+"/history", view(handleHistory(models.ResourceSleep))).Methods("GET")
+	api.HandleFunc("/sleep/"+entryIDPattern+"/history/{revision:[0-9]+}/revert", edit(handleRevert(models.ResourceSleep, storage.UpdateSleep))).Methods("POST")
 
 	// Growth endpoints
-	r.HandleFunc("/api/growth", handleListGrowth).Methods("GET")
-	r.HandleFunc("/api/growth", handleLogGrowth).Methods("POST")
-	r.HandleFunc("/api/growth/{id:[0-9]+}", handleGetGrowth).Methods("GET")
-	r.HandleFunc("/api/growth/{id:[0-9]+}", handleUpdateGrowth).Methods("PUT")
-	r.HandleFunc("/api/growth/{id:[0-9]+}", handleDeleteGrowth).Methods("DELETE")
+	api.HandleFunc("/growth", view(handleListGrowth)).Methods("GET")
+	api.HandleFunc("/growth", edit(handleLogGrowth)).Methods("POST")
+	api.HandleFunc("/growth/"+entryIDPattern, view(handleGetGrowth)).Methods("GET")
+	api.HandleFunc("/growth/"+entryIDPattern, edit(handleUpdateGrowth)).Methods("PUT")
+	api.HandleFunc("/growth/"+entryIDPattern, edit(handlePatchGrowth)).Methods("PATCH")
+	api.HandleFunc("/growth/"+entryIDPattern, edit(handleDeleteGrowth)).Methods("DELETE")
+	api.HandleFunc("/growth/"+entryIDPattern+"/history", view(handleHistory(models.ResourceGrowth))).Methods("GET")
+	api.HandleFunc("/growth/"+entryIDPattern+"/history/{revision:[0-9]+}/revert", edit(handleRevert(models.ResourceGrowth, storage.UpdateGrowth))).Methods("POST")
 
 	// Diaper endpoints
-	r.HandleFunc("/api/diapers", handleListDiapers).Methods("GET")
-	r.HandleFunc("/api/diapers", handleLogDiaper).Methods("POST")
-	r.HandleFunc("/api/diapers/{id:[0-9]+}", handleGetDiaper).Methods("GET")
-	r.HandleFunc("/api/diapers/{id:[0-9]+}", handleUpdateDiaper).Methods("PUT")
-	r.HandleFunc("/api/diapers/{id:[0-9]+}", handleDeleteDiaper).Methods("DELETE")
+	api.HandleFunc("/diapers", view(handleListDiapers)).Methods("GET")
+	api.HandleFunc("/diapers", edit(handleLogDiaper)).Methods("POST")
+	api.HandleFunc("/diapers/"+entryIDPattern, view(handleGetDiaper)).Methods("GET")
+	api.HandleFunc("/diapers/"+entryIDPattern, edit(handleUpdateDiaper)).Methods("PUT")
+	api.HandleFunc("/diapers/"+entryIDPattern, edit(handlePatchDiaper)).Methods("PATCH")
+	api.HandleFunc("/diapers/"+entryIDPattern, edit(handleDeleteDiaper)).Methods("DELETE")
+	api.HandleFunc("/diapers/"+entryIDPattern+"/history", view(handleHistory(models.ResourceDiapers))).Methods("GET")
+	api.HandleFunc("/diapers/"+entryIDPattern+"/history/{revision:[0-9]+}/revert", edit(handleRevert(models.ResourceDiapers, storage.UpdateDiaper))).Methods("POST")
+
+	// Batch writes — an offline client's queue, applied all or nothing
+	api.HandleFunc("/batch", edit(handleBatch)).Methods("POST")
+
+	// Export & import — bundles match entries across data directories by UID
+	api.HandleFunc("/export", view(handleExport)).Methods("GET")
+	api.HandleFunc("/import", own(handleImport)).Methods("POST")
+
+	// Backups — archives of the whole data directory, so owners only
+	api.HandleFunc("/backups", own(handleListBackups)).Methods("GET")
+	api.HandleFunc("/backups", own(handleCreateBackup)).Methods("POST")
+	api.HandleFunc("/backups/{name}/restore", own(handleRestoreBackup)).Methods("POST")
+
+	// Summary endpoints
+	api.HandleFunc("/summary", view(handleDailySummary)).Methods("GET")
+
+	// Caregiver registry
+	api.HandleFunc("/caregivers", view(handleListCaregivers)).Methods("GET")
+	api.HandleFunc("/caregivers", own(handleAddCaregiver)).Methods("POST")
+	api.HandleFunc("/caregivers/{id:[0-9]+}", own(handleDeleteCaregiver)).Methods("DELETE")
+
+	// Accounts & tokens — token handlers scope non-owners to their own tokens
+	api.HandleFunc("/me", view(handleCurrentUser)).Methods("GET")
+	api.HandleFunc("/users", own(handleListUsers)).Methods("GET")
+	api.HandleFunc("/users", own(handleAddUser)).Methods("POST")
+	api.HandleFunc("/users/{id:[0-9]+}", own(handleDeleteUser)).Methods("DELETE")
+	api.HandleFunc("/tokens", view(handleListTokens)).Methods("GET")
+	api.HandleFunc("/tokens", view(handleCreateToken)).Methods("POST")
+	api.HandleFunc("/tokens/{id:[0-9]+}", view(handleRevokeToken)).Methods("DELETE")
+
+	// Trash — deleted entries stay restorable until purged after TRASH_RETENTION_DAYS
+	api.HandleFunc("/trash", view(handleListTrash)).Methods("GET")
+	api.HandleFunc("/trash/{resource}/"+entryIDPattern+"/restore", edit(handleRestore)).Methods("POST")
+
+	// Audit log — caregivers and owners can see who changed what
+	api.HandleFunc("/audit", edit(handleListAudit)).Methods("GET")
+
+	// Share links
+	api.HandleFunc("/shares", own(handleCreateShare(cfg))).Methods("POST")
 
 	// CORS wraps the entire router so OPTIONS preflight is handled before
-	// mux rejects it with 405 (routes only register GET/POST).
-	// If configured origin is localhost, accept any localhost port for dev.
-	return corsHandler(cfg.CORSOrigin, r)
+	// mux rejects it with 405 (routes only register GET/POST/PUT/PATCH/DELETE).
+	// Security headers wrap everything, including preflight responses.
+	return securityHeaders(corsHandler(cfg.CORSOrigins, r))
 }
 
-func corsHandler(corsOrigin string, next http.Handler) http.Handler {
-	isLocalhost := strings.HasPrefix(corsOrigin, "http://localhost")
+// corsHandler allows cross-origin requests only from the explicit allowlist (FINDING-03).
+// The request's Origin is echoed back when listed; other origins get no CORS headers.
+func corsHandler(origins []string, next http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		allowed[strings.TrimSuffix(o, "/")] = true
+	}
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		origin := req.Header.Get("Origin")
-		allowedOrigin := corsOrigin
-		if isLocalhost && strings.HasPrefix(origin, "http://localhost") {
-			allowedOrigin = origin
+		w.Header().Add("Vary", "Origin")
+		if origin := req.Header.Get("Origin"); allowed[origin] {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Caregiver, If-Match, If-None-Match, Idempotency-Key")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed")
+			w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
 		}
-		w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
 		if req.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -93,3 +164,24 @@ func corsHandler(corsOrigin string, next http.Handler) http.Handler {
 		next.ServeHTTP(w, req)
 	})
 }
+
+// securityHeaders sets browser hardening headers on every response (FINDING-05).
+// The API only serves JSON, so its CSP forbids everything; share reports may use
+// their inline stylesheet. HSTS is only sent over HTTPS.
+func securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		if strings.HasPrefix(req.URL.Path, "/share/") {
+			h.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'")
+		} else {
+			h.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+		}
+		if req.TLS != nil {
+			h.Set("Strict-Transport-Security", "max-age=31536000")
+		}
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"babytracker/internal/auth"
+	"babytracker/internal/config"
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+const (
+	shareClaimsKey contextKey = "share"
+
+	defaultShareDays     = 30
+	defaultShareLifetime = 7 * 24 * time.Hour
+	maxShareLifetime     = 90 * 24 * time.Hour
+)
+
+//go:embed templates/share.html
+var templateFS embed.FS
+
+var shareTemplate = template.Must(template.New("share.html").Funcs(template.FuncMap{
+	"clock": func(ft models.FlexTime) string {
+		if ft.IsZero() {
+			return ""
+		}
+		return ft.InHousehold().Format("15:04")
+	},
+	"hours": func(minutes int) float64 { return float64(minutes) / 60 },
+}).ParseFS(templateFS, "templates/share.html"))
+
+// shareReport is the data rendered by templates/share.html.
+type shareReport struct {
+	Child    string
+	From, To string
+	Expires  string
+	Units    models.Units
+	Claims   models.ShareClaims
+	Days     []models.DailySummary // Newest first; only allowed resources are counted
+	Feeds    []models.FeedEntry
+	Sleep    []models.SleepEntry
+	Diapers  []models.DiaperEntry
+	Growth   []models.GrowthEntry
+}
+
+// handleCreateShare signs a read-only share link. Defaults to every resource,
+// the last 30 days and a 7-day expiry.
+func handleCreateShare(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Resources      []string `json:"resources"`
+			From           string   `json:"from"`
+			To             string   `json:"to"`
+			Days           int      `json:"days"`
+			ExpiresInHours int      `json:"expires_in_hours"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+		if len(req.Resources) == 0 {
+			req.Resources = models.AllResources
+		}
+		if req.To == "" {
+			req.To = models.Now().Format("2006-01-02")
+		}
+		if req.From == "" {
+			if req.Days <= 0 {
+				req.Days = defaultShareDays
+			}
+			to, err := time.Parse("2006-01-02", req.To)
+			if err != nil {
+				jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid to date"})
+				return
+			}
+			req.From = to.AddDate(0, 0, 1-req.Days).Format("2006-01-02")
+		}
+		lifetime := defaultShareLifetime
+		if req.ExpiresInHours > 0 {
+			lifetime = time.Duration(req.ExpiresInHours) * time.Hour
+		}
+		if lifetime > maxShareLifetime {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "share links can last at most 90 days"})
+			return
+		}
+		claims := models.ShareClaims{
+			Child:     cfg.ChildName,
+			Resources: req.Resources,
+			From:      req.From,
+			To:        req.To,
+			ExpiresAt: time.Now().Add(lifetime).UTC().Truncate(time.Second),
+		}
+		// The range bounds how much work every view of the link does
+		if err := claims.Validate(); err != nil {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		token, err := auth.SignShare(claims)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("Create Share: %v %s..%s until %s\n", claims.Resources, claims.From, claims.To, claims.ExpiresAt)
+		withholdReplay(w)
+		jsonResponse(w, http.StatusCreated, map[string]interface{}{
+			"token":  token,
+			"url":    "/share/" + token,
+			"claims": claims,
+		})
+	}
+}
+
+// requireShare validates the {token} path segment of /share routes and stores
+// its claims on the context. Links for another child are treated as invalid.
+func requireShare(childName string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			claims, err := auth.VerifyShare(mux.Vars(req)["token"], time.Now())
+			if err == nil && !models.SameName(claims.Child, childName) {
+				err = auth.ErrInvalidShare
+			}
+			switch {
+			case errors.Is(err, auth.ErrShareExpired):
+				http.Error(w, "This share link has expired.", http.StatusGone)
+				return
+			case errors.Is(err, auth.ErrInvalidShare):
+				http.Error(w, "Share link not found.", http.StatusNotFound)
+				return
+			case err != nil:
+				log.Printf("Share verification failed: %v", err)
+				http.Error(w, "Internal error.", http.StatusInternalServerError)
+				return
+			}
+			// The token is a credential: keep it out of caches, logs of other sites and search engines
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("Referrer-Policy", "no-referrer")
+			w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), shareClaimsKey, claims)))
+		})
+	}
+}
+
+// handleShareReport renders the read-only HTML report for a share link.
+func handleShareReport(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value(shareClaimsKey).(*models.ShareClaims)
+		report, err := buildShareReport(cfg, claims)
+		if err != nil {
+			log.Printf("Share report failed: %v", err)
+			http.Error(w, "Internal error.", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := shareTemplate.Execute(w, report); err != nil {
+			log.Printf("Share report render failed: %v", err)
+		}
+	}
+}
+
+// buildShareReport loads the allowed resources in the shared date range, newest
+// first, reading only the months it covers.
+func buildShareReport(cfg *config.Config, c *models.ShareClaims) (*shareReport, error) {
+	units := cfg.Units()
+	report := &shareReport{
+		Child:   c.Child,
+		From:    c.From,
+		To:      c.To,
+		Expires: c.ExpiresAt.In(models.Location()).Format("2 Jan 2006 15:04"),
+		Units:   units,
+		Claims:  *c,
+	}
+	q := models.EntryQuery{From: c.From, To: c.To}
+	var feeds []models.FeedEntry // canonical ml, for the daily totals
+	var err error
+	if c.Allows(models.ResourceFeeds) {
+		if feeds, _, err = storage.QueryEntries[models.FeedEntry](models.ResourceFeeds, q); err != nil {
+			return nil, err
+		}
+		for _, f := range feeds {
+			report.Feeds = append(report.Feeds, f.InUnits(units))
+		}
+	}
+	if c.Allows(models.ResourceSleep) {
+		if report.Sleep, _, err = storage.QueryEntries[models.SleepEntry](models.ResourceSleep, q); err != nil {
+			return nil, err
+		}
+	}
+	if c.Allows(models.ResourceDiapers) {
+		if report.Diapers, _, err = storage.QueryEntries[models.DiaperEntry](models.ResourceDiapers, q); err != nil {
+			return nil, err
+		}
+	}
+	if c.Allows(models.ResourceGrowth) {
+		growth, _, err := storage.QueryEntries[models.GrowthEntry](models.ResourceGrowth, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range growth {
+			report.Growth = append(report.Growth, g.InUnits(units))
+		}
+	}
+
+	if len(report.Feeds)+len(report.Sleep)+len(report.Diapers) > 0 {
+		for _, day := range c.Days() {
+			s, err := models.SummarizeDay(day, feeds, report.Sleep, report.Diapers)
+			if err != nil {
+				return nil, err
+			}
+			report.Days = append(report.Days, s.InUnits(units))
+		}
+	}
+	return report, nil
+}
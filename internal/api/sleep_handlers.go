@@ -2,25 +2,20 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"strconv"
-
-	"github.com/gorilla/mux"
 
 	"babytracker/internal/models"
 	"babytracker/internal/storage"
 )
 
 func handleListSleep(w http.ResponseWriter, r *http.Request) {
-	entries, err := storage.LoadSleep()
-	if err != nil {
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	page, total, q, ok := listEntries(w, r, models.ResourceSleep, func(e models.SleepEntry) (string, string) { return e.Date, e.LoggedBy })
+	if !ok {
 		return
 	}
-	limit, offset := parsePagination(r)
-	page, total := paginateReverse(entries, limit, offset)
-	jsonResponse(w, http.StatusOK, PaginatedResponse{Items: page, Total: total, Limit: limit, Offset: offset})
+	conditionalJSON(w, r, PaginatedResponse{Items: page, Total: total, Limit: q.Limit, Offset: q.Offset})
 }
 
 func handleLogSleep(w http.ResponseWriter, r *http.Request) {
@@ -29,42 +24,45 @@ func handleLogSleep(w http.ResponseWriter, r *http.Request) {
 		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
 		return
 	}
-	if entry.Date == "" || entry.Type == "" {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "missing required fields (date, type)"})
+	if err := validateSleep(&entry); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
+	entry.LoggedBy = caregiverFrom(r)
 	log.Printf("Log Sleep: %+v\n", entry)
-	if err := storage.SaveSleep(&entry); err != nil {
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := storage.SaveSleep(r.Context(), &entry); err != nil {
+		writeCreateError(w, err)
 		return
 	}
 	jsonResponse(w, http.StatusCreated, entry)
 }
 
 func handleGetSleep(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
-	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+	id, ok := entryID(w, r, models.ResourceSleep)
+	if !ok {
 		return
 	}
-	entries, err := storage.LoadSleep()
+	e, found, err := storage.FindEntry[models.SleepEntry](models.ResourceSleep, id)
 	if err != nil {
 		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	for _, e := range entries {
-		if e.ID == id {
-			jsonResponse(w, http.StatusOK, e)
-			return
-		}
+	if !found {
+		jsonResponse(w, http.StatusNotFound, map[string]string{"error": "sleep entry not found"})
+		return
 	}
-	jsonResponse(w, http.StatusNotFound, map[string]string{"error": "sleep entry not found"})
+	w.Header().Set("ETag", entityTag(e.Version))
+	jsonResponse(w, http.StatusOK, e)
 }
 
 func handleUpdateSleep(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	id, ok := entryID(w, r, models.ResourceSleep)
+	if !ok {
+		return
+	}
+	ifVersion, err := parseIfMatch(r)
 	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	var entry models.SleepEntry
@@ -72,29 +70,53 @@ func handleUpdateSleep(w http.ResponseWriter, r *http.Request) {
 		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
 		return
 	}
-	if entry.Date == "" || entry.Type == "" {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "missing required fields (date, type)"})
+	if err := validateSleep(&entry); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	log.Printf("Update Sleep ID %d: %+v\n", id, entry)
-	if err := storage.UpdateSleep(id, &entry); err != nil {
-		jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	if err := storage.UpdateSleep(r.Context(), id, ifVersion, &entry); err != nil {
+		writeChangeError(w, err)
 		return
 	}
 	entry.ID = id
+	w.Header().Set("ETag", entityTag(entry.Version))
+	jsonResponse(w, http.StatusOK, entry)
+}
+
+func handlePatchSleep(w http.ResponseWriter, r *http.Request) {
+	entry, ok := patchEntry(w, r, models.ResourceSleep, nil,
+		func(e models.SleepEntry) (int, int) { return e.ID, e.Version }, validateSleep, storage.UpdateSleep)
+	if !ok {
+		return
+	}
+	w.Header().Set("ETag", entityTag(entry.Version))
 	jsonResponse(w, http.StatusOK, entry)
 }
 
 func handleDeleteSleep(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	id, ok := entryID(w, r, models.ResourceSleep)
+	if !ok {
+		return
+	}
+	ifVersion, err := parseIfMatch(r)
 	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 	log.Printf("Delete Sleep ID %d\n", id)
-	if err := storage.DeleteSleep(id); err != nil {
-		jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	if err := storage.DeleteSleep(r.Context(), id, ifVersion); err != nil {
+		writeChangeError(w, err)
 		return
 	}
-	jsonResponse(w, http.StatusOK, map[string]string{"status": "deleted"})
+	jsonResponse(w, http.StatusOK, deletedResponse(models.ResourceSleep, id))
+}
+
+// validateSleep normalizes a sleep entry from a request and checks its required fields.
+func validateSleep(entry *models.SleepEntry) error {
+	entry.SyncDate()
+	if entry.Date == "" || entry.Type == "" {
+		return errors.New("missing required fields (date, type)")
+	}
+	return nil
 }
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// handleDailySummary returns activity totals for ?date=YYYY-MM-DD (default: today),
+// with day boundaries computed in the household time zone. Supports ?units=imperial.
+func handleDailySummary(w http.ResponseWriter, r *http.Request) {
+	units, err := parseUnits(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = models.Now().Format("2006-01-02")
+	}
+	feeds, err := storage.LoadFeeds()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	sleeps, err := storage.LoadSleep()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	diapers, err := storage.LoadDiapers()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	summary, err := models.SummarizeDay(date, feeds, sleeps, diapers)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	jsonResponse(w, http.StatusOK, summary.InUnits(units))
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// deletedResponse is returned by the DELETE endpoints, pointing at the restore
+// endpoint so clients can offer undo.
+func deletedResponse(resource string, id int) map[string]string {
+	return map[string]string{
+		"status":  "deleted",
+		"restore": "/api/trash/" + resource + "/" + strconv.Itoa(id) + "/restore",
+	}
+}
+
+// handleListTrash returns trashed entries, most recently deleted first (paginated).
+// ?resource= limits the list to one of feeds, sleep, growth or diapers.
+func handleListTrash(w http.ResponseWriter, r *http.Request) {
+	resources := models.AllResources
+	if v := r.URL.Query().Get("resource"); v != "" {
+		if !slices.Contains(models.AllResources, v) {
+			jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "unknown resource " + v})
+			return
+		}
+		resources = []string{v}
+	}
+	var items []models.TrashItem
+	for _, resource := range resources {
+		trash, err := storage.LoadTrash(resource)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		items = append(items, trash...)
+	}
+	// Oldest deletion first, so paginateReverse yields the most recent first
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DeletedAt.Before(items[j].DeletedAt) })
+	limit, offset := parsePagination(r)
+	page, total := paginateReverse(items, limit, offset)
+	jsonResponse(w, http.StatusOK, PaginatedResponse{Items: page, Total: total, Limit: limit, Offset: offset})
+}
+
+// handleRestore moves a trashed entry back into its resource.
+func handleRestore(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	resource := vars["resource"]
+	if !slices.Contains(models.AllResources, resource) {
+		jsonResponse(w, http.StatusNotFound, map[string]string{"error": "unknown resource " + resource})
+		return
+	}
+	id, ok := entryID(w, r, resource)
+	if !ok {
+		return
+	}
+	log.Printf("Restore %s ID %d\n", resource, id)
+	if err := storage.RestoreEntry(r.Context(), resource, id); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, storage.ErrNotInTrash):
+			status = http.StatusNotFound
+		case errors.Is(err, storage.ErrRestoreConflict):
+			status = http.StatusConflict
+		}
+		jsonResponse(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	jsonResponse(w, http.StatusOK, map[string]string{"status": "restored"})
+}
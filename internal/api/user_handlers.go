@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"babytracker/internal/auth"
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// issuedToken is the create-token response — the only time the plaintext is shown.
+type issuedToken struct {
+	models.APIToken
+	Token string `json:"token"`
+}
+
+func handleCurrentUser(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, http.StatusOK, userFrom(r))
+}
+
+func handleListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := storage.LoadUsers()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	jsonResponse(w, http.StatusOK, users)
+}
+
+func handleAddUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Role == "" {
+		req.Role = models.RoleCaregiver
+	}
+	if req.Name == "" {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "missing required field (name)"})
+		return
+	}
+	if err := models.ValidateRole(req.Role); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("Add User: %s (%s)\n", req.Name, req.Role)
+	u, err := auth.CreateUser(r.Context(), req.Name, req.Role)
+	if err != nil {
+		status := http.StatusConflict
+		if u != nil {
+			status = http.StatusInternalServerError // user saved, caregiver registration failed
+		}
+		jsonResponse(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	jsonResponse(w, http.StatusCreated, u)
+}
+
+func handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+		return
+	}
+	if id == userFrom(r).ID {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "cannot delete your own account"})
+		return
+	}
+	log.Printf("Delete User ID %d\n", id)
+	if err := storage.DeleteUser(r.Context(), id); err != nil {
+		jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	jsonResponse(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// handleListTokens lists token metadata: all tokens for owners, otherwise the caller's own.
+func handleListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := storage.LoadTokens()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	me := userFrom(r)
+	visible := make([]models.APIToken, 0, len(tokens))
+	for _, t := range tokens {
+		if me.HasRole(models.RoleOwner) || t.UserID == me.ID {
+			visible = append(visible, t.Redacted())
+		}
+	}
+	jsonResponse(w, http.StatusOK, visible)
+}
+
+// handleCreateToken issues a token for the caller, or for user_id when the caller is an owner.
+func handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Label  string `json:"label"`
+		UserID int    `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	me := userFrom(r)
+	if req.UserID == 0 {
+		req.UserID = me.ID
+	}
+	if req.UserID == 0 {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "user_id is required when using the shared API key"})
+		return
+	}
+	if req.UserID != me.ID && !me.HasRole(models.RoleOwner) {
+		jsonResponse(w, http.StatusForbidden, map[string]string{"error": "forbidden: requires owner role"})
+		return
+	}
+	log.Printf("Create Token for User ID %d (%s)\n", req.UserID, req.Label)
+	plain, t, err := auth.IssueToken(r.Context(), req.UserID, req.Label)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	withholdReplay(w)
+	jsonResponse(w, http.StatusCreated, issuedToken{APIToken: t.Redacted(), Token: plain})
+}
+
+// handleRevokeToken deletes one of the caller's tokens; owners may revoke any token.
+func handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+		return
+	}
+	tokens, err := storage.LoadTokens()
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	me := userFrom(r)
+	for _, t := range tokens {
+		if t.ID != id {
+			continue
+		}
+		if t.UserID != me.ID && !me.HasRole(models.RoleOwner) {
+			break // don't reveal that someone else's token exists
+		}
+		log.Printf("Revoke Token ID %d\n", id)
+		if err := storage.DeleteToken(r.Context(), id); err != nil {
+			jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		jsonResponse(w, http.StatusOK, map[string]string{"status": "revoked"})
+		return
+	}
+	jsonResponse(w, http.StatusNotFound, map[string]string{"error": "token not found"})
+}
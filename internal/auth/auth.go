@@ -0,0 +1,129 @@
+// Package auth manages user accounts and their per-user API tokens.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// tokenPrefix makes tokens recognisable in config files and secret scanners.
+const tokenPrefix = "bt_"
+
+// ErrInvalidToken is returned when a bearer token matches no stored token.
+var ErrInvalidToken = errors.New("invalid token")
+
+// HashToken returns the hex SHA-256 of a token. Tokens carry 256 bits of
+// randomness, so a fast hash is enough — there is nothing to brute-force.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueToken creates a token for the user, stores its hash and returns the plaintext.
+// The plaintext cannot be recovered later.
+func IssueToken(ctx context.Context, userID int, label string) (string, *models.APIToken, error) {
+	if _, err := storage.FindUser(userID); err != nil {
+		return "", nil, err
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	plain := tokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	t := &models.APIToken{
+		UserID:    userID,
+		Label:     strings.TrimSpace(label),
+		Hash:      HashToken(plain),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := storage.SaveToken(ctx, t); err != nil {
+		return "", nil, err
+	}
+	return plain, t, nil
+}
+
+// Authenticate resolves a bearer token to the user it belongs to.
+func Authenticate(token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+	tokens, err := storage.LoadTokens()
+	if err != nil {
+		return nil, err
+	}
+	hash := []byte(HashToken(token))
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) == 1 {
+			return storage.FindUser(t.UserID)
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+// HasUsers reports whether any account exists. Without accounts (and without a
+// legacy API_KEY) the API runs open, as it did before accounts existed.
+func HasUsers() (bool, error) {
+	users, err := storage.LoadUsers()
+	if err != nil {
+		return false, err
+	}
+	return len(users) > 0, nil
+}
+
+// CreateUser validates and stores a new account. Owners and caregivers are also
+// added to the caregiver registry so their name can be picked in the desktop app.
+func CreateUser(ctx context.Context, name, role string) (*models.User, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("missing required field (name)")
+	}
+	if err := models.ValidateRole(role); err != nil {
+		return nil, err
+	}
+	u := &models.User{Name: name, Role: role, CreatedAt: time.Now().UTC()}
+	if err := storage.SaveUser(ctx, u); err != nil {
+		return nil, err
+	}
+	if u.HasRole(models.RoleCaregiver) {
+		if _, err := storage.FindCaregiver(name); err != nil {
+			if err := storage.SaveCaregiver(ctx, &models.Caregiver{Name: name}); err != nil {
+				return u, fmt.Errorf("user created but caregiver registration failed: %w", err)
+			}
+		}
+	}
+	return u, nil
+}
+
+// BootstrapOwner creates the first owner account and an initial token for it.
+// It refuses to run once an owner exists, so it can't be used to take over a household.
+func BootstrapOwner(ctx context.Context, name string) (*models.User, string, error) {
+	users, err := storage.LoadUsers()
+	if err != nil {
+		return nil, "", err
+	}
+	for _, u := range users {
+		if u.Role == models.RoleOwner {
+			return nil, "", fmt.Errorf("an owner already exists (%s); create further users through the API", u.Name)
+		}
+	}
+	u, err := CreateUser(ctx, name, models.RoleOwner)
+	if err != nil {
+		return nil, "", err
+	}
+	token, _, err := IssueToken(ctx, u.ID, "bootstrap")
+	if err != nil {
+		return nil, "", err
+	}
+	return u, token, nil
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+func setupTestStorage(t *testing.T) {
+	t.Helper()
+	if err := storage.Init(t.TempDir()); err != nil {
+		t.Fatalf("failed to init test storage: %v", err)
+	}
+}
+
+func TestIssueAndAuthenticate(t *testing.T) {
+	setupTestStorage(t)
+	u, err := CreateUser(context.Background(), "Asha", models.RoleCaregiver)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	token, stored, err := IssueToken(context.Background(), u.ID, "phone")
+	if err != nil {
+		t.Fatalf("IssueToken failed: %v", err)
+	}
+	if !strings.HasPrefix(token, tokenPrefix) {
+		t.Errorf("token %q missing %q prefix", token, tokenPrefix)
+	}
+	if stored.Hash == token || stored.Hash != HashToken(token) {
+		t.Error("expected only the token hash to be stored")
+	}
+
+	got, err := Authenticate(token)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if got.ID != u.ID {
+		t.Errorf("authenticated as user %d, want %d", got.ID, u.ID)
+	}
+	if _, err := Authenticate(token + "x"); err != ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken for wrong token, got %v", err)
+	}
+
+	if err := storage.DeleteToken(context.Background(), stored.ID); err != nil {
+		t.Fatalf("DeleteToken failed: %v", err)
+	}
+	if _, err := Authenticate(token); err != ErrInvalidToken {
+		t.Errorf("expected revoked token to be rejected, got %v", err)
+	}
+}
+
+func TestCreateUserRegistersCaregiver(t *testing.T) {
+	setupTestStorage(t)
+	if _, err := CreateUser(context.Background(), "Ravi", models.RoleCaregiver); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, err := storage.FindCaregiver("Ravi"); err != nil {
+		t.Errorf("expected Ravi in caregiver registry: %v", err)
+	}
+	if _, err := CreateUser(context.Background(), "Nani", models.RoleViewer); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, err := storage.FindCaregiver("Nani"); err == nil {
+		t.Error("viewers should not be registered as caregivers")
+	}
+	if _, err := CreateUser(context.Background(), "Bob", "admin"); err == nil {
+		t.Error("expected error for unknown role")
+	}
+}
+
+func TestBootstrapOwnerOnlyOnce(t *testing.T) {
+	setupTestStorage(t)
+	u, token, err := BootstrapOwner(context.Background(), "Mum")
+	if err != nil {
+		t.Fatalf("BootstrapOwner failed: %v", err)
+	}
+	if u.Role != models.RoleOwner || token == "" {
+		t.Errorf("got role %q and token %q, want owner with a token", u.Role, token)
+	}
+	if _, _, err := BootstrapOwner(context.Background(), "Intruder"); err == nil {
+		t.Error("expected second bootstrap to be refused")
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// shareKeyFile holds the HMAC key for share links. Deleting it revokes every link.
+const shareKeyFile = "share.key"
+
+var (
+	// ErrInvalidShare is returned for malformed or tampered share links.
+	ErrInvalidShare = errors.New("invalid share link")
+	// ErrShareExpired is returned for correctly signed links past their expiry.
+	ErrShareExpired = errors.New("share link has expired")
+)
+
+// SignShare validates the claims and returns a URL-safe token of the form
+// base64url(claims JSON) "." base64url(HMAC-SHA256).
+func SignShare(c models.ShareClaims) (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+	key, err := storage.LoadOrCreateSecret(shareKeyFile, 32)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode share claims: %w", err)
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(shareMAC(key, body)), nil
+}
+
+// VerifyShare checks a token's signature and expiry and returns its claims.
+func VerifyShare(token string, now time.Time) (*models.ShareClaims, error) {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidShare
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, ErrInvalidShare
+	}
+	// No key means no link was ever signed; an anonymous request must not create one
+	key, found, err := storage.LoadSecret(shareKeyFile, 32)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrInvalidShare
+	}
+	if !hmac.Equal(mac, shareMAC(key, body)) {
+		return nil, ErrInvalidShare
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrInvalidShare
+	}
+	var c models.ShareClaims
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil || c.Validate() != nil {
+		return nil, ErrInvalidShare
+	}
+	if !now.Before(c.ExpiresAt) {
+		return nil, ErrShareExpired
+	}
+	return &c, nil
+}
+
+func shareMAC(key []byte, body string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(body))
+	return h.Sum(nil)
+}
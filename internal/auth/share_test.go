@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+func testClaims() models.ShareClaims {
+	return models.ShareClaims{
+		Child:     "Baby",
+		Resources: []string{models.ResourceFeeds, models.ResourceGrowth},
+		From:      "2026-04-01",
+		To:        "2026-04-30",
+		ExpiresAt: time.Date(2026, 5, 7, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestShareRoundTrip(t *testing.T) {
+	setupTestStorage(t)
+	token, err := SignShare(testClaims())
+	if err != nil {
+		t.Fatalf("SignShare failed: %v", err)
+	}
+	c, err := VerifyShare(token, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("VerifyShare failed: %v", err)
+	}
+	if !c.Allows(models.ResourceGrowth) || c.Allows(models.ResourceSleep) {
+		t.Errorf("unexpected resources %v", c.Resources)
+	}
+	if _, err := VerifyShare(token, time.Date(2026, 5, 8, 0, 0, 0, 0, time.UTC)); err != ErrShareExpired {
+		t.Errorf("expected ErrShareExpired, got %v", err)
+	}
+}
+
+func TestVerifyShareWithoutKey(t *testing.T) {
+	setupTestStorage(t)
+	if _, err := VerifyShare("eyJ9.c2ln", time.Now()); err != ErrInvalidShare {
+		t.Errorf("VerifyShare without a key = %v, want ErrInvalidShare", err)
+	}
+	if _, found, err := storage.LoadSecret(shareKeyFile, 32); found || err != nil {
+		t.Errorf("VerifyShare created %s (%v)", shareKeyFile, err)
+	}
+}
+
+func TestShareTampered(t *testing.T) {
+	setupTestStorage(t)
+	token, err := SignShare(testClaims())
+	if err != nil {
+		t.Fatalf("SignShare failed: %v", err)
+	}
+	widened := testClaims()
+	widened.Resources = models.AllResources
+	other, err := SignShare(widened)
+	if err != nil {
+		t.Fatalf("SignShare failed: %v", err)
+	}
+	// Swap in the wider claims with the original signature
+	body, _, _ := strings.Cut(other, ".")
+	_, sig, _ := strings.Cut(token, ".")
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, bad := range []string{body + "." + sig, "garbage", token + "x"} {
+		if _, err := VerifyShare(bad, now); err != ErrInvalidShare {
+			t.Errorf("VerifyShare(%q) = %v, want ErrInvalidShare", bad, err)
+		}
+	}
+}
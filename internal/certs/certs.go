@@ -0,0 +1,234 @@
+// Package certs generates and persists a self-signed CA and server certificate
+// so the API can serve HTTPS on a home network without an external CA (FINDING-04).
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// File names inside the TLS directory.
+const (
+	CAFile     = "ca.pem"
+	caKeyFile  = "ca-key.pem"
+	CertFile   = "server.pem"
+	KeyFile    = "server-key.pem"
+	caValidity = 10 * 365 * 24 * time.Hour
+	// Apple platforms reject server certificates valid for more than 825 days
+	serverValidity = 825 * 24 * time.Hour
+	renewBefore    = 30 * 24 * time.Hour
+)
+
+// EnsureSelfSigned makes sure dir holds a CA and a server certificate signed by it
+// covering localhost plus hosts (DNS names or IP addresses). The CA is created once
+// and kept, so phones that trust it keep working; the server certificate is reissued
+// when it nears expiry or a host is missing. Returns the server cert and key paths.
+func EnsureSelfSigned(dir string, hosts []string) (certFile, keyFile string, err error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", fmt.Errorf("failed to create TLS directory: %w", err)
+	}
+	ca, caKey, err := loadOrCreateCA(dir)
+	if err != nil {
+		return "", "", err
+	}
+	certFile = filepath.Join(dir, CertFile)
+	keyFile = filepath.Join(dir, KeyFile)
+	names := serverNames(hosts)
+	if leaf, err := readCert(certFile); err == nil && serverCertUsable(leaf, ca, names) {
+		return certFile, keyFile, nil
+	}
+	if err := issueServerCert(dir, ca, caKey, names); err != nil {
+		return "", "", err
+	}
+	return certFile, keyFile, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the first certificate in a PEM
+// file, as colon-separated upper-case hex (the format browsers display).
+func Fingerprint(pemFile string) (string, error) {
+	cert, err := readCert(pemFile)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":"), nil
+}
+
+// serverNames returns the default names plus extra hosts, de-duplicated.
+func serverNames(hosts []string) []string {
+	names := []string{"localhost", "127.0.0.1", "::1"}
+	if h, err := os.Hostname(); err == nil && h != "" {
+		names = append(names, h)
+	}
+	for _, h := range hosts {
+		if h = strings.TrimSpace(h); h != "" && !slices.Contains(names, h) {
+			names = append(names, h)
+		}
+	}
+	return names
+}
+
+func serverCertUsable(leaf, ca *x509.Certificate, names []string) bool {
+	if time.Until(leaf.NotAfter) < renewBefore {
+		return false
+	}
+	if leaf.CheckSignatureFrom(ca) != nil {
+		return false
+	}
+	for _, n := range names {
+		if leaf.VerifyHostname(n) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func loadOrCreateCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	caPath := filepath.Join(dir, CAFile)
+	caKeyPath := filepath.Join(dir, caKeyFile)
+	ca, certErr := readCert(caPath)
+	key, keyErr := readKey(caKeyPath)
+	if certErr == nil && keyErr == nil {
+		return ca, key, nil
+	}
+	if !errors.Is(certErr, os.ErrNotExist) && certErr != nil {
+		return nil, nil, certErr
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: "Baby Tracker Local CA", Organization: []string{"Baby Tracker"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	if err := writeKey(caKeyPath, key); err != nil {
+		return nil, nil, err
+	}
+	if err := writePEM(caPath, "CERTIFICATE", der, 0644); err != nil {
+		return nil, nil, err
+	}
+	ca, err = x509.ParseCertificate(der)
+	return ca, key, err
+}
+
+func issueServerCert(dir string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, names []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate server key: %w", err)
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: names[0], Organization: []string{"Baby Tracker"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(serverValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, n := range names {
+		if ip := net.ParseIP(n); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, n)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return fmt.Errorf("failed to create server certificate: %w", err)
+	}
+	if err := writeKey(filepath.Join(dir, KeyFile), key); err != nil {
+		return err
+	}
+	// Chain the CA so clients that pin or trust it can build the path
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})...)
+	return writeFile(filepath.Join(dir, CertFile), data, 0644)
+}
+
+func randomSerial() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(err)
+	}
+	return serial
+}
+
+func readCert(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s does not contain a PEM certificate", filepath.Base(path))
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func readKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM key", filepath.Base(path))
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+func writeKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode key: %w", err)
+	}
+	return writePEM(path, "EC PRIVATE KEY", der, 0600)
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	return writeFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), perm)
+}
+
+// writeFile writes atomically via temp file + rename, like the storage package.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, err := EnsureSelfSigned(dir, []string{"babytracker.local", "192.168.1.20"})
+	if err != nil {
+		t.Fatalf("EnsureSelfSigned failed: %v", err)
+	}
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		t.Fatalf("generated pair does not load: %v", err)
+	}
+
+	ca, err := readCert(filepath.Join(dir, CAFile))
+	if err != nil {
+		t.Fatalf("readCert(ca) failed: %v", err)
+	}
+	leaf, err := readCert(certFile)
+	if err != nil {
+		t.Fatalf("readCert(server) failed: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	for _, host := range []string{"localhost", "127.0.0.1", "babytracker.local", "192.168.1.20"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+			t.Errorf("certificate does not verify for %s: %v", host, err)
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(dir, caKeyFile))
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("CA key permissions = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestEnsureSelfSignedReuse(t *testing.T) {
+	dir := t.TempDir()
+	certFile, _, err := EnsureSelfSigned(dir, nil)
+	if err != nil {
+		t.Fatalf("EnsureSelfSigned failed: %v", err)
+	}
+	caPrint, _ := Fingerprint(filepath.Join(dir, CAFile))
+	first, _ := Fingerprint(certFile)
+
+	if _, _, err := EnsureSelfSigned(dir, nil); err != nil {
+		t.Fatalf("EnsureSelfSigned failed: %v", err)
+	}
+	if again, _ := Fingerprint(certFile); again != first {
+		t.Error("server certificate was reissued although still valid")
+	}
+
+	// A new host reissues the server certificate but keeps the CA
+	if _, _, err := EnsureSelfSigned(dir, []string{"nursery.local"}); err != nil {
+		t.Fatalf("EnsureSelfSigned failed: %v", err)
+	}
+	if again, _ := Fingerprint(certFile); again == first {
+		t.Error("expected server certificate to be reissued for a new host")
+	}
+	if again, _ := Fingerprint(filepath.Join(dir, CAFile)); again != caPrint {
+		t.Error("CA must not change when the server certificate is reissued")
+	}
+}
@@ -1,47 +1,281 @@
 // Package config provides centralized configuration for the Baby Tracker application.
-// Values are read from environment variables with sensible defaults.
-// Set values via a .env file (loaded by the caller) or export them directly.
+// Each value is taken from a command-line flag, else an environment variable,
+// else the TOML config file, else a sensible default.
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // embedded zone database so TIMEZONE works on hosts without one
+
+	"babytracker/internal/models"
 )
 
 // Config holds all application configuration.
 type Config struct {
-	APIPort  string // HTTP port for the API server
-	DataDir  string // Directory for JSON data files
-	AppTitle string // Desktop window title
-	APIKey     string // Shared secret for API authentication (empty = no auth)
-	CORSOrigin string // Allowed CORS origin (default: http://localhost:3000)
+	APIPort        string   // HTTP port for the API server
+	DataDir        string   // Directory for JSON data files
+	PassphraseFile string   // File holding the data directory passphrase (encrypted directories only)
+	AppTitle       string   // Desktop window title
+	ChildName      string   // Child's name, shown on and signed into share links
+	APIKey         string   // Legacy shared secret, authenticates as an owner (empty + no accounts = no auth)
+	CORSOrigins    []string // Origins allowed to call the API from a browser (exact match)
+	BindAddr       string   // Interface the API listens on (default: loopback only)
+	TLSCertFile    string   // PEM certificate (chain) for HTTPS; requires TLSKeyFile
+	TLSKeyFile     string   // PEM private key for TLSCertFile
+	TLSSelfSigned  bool     // Without a cert/key, generate a self-signed CA + server cert in DataDir/tls
+	TLSHosts       []string // Extra DNS names / IPs for the self-signed server cert (e.g. babytracker.local)
+
+	ReadHeaderTimeout time.Duration // Max time to read request headers
+	ReadTimeout       time.Duration // Max time to read a whole request
+	WriteTimeout      time.Duration // Max time to write a response
+	IdleTimeout       time.Duration // Keep-alive idle connection lifetime
+	ShutdownTimeout   time.Duration // How long SIGINT/SIGTERM waits for in-flight requests
+
+	RateLimit          float64        // Sustained requests per second per client IP and per token (0 = unlimited)
+	RateBurst          int            // Requests allowed in a burst above RateLimit
+	AuthFailLimit      int            // Failed sign-ins from one IP before lockout (0 = never lock out)
+	AuthLockout        time.Duration  // First lockout, doubled for each further failure
+	AuthLockoutMax     time.Duration  // Cap on the lockout
+	TrashRetentionDays int            // Days deleted entries stay restorable before being purged (0 = forever)
+	IdempotencyHours   int            // Hours an Idempotency-Key is remembered and replayed (0 = forever)
+	BackupInterval     time.Duration  // How often the API server backs up the data directory (0 = never)
+	BackupKeepHourly   int            // Scheduled backups kept: newest of each of the last N hours,
+	BackupKeepDaily    int            // days,
+	BackupKeepWeekly   int            // and ISO weeks (all 0 = keep every backup)
+	TimeZone           string         // Household IANA time zone, e.g. Europe/London (empty = system local zone)
+	Location           *time.Location // TimeZone resolved by Load
+	VolumeUnit         string         // Household preference for feed quantities: ml or oz
+	WeightUnit         string         // Household preference for weight: kg or lb
+	LengthUnit         string         // Household preference for height and head circumference: cm or in
+
+	DesktopProfile string // Caregiver the desktop app logs entries as (selectable at runtime)
+
+	File    string            // Config file the settings were read from ("" = none)
+	sources map[string]string // Where each setting not left at its default came from, for Print
 }
 
 // Default values
 const (
-	DefaultAPIPort    = "8080"
-	DefaultDataDir    = ".babytracker"
-	DefaultAppTitle   = "Baby Tracker"
-	DefaultCORSOrigin = "http://localhost:3000"
+	DefaultAPIPort     = "8080"
+	DefaultDataDir     = ".babytracker"
+	DefaultAppTitle    = "Baby Tracker"
+	DefaultChildName   = "Baby"
+	DefaultCORSOrigins = "http://localhost:3000,http://localhost:3005" // Vite preview and dev server
+	DefaultBindAddr    = "127.0.0.1"
+	DefaultVolumeUnit  = models.UnitML
+	DefaultWeightUnit  = models.UnitKg
+	DefaultLengthUnit  = models.UnitCm
+
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultReadTimeout       = 15 * time.Second
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 2 * time.Minute
+	DefaultShutdownTimeout   = 30 * time.Second
+
+	DefaultRateLimit      = 10
+	DefaultRateBurst      = 40
+	DefaultAuthFailLimit  = 5
+	DefaultAuthLockout    = time.Minute
+	DefaultAuthLockoutMax = time.Hour
+
+	DefaultTrashRetentionDays = 30
+	DefaultIdempotencyHours   = 24
+	DefaultBackupInterval     = 24 * time.Hour
+	DefaultBackupKeepHourly   = 24
+	DefaultBackupKeepDaily    = 7
+	DefaultBackupKeepWeekly   = 4
 )
 
-// Load reads configuration from environment variables, falling back to defaults.
+// Load reads configuration from environment variables and the config file,
+// falling back to defaults. See LoadFlags.
 //
-// Supported environment variables:
+// Supported environment variables (config file keys are the same in lower case):
 //
 //	PORT           - API server port (default: 8080)
+//	BIND_ADDR      - Interface to listen on; 0.0.0.0 for all (default: 127.0.0.1)
+//	CORS_ORIGINS   - Comma-separated browser origins allowed to call the API
+//	                 (default: http://localhost:3000,http://localhost:3005; CORS_ORIGIN still accepted)
 //	DATA_DIR       - Absolute path for data storage (default: ~/.babytracker)
+//	DATA_PASSPHRASE_FILE - File containing the passphrase of an encrypted data directory
 //	APP_TITLE      - Desktop window title (default: Baby Tracker)
+//	CHILD_NAME     - Child's name for share links and reports (default: Baby)
+//	TLS_CERT_FILE  - PEM certificate for HTTPS (with TLS_KEY_FILE)
+//	TLS_KEY_FILE   - PEM private key for HTTPS (with TLS_CERT_FILE)
+//	TLS_SELF_SIGNED - true to generate a self-signed certificate when no cert is set (default: false)
+//	TLS_HOSTS      - Comma-separated extra hosts/IPs for the self-signed certificate
+//	READ_HEADER_TIMEOUT, READ_TIMEOUT, WRITE_TIMEOUT, IDLE_TIMEOUT, SHUTDOWN_TIMEOUT
+//	               - API server timeouts as Go durations, e.g. 30s (defaults: 5s, 15s, 30s, 2m, 30s)
+//	RATE_LIMIT     - Requests per second per client IP and per token, 0 = off (default: 10)
+//	RATE_BURST     - Burst size above RATE_LIMIT (default: 40)
+//	AUTH_FAIL_LIMIT - Failed sign-ins per IP before lockout, 0 = off (default: 5)
+//	AUTH_LOCKOUT, AUTH_LOCKOUT_MAX - First and maximum lockout; doubles per failure (defaults: 1m, 1h)
+//	TRASH_RETENTION_DAYS - Days deleted entries stay in the trash, 0 = forever (default: 30)
+//	IDEMPOTENCY_RETENTION_HOURS - Hours Idempotency-Key responses are replayed, 0 = forever (default: 24)
+//	BACKUP_INTERVAL - How often the API server backs up the data directory, 0 = never (default: 24h)
+//	BACKUP_KEEP_HOURLY, BACKUP_KEEP_DAILY, BACKUP_KEEP_WEEKLY
+//	               - Scheduled backups kept per hour, day and week; all 0 = keep all (defaults: 24, 7, 4)
+//	TIMEZONE       - Household IANA time zone (default: system local zone)
+//	VOLUME_UNIT    - Feed quantity unit, ml or oz (default: ml)
+//	WEIGHT_UNIT    - Weight unit, kg or lb (default: kg)
+//	LENGTH_UNIT    - Length unit, cm or in (default: cm)
+//	DESKTOP_PROFILE - Caregiver name the desktop app logs as (default: none)
+//	CONFIG_FILE    - Config file to read (default: DefaultFile, if it exists)
 func Load() (*Config, error) {
+	return LoadFlags(nil)
+}
+
+// LoadFlags is Load with the command-line flags from RegisterFlags, which
+// take precedence over the environment and the config file. A config file
+// named by -config or CONFIG_FILE must exist. Errors name the flag, variable
+// or file key the bad value came from.
+func LoadFlags(f *Flags) (*Config, error) {
+	l := &loader{sources: map[string]string{}}
+	if f != nil {
+		l.flags = f.values
+	}
+	path := os.Getenv("CONFIG_FILE")
+	if f != nil && f.File != "" {
+		path = f.File
+	}
+	if path == "" {
+		if def := DefaultFile(); def != "" {
+			if _, err := os.Stat(def); err == nil {
+				path = def
+			}
+		}
+	}
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		l.file, l.path = values, path
+	}
+
 	cfg := &Config{
-		APIPort:    envOr("PORT", DefaultAPIPort),
-		AppTitle:   envOr("APP_TITLE", DefaultAppTitle),
-		APIKey:     os.Getenv("API_KEY"),
-		CORSOrigin: envOr("CORS_ORIGIN", DefaultCORSOrigin),
+		APIPort:        l.or("PORT", DefaultAPIPort),
+		AppTitle:       l.or("APP_TITLE", DefaultAppTitle),
+		ChildName:      l.or("CHILD_NAME", DefaultChildName),
+		APIKey:         l.get("API_KEY"),
+		PassphraseFile: l.get("DATA_PASSPHRASE_FILE"),
+		CORSOrigins:    splitList(l.or("CORS_ORIGINS", envOr("CORS_ORIGIN", DefaultCORSOrigins))),
+		BindAddr:       l.or("BIND_ADDR", DefaultBindAddr),
+		TLSCertFile:    l.get("TLS_CERT_FILE"),
+		TLSKeyFile:     l.get("TLS_KEY_FILE"),
+		TLSHosts:       splitList(l.get("TLS_HOSTS")),
+		TimeZone:       l.get("TIMEZONE"),
+		Location:       time.Local,
+		VolumeUnit:     l.or("VOLUME_UNIT", DefaultVolumeUnit),
+		WeightUnit:     l.or("WEIGHT_UNIT", DefaultWeightUnit),
+		LengthUnit:     l.or("LENGTH_UNIT", DefaultLengthUnit),
+
+		DesktopProfile: l.get("DESKTOP_PROFILE"),
+
+		File:    path,
+		sources: l.sources,
+	}
+
+	if err := validPort(cfg.APIPort); err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", l.name("PORT"), cfg.APIPort, err)
+	}
+
+	if err := cfg.Units().Validate(); err != nil {
+		return nil, fmt.Errorf("invalid unit preference: %w", err)
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if v := l.get("TLS_SELF_SIGNED"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", l.name("TLS_SELF_SIGNED"), v, err)
+		}
+		cfg.TLSSelfSigned = b
+	}
+
+	timeouts := []struct {
+		env      string
+		dst      *time.Duration
+		fallback time.Duration
+	}{
+		{"READ_HEADER_TIMEOUT", &cfg.ReadHeaderTimeout, DefaultReadHeaderTimeout},
+		{"READ_TIMEOUT", &cfg.ReadTimeout, DefaultReadTimeout},
+		{"WRITE_TIMEOUT", &cfg.WriteTimeout, DefaultWriteTimeout},
+		{"IDLE_TIMEOUT", &cfg.IdleTimeout, DefaultIdleTimeout},
+		{"SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout, DefaultShutdownTimeout},
+		{"AUTH_LOCKOUT", &cfg.AuthLockout, DefaultAuthLockout},
+		{"AUTH_LOCKOUT_MAX", &cfg.AuthLockoutMax, DefaultAuthLockoutMax},
+	}
+	for _, t := range timeouts {
+		*t.dst = t.fallback
+		if v := l.get(t.env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid %s %q: expected a positive duration like 30s", l.name(t.env), v)
+			}
+			*t.dst = d
+		}
+	}
+
+	cfg.RateLimit = DefaultRateLimit
+	if v := l.get("RATE_LIMIT"); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || r < 0 {
+			return nil, fmt.Errorf("invalid %s %q: expected requests per second, 0 to disable", l.name("RATE_LIMIT"), v)
+		}
+		cfg.RateLimit = r
+	}
+	counts := []struct {
+		env      string
+		dst      *int
+		fallback int
+	}{
+		{"RATE_BURST", &cfg.RateBurst, DefaultRateBurst},
+		{"AUTH_FAIL_LIMIT", &cfg.AuthFailLimit, DefaultAuthFailLimit},
+		{"TRASH_RETENTION_DAYS", &cfg.TrashRetentionDays, DefaultTrashRetentionDays},
+		{"IDEMPOTENCY_RETENTION_HOURS", &cfg.IdempotencyHours, DefaultIdempotencyHours},
+		{"BACKUP_KEEP_HOURLY", &cfg.BackupKeepHourly, DefaultBackupKeepHourly},
+		{"BACKUP_KEEP_DAILY", &cfg.BackupKeepDaily, DefaultBackupKeepDaily},
+		{"BACKUP_KEEP_WEEKLY", &cfg.BackupKeepWeekly, DefaultBackupKeepWeekly},
+	}
+	for _, c := range counts {
+		*c.dst = c.fallback
+		if v := l.get(c.env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s %q: expected a non-negative integer", l.name(c.env), v)
+			}
+			*c.dst = n
+		}
+	}
+	cfg.BackupInterval = DefaultBackupInterval
+	if v := l.get("BACKUP_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid %s %q: expected a duration like 6h, 0 to disable", l.name("BACKUP_INTERVAL"), v)
+		}
+		cfg.BackupInterval = d
+	}
+	if cfg.RateLimit > 0 && cfg.RateBurst < 1 {
+		return nil, fmt.Errorf("RATE_BURST must be at least 1 when RATE_LIMIT is set")
+	}
+
+	if cfg.TimeZone != "" {
+		loc, err := time.LoadLocation(cfg.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", l.name("TIMEZONE"), cfg.TimeZone, err)
+		}
+		cfg.Location = loc
 	}
 
 	// Data directory: use DATA_DIR if set, otherwise ~/.babytracker
-	if dir := os.Getenv("DATA_DIR"); dir != "" {
+	if dir := l.get("DATA_DIR"); dir != "" {
 		cfg.DataDir = dir
 	} else {
 		homeDir, err := os.UserHomeDir()
@@ -50,10 +284,154 @@ func Load() (*Config, error) {
 		}
 		cfg.DataDir = filepath.Join(homeDir, DefaultDataDir)
 	}
+	if err := checkDataDir(cfg.DataDir); err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", l.name("DATA_DIR"), cfg.DataDir, err)
+	}
 
 	return cfg, nil
 }
 
+// loader looks settings up in the flags, the environment and the config file,
+// in that order, and remembers where each was found.
+type loader struct {
+	flags   map[string]string
+	file    map[string]string
+	path    string // The config file
+	sources map[string]string
+}
+
+// get returns a setting's value, "" when it is set nowhere. An empty
+// environment variable counts as unset; an empty flag does not.
+func (l *loader) get(env string) string {
+	if v, ok := l.flags[env]; ok {
+		l.sources[env] = "flag -" + flagName(env)
+		return v
+	}
+	if v := os.Getenv(env); v != "" {
+		l.sources[env] = "env " + env
+		return v
+	}
+	if v, ok := l.file[env]; ok {
+		l.sources[env] = "file"
+		return v
+	}
+	return ""
+}
+
+// or is get with a fallback for a setting that is set nowhere or empty.
+func (l *loader) or(env, fallback string) string {
+	if v := l.get(env); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// name is how an error should refer to a setting: the flag, the config file
+// key or the environment variable it was read from.
+func (l *loader) name(env string) string {
+	switch src := l.sources[env]; {
+	case strings.HasPrefix(src, "flag"):
+		return "-" + flagName(env)
+	case src == "file":
+		return fmt.Sprintf("%s in %s", fileKey(env), l.path)
+	}
+	return env
+}
+
+// validPort checks that a port is a number from 1 to 65535.
+func validPort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("expected a port number from 1 to 65535")
+	}
+	return nil
+}
+
+// checkDataDir checks that the data directory is an absolute path without ".."
+// elements and that it, or the nearest parent that exists when it doesn't yet,
+// is a directory. It doesn't write there: Load runs on every reload and config
+// print, so storage checks the directory is writable once, when it opens it.
+func checkDataDir(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("must be an absolute path")
+	}
+	for _, elem := range strings.Split(filepath.ToSlash(dir), "/") {
+		if elem == ".." {
+			return fmt.Errorf("must not contain ..")
+		}
+	}
+	existing := dir
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", existing)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return err
+		}
+		existing = parent
+	}
+	return nil
+}
+
+// Units returns the household unit preferences. Unset fields fall back to metric.
+func (c *Config) Units() models.Units {
+	u := models.MetricUnits
+	if c.VolumeUnit != "" {
+		u.Volume = c.VolumeUnit
+	}
+	if c.WeightUnit != "" {
+		u.Weight = c.WeightUnit
+	}
+	if c.LengthUnit != "" {
+		u.Length = c.LengthUnit
+	}
+	return u
+}
+
+// TrashRetention is how long deleted entries stay restorable (0 = forever).
+func (c *Config) TrashRetention() time.Duration {
+	return time.Duration(c.TrashRetentionDays) * 24 * time.Hour
+}
+
+// IdempotencyRetention is how long a request's Idempotency-Key is remembered (0 = forever).
+func (c *Config) IdempotencyRetention() time.Duration {
+	return time.Duration(c.IdempotencyHours) * time.Hour
+}
+
+// BackupPolicy is how many scheduled backups to keep.
+func (c *Config) BackupPolicy() models.BackupPolicy {
+	return models.BackupPolicy{Hourly: c.BackupKeepHourly, Daily: c.BackupKeepDaily, Weekly: c.BackupKeepWeekly}
+}
+
+// TLSEnabled reports whether the API server should serve HTTPS.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || c.TLSSelfSigned
+}
+
+// TLSDir is where self-signed certificates are generated and kept.
+func (c *Config) TLSDir() string {
+	return filepath.Join(c.DataDir, "tls")
+}
+
+// splitList splits a comma-separated value, dropping empty items.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func envOr(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad_Defaults(t *testing.T) {
@@ -45,3 +46,143 @@ func TestLoad_FromEnv(t *testing.T) {
 		t.Errorf("AppTitle = %q, want %q", cfg.AppTitle, "Test Tracker")
 	}
 }
+
+func TestLoad_TimeZone(t *testing.T) {
+	t.Setenv("TIMEZONE", "Europe/Berlin")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Location == nil || cfg.Location.String() != "Europe/Berlin" {
+		t.Errorf("Location = %v, want Europe/Berlin", cfg.Location)
+	}
+
+	t.Setenv("TIMEZONE", "Mars/Olympus_Mons")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for unknown TIMEZONE")
+	}
+}
+
+func TestLoad_Units(t *testing.T) {
+	t.Setenv("VOLUME_UNIT", "oz")
+	t.Setenv("WEIGHT_UNIT", "lb")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	u := cfg.Units()
+	if u.Volume != "oz" || u.Weight != "lb" || u.Length != DefaultLengthUnit {
+		t.Errorf("Units() = %+v, want oz/lb/%s", u, DefaultLengthUnit)
+	}
+
+	t.Setenv("LENGTH_UNIT", "furlong")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for unknown LENGTH_UNIT")
+	}
+}
+
+func TestLoad_TLS(t *testing.T) {
+	t.Setenv("TLS_SELF_SIGNED", "true")
+	t.Setenv("TLS_HOSTS", "babytracker.local, 192.168.1.20,")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if !cfg.TLSEnabled() {
+		t.Error("expected TLS to be enabled")
+	}
+	if len(cfg.TLSHosts) != 2 || cfg.TLSHosts[1] != "192.168.1.20" {
+		t.Errorf("TLSHosts = %q, want [babytracker.local 192.168.1.20]", cfg.TLSHosts)
+	}
+
+	t.Setenv("TLS_CERT_FILE", "/etc/ssl/cert.pem")
+	if _, err := Load(); err == nil {
+		t.Error("expected error when TLS_CERT_FILE is set without TLS_KEY_FILE")
+	}
+}
+
+func TestLoad_Timeouts(t *testing.T) {
+	t.Setenv("WRITE_TIMEOUT", "1m")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.WriteTimeout != time.Minute {
+		t.Errorf("WriteTimeout = %v, want 1m", cfg.WriteTimeout)
+	}
+	if cfg.TrashRetention() != DefaultTrashRetentionDays*24*time.Hour {
+		t.Errorf("TrashRetention = %v, want %d days", cfg.TrashRetention(), DefaultTrashRetentionDays)
+	}
+	if cfg.IdempotencyRetention() != DefaultIdempotencyHours*time.Hour {
+		t.Errorf("IdempotencyRetention = %v, want %d hours", cfg.IdempotencyRetention(), DefaultIdempotencyHours)
+	}
+	if cfg.ShutdownTimeout != DefaultShutdownTimeout {
+		t.Errorf("ShutdownTimeout = %v, want default %v", cfg.ShutdownTimeout, DefaultShutdownTimeout)
+	}
+
+	t.Setenv("IDLE_TIMEOUT", "forever")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for invalid IDLE_TIMEOUT")
+	}
+}
+
+func TestLoad_Backups(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.BackupInterval != DefaultBackupInterval || cfg.BackupPolicy().Weekly != DefaultBackupKeepWeekly {
+		t.Errorf("backup defaults = %v, %+v", cfg.BackupInterval, cfg.BackupPolicy())
+	}
+
+	t.Setenv("BACKUP_INTERVAL", "0")
+	t.Setenv("BACKUP_KEEP_DAILY", "14")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.BackupInterval != 0 || cfg.BackupPolicy().Daily != 14 {
+		t.Errorf("BackupInterval = %v, policy %+v; want 0 and 14 daily", cfg.BackupInterval, cfg.BackupPolicy())
+	}
+
+	t.Setenv("BACKUP_INTERVAL", "-1h")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for a negative BACKUP_INTERVAL")
+	}
+}
+
+func TestLoad_BindAndCORS(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.BindAddr != DefaultBindAddr {
+		t.Errorf("BindAddr = %q, want loopback default %q", cfg.BindAddr, DefaultBindAddr)
+	}
+	if len(cfg.CORSOrigins) != 2 {
+		t.Errorf("CORSOrigins = %q, want the two default dev origins", cfg.CORSOrigins)
+	}
+
+	t.Setenv("BIND_ADDR", "0.0.0.0")
+	t.Setenv("CORS_ORIGIN", "https://legacy.example")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.BindAddr != "0.0.0.0" {
+		t.Errorf("BindAddr = %q, want 0.0.0.0", cfg.BindAddr)
+	}
+	if len(cfg.CORSOrigins) != 1 || cfg.CORSOrigins[0] != "https://legacy.example" {
+		t.Errorf("CORSOrigins = %q, want legacy CORS_ORIGIN", cfg.CORSOrigins)
+	}
+
+	t.Setenv("CORS_ORIGINS", "https://a.example, https://b.example")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.CORSOrigins) != 2 || cfg.CORSOrigins[1] != "https://b.example" {
+		t.Errorf("CORSOrigins = %q, want CORS_ORIGINS to take precedence", cfg.CORSOrigins)
+	}
+}
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// setting is one configuration value, named by its environment variable. In
+// the config file its key is the name in lower case (bind_addr) and on the
+// command line a flag with dashes (-bind-addr).
+type setting struct {
+	env    string
+	usage  string
+	secret bool                   // Never taken from a flag, and redacted by Print
+	value  func(c *Config) string // The effective value, for Print
+}
+
+// settings lists every value Load reads, in the order Print shows them.
+var settings = []setting{
+	{"PORT", "API server port", false, func(c *Config) string { return c.APIPort }},
+	{"BIND_ADDR", "interface the API listens on; 0.0.0.0 for all", false, func(c *Config) string { return c.BindAddr }},
+	{"CORS_ORIGINS", "comma-separated browser origins allowed to call the API", false, func(c *Config) string { return strings.Join(c.CORSOrigins, ",") }},
+	{"DATA_DIR", "absolute path of the data directory", false, func(c *Config) string { return c.DataDir }},
+	{"DATA_PASSPHRASE_FILE", "file containing the passphrase of an encrypted data directory", false, func(c *Config) string { return c.PassphraseFile }},
+	{"API_KEY", "legacy shared secret that authenticates as an owner", true, func(c *Config) string { return c.APIKey }},
+	{"APP_TITLE", "desktop window title", false, func(c *Config) string { return c.AppTitle }},
+	{"CHILD_NAME", "child's name for share links and reports", false, func(c *Config) string { return c.ChildName }},
+	{"TLS_CERT_FILE", "PEM certificate for HTTPS (with tls-key-file)", false, func(c *Config) string { return c.TLSCertFile }},
+	{"TLS_KEY_FILE", "PEM private key for HTTPS (with tls-cert-file)", false, func(c *Config) string { return c.TLSKeyFile }},
+	{"TLS_SELF_SIGNED", "true to generate a self-signed certificate when no cert is set", false, func(c *Config) string { return strconv.FormatBool(c.TLSSelfSigned) }},
+	{"TLS_HOSTS", "comma-separated extra hosts/IPs for the self-signed certificate", false, func(c *Config) string { return strings.Join(c.TLSHosts, ",") }},
+	{"READ_HEADER_TIMEOUT", "max time to read request headers", false, func(c *Config) string { return c.ReadHeaderTimeout.String() }},
+	{"READ_TIMEOUT", "max time to read a whole request", false, func(c *Config) string { return c.ReadTimeout.String() }},
+	{"WRITE_TIMEOUT", "max time to write a response", false, func(c *Config) string { return c.WriteTimeout.String() }},
+	{"IDLE_TIMEOUT", "keep-alive idle connection lifetime", false, func(c *Config) string { return c.IdleTimeout.String() }},
+	{"SHUTDOWN_TIMEOUT", "how long shutdown waits for in-flight requests", false, func(c *Config) string { return c.ShutdownTimeout.String() }},
+	{"RATE_LIMIT", "requests per second per client IP and per token, 0 = off", false, func(c *Config) string { return strconv.FormatFloat(c.RateLimit, 'g', -1, 64) }},
+	{"RATE_BURST", "burst size above rate-limit", false, func(c *Config) string { return strconv.Itoa(c.RateBurst) }},
+	{"AUTH_FAIL_LIMIT", "failed sign-ins per IP before lockout, 0 = off", false, func(c *Config) string { return strconv.Itoa(c.AuthFailLimit) }},
+	{"AUTH_LOCKOUT", "first lockout, doubled per failure", false, func(c *Config) string { return c.AuthLockout.String() }},
+	{"AUTH_LOCKOUT_MAX", "maximum lockout", false, func(c *Config) string { return c.AuthLockoutMax.String() }},
+	{"TRASH_RETENTION_DAYS", "days deleted entries stay in the trash, 0 = forever", false, func(c *Config) string { return strconv.Itoa(c.TrashRetentionDays) }},
+	{"IDEMPOTENCY_RETENTION_HOURS", "hours Idempotency-Key responses are replayed, 0 = forever", false, func(c *Config) string { return strconv.Itoa(c.IdempotencyHours) }},
+	{"BACKUP_INTERVAL", "how often the API server backs up the data directory, 0 = never", false, func(c *Config) string { return c.BackupInterval.String() }},
+	{"BACKUP_KEEP_HOURLY", "scheduled backups kept, one per hour", false, func(c *Config) string { return strconv.Itoa(c.BackupKeepHourly) }},
+	{"BACKUP_KEEP_DAILY", "scheduled backups kept, one per day", false, func(c *Config) string { return strconv.Itoa(c.BackupKeepDaily) }},
+	{"BACKUP_KEEP_WEEKLY", "scheduled backups kept, one per week", false, func(c *Config) string { return strconv.Itoa(c.BackupKeepWeekly) }},
+	{"TIMEZONE", "household IANA time zone (default: system local zone)", false, func(c *Config) string { return c.TimeZone }},
+	{"VOLUME_UNIT", "feed quantity unit, ml or oz", false, func(c *Config) string { return c.VolumeUnit }},
+	{"WEIGHT_UNIT", "weight unit, kg or lb", false, func(c *Config) string { return c.WeightUnit }},
+	{"LENGTH_UNIT", "length unit, cm or in", false, func(c *Config) string { return c.LengthUnit }},
+	{"DESKTOP_PROFILE", "caregiver name the desktop app logs as", false, func(c *Config) string { return c.DesktopProfile }},
+}
+
+// fileKey and flagName are a setting's names in the config file and on the command line.
+func fileKey(env string) string  { return strings.ToLower(env) }
+func flagName(env string) string { return strings.ReplaceAll(fileKey(env), "_", "-") }
+
+// Flags holds the settings given on the command line.
+type Flags struct {
+	File   string            // -config: the config file to read instead of DefaultFile
+	values map[string]string // Flags that were set, by environment variable name
+}
+
+// settingFlag is the flag.Value of one setting.
+type settingFlag struct {
+	f   *Flags
+	env string
+}
+
+func (s settingFlag) String() string { return "" }
+
+func (s settingFlag) Set(v string) error {
+	s.f.values[s.env] = v
+	return nil
+}
+
+// RegisterFlags defines -config and a flag for every setting but secrets on
+// fs. Pass the result to LoadFlags once fs is parsed.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{values: map[string]string{}}
+	fs.StringVar(&f.File, "config", "", "config file (default: "+DefaultFile()+"; also CONFIG_FILE)")
+	for _, s := range settings {
+		if !s.secret {
+			fs.Var(settingFlag{f, s.env}, flagName(s.env), s.usage+" ("+s.env+")")
+		}
+	}
+	return f
+}
+
+// DefaultFile is the config file Load reads when none is named, if it exists:
+// babytracker/config.toml under $XDG_CONFIG_HOME, or else the OS user config directory.
+func DefaultFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		var err error
+		if dir, err = os.UserConfigDir(); err != nil {
+			return ""
+		}
+	}
+	return filepath.Join(dir, "babytracker", "config.toml")
+}
+
+// readFile reads a TOML config file into values by environment variable name.
+// Strings, numbers, booleans and lists (joined with commas) are accepted, so
+// `port = 8080` and `port = "8080"` are the same; unknown keys are refused.
+func readFile(path string) (map[string]string, error) {
+	var raw map[string]any
+	if _, err := toml.DecodeFile(path, &raw); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	known := map[string]bool{}
+	for _, s := range settings {
+		known[fileKey(s.env)] = true
+	}
+	values := map[string]string{}
+	for key, v := range raw {
+		if !known[key] {
+			return nil, fmt.Errorf("unknown setting %q in %s", key, path)
+		}
+		s, ok := fileValue(v)
+		if !ok {
+			return nil, fmt.Errorf("%s in %s: expected a string, number, boolean or list of them", key, path)
+		}
+		values[strings.ToUpper(key)] = s
+	}
+	return values, nil
+}
+
+// fileValue renders a TOML value as the string an environment variable would hold.
+func fileValue(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, ok := fileValue(item)
+			if !ok {
+				return "", false
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), true
+	}
+	return "", false
+}
+
+// Print writes the effective configuration as a TOML config file, each value
+// annotated with where it came from. A secret that is set is redacted and
+// commented out, so the output can be saved as a config file without it.
+func (c *Config) Print(w io.Writer) {
+	if c.File != "" {
+		fmt.Fprintf(w, "# Config file: %s\n", c.File)
+	} else {
+		fmt.Fprintln(w, "# Config file: none")
+	}
+	for _, s := range settings {
+		source := c.sources[s.env]
+		if source == "" {
+			source = "default"
+		}
+		line := fmt.Sprintf("%s = %s", fileKey(s.env), strconv.Quote(s.value(c)))
+		if s.secret && s.value(c) != "" {
+			line = fmt.Sprintf("# %s = %s", fileKey(s.env), redact(s.value(c)))
+		}
+		fmt.Fprintf(w, "%-48s # %s\n", line, source)
+	}
+}
+
+// reloadable are the settings a running API server applies when its config is
+// reloaded; the rest only take effect on a restart. APP_TITLE and
+// DESKTOP_PROFILE are only read by the desktop app, so the server has nothing
+// to restart for.
+var reloadable = map[string]bool{
+	"CORS_ORIGINS":     true,
+	"API_KEY":          true,
+	"CHILD_NAME":       true,
+	"RATE_LIMIT":       true,
+	"RATE_BURST":       true,
+	"AUTH_FAIL_LIMIT":  true,
+	"AUTH_LOCKOUT":     true,
+	"AUTH_LOCKOUT_MAX": true,
+	"VOLUME_UNIT":      true,
+	"WEIGHT_UNIT":      true,
+	"LENGTH_UNIT":      true,
+	"APP_TITLE":        true,
+	"DESKTOP_PROFILE":  true,
+}
+
+// Change is a setting whose effective value differs between two configs.
+type Change struct {
+	Setting    string // Environment variable name
+	Old, New   string // Effective values; a secret's are redacted
+	Reloadable bool   // Applied by a running API server, rather than needing a restart
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Setting, strconv.Quote(c.Old), strconv.Quote(c.New))
+}
+
+// Diff lists the settings whose effective values differ from old to next, in
+// the order Print shows them.
+func Diff(old, next *Config) []Change {
+	var changes []Change
+	for _, s := range settings {
+		o, n := s.value(old), s.value(next)
+		if o == n {
+			continue
+		}
+		if s.secret {
+			o, n = redact(o), redact(n)
+		}
+		changes = append(changes, Change{Setting: s.env, Old: o, New: n, Reloadable: reloadable[s.env]})
+	}
+	return changes
+}
+
+// redact hides a secret's value, showing only whether it is set.
+func redact(v string) string {
+	if v == "" {
+		return ""
+	}
+	return "<redacted>"
+}
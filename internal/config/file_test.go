@@ -0,0 +1,180 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigFile writes the default config file under a fresh XDG_CONFIG_HOME.
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := DefaultFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// parseFlags registers the settings flags and parses args.
+func parseFlags(t *testing.T, args ...string) *Flags {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return f
+}
+
+func TestLoadFlags_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `
+port = 9000
+bind_addr = "0.0.0.0"
+child_name = "Mira"
+rate_limit = 2.5
+tls_hosts = ["a.local", "b.local"]
+backup_interval = "6h"
+`)
+	t.Setenv("BIND_ADDR", "192.168.1.2")
+	t.Setenv("CHILD_NAME", "Ada")
+
+	cfg, err := LoadFlags(parseFlags(t, "-child-name", "Lina"))
+	if err != nil {
+		t.Fatalf("LoadFlags failed: %v", err)
+	}
+	if cfg.File != path {
+		t.Errorf("File = %q, want %q", cfg.File, path)
+	}
+	if cfg.APIPort != "9000" || cfg.RateLimit != 2.5 || cfg.BackupInterval.Hours() != 6 {
+		t.Errorf("file values not applied: port %q, rate %g, backups %v", cfg.APIPort, cfg.RateLimit, cfg.BackupInterval)
+	}
+	if len(cfg.TLSHosts) != 2 || cfg.TLSHosts[1] != "b.local" {
+		t.Errorf("TLSHosts = %q, want the file's list", cfg.TLSHosts)
+	}
+	if cfg.BindAddr != "192.168.1.2" {
+		t.Errorf("BindAddr = %q, want the environment over the file", cfg.BindAddr)
+	}
+	if cfg.ChildName != "Lina" {
+		t.Errorf("ChildName = %q, want the flag over the environment", cfg.ChildName)
+	}
+}
+
+func TestLoadFlags_ConfigFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if cfg, err := Load(); err != nil || cfg.File != "" {
+		t.Errorf("without a config file: %q, %v", cfg.File, err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing.toml")
+	if _, err := LoadFlags(parseFlags(t, "-config", missing)); err == nil {
+		t.Error("expected an error for a -config file that doesn't exist")
+	}
+
+	path := writeConfigFile(t, "prot = 9000\n")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), `unknown setting "prot"`) {
+		t.Errorf("expected an unknown setting error, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte("port = 9000\nport = 9001\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("expected an error for invalid TOML")
+	}
+	if err := os.WriteFile(path, []byte("[server]\nport = 9000\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("expected an error for a table")
+	}
+
+	// Errors name where the bad value came from
+	if err := os.WriteFile(path, []byte("write_timeout = \"soon\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "write_timeout in "+path) {
+		t.Errorf("expected the error to name the file key, got %v", err)
+	}
+	if _, err := LoadFlags(parseFlags(t, "-write-timeout", "later")); err == nil || !strings.Contains(err.Error(), "-write-timeout") {
+		t.Errorf("expected the error to name the flag, got %v", err)
+	}
+}
+
+func TestLoad_Validation(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, port := range []string{"0", "65536", "http", "-1"} {
+		t.Setenv("PORT", port)
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "invalid PORT") {
+			t.Errorf("PORT=%s: expected an invalid PORT error, got %v", port, err)
+		}
+	}
+	t.Setenv("PORT", "65535")
+
+	file := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"relative/data", "absolute"},
+		{"/srv/../etc", "must not contain .."},
+		{file, "not a directory"},
+		{filepath.Join(file, "below"), "not a directory"},
+	}
+	for _, tt := range tests {
+		t.Setenv("DATA_DIR", tt.dir)
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("DATA_DIR=%s: got %v, want an error containing %q", tt.dir, err, tt.want)
+		}
+	}
+
+	// A directory that doesn't exist yet is fine if it can be created
+	t.Setenv("DATA_DIR", filepath.Join(t.TempDir(), "new", "data"))
+	if _, err := Load(); err != nil {
+		t.Errorf("a creatable DATA_DIR was refused: %v", err)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	writeConfigFile(t, "api_key = \"hunter2\"\nport = 9000\n")
+	t.Setenv("RATE_LIMIT", "3")
+	cfg, err := LoadFlags(parseFlags(t, "-bind-addr", "0.0.0.0"))
+	if err != nil {
+		t.Fatalf("LoadFlags failed: %v", err)
+	}
+	var out strings.Builder
+	cfg.Print(&out)
+	printed := out.String()
+	if strings.Contains(printed, "hunter2") {
+		t.Error("Print revealed the API key")
+	}
+	for _, want := range []string{
+		"# api_key = <redacted>",
+		`port = "9000"`,
+		"# flag -bind-addr",
+		"# env RATE_LIMIT",
+		`child_name = "Baby"`,
+	} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("Print output lacks %q:\n%s", want, printed)
+		}
+	}
+
+	// The output reads back as a config file
+	path := writeConfigFile(t, printed)
+	t.Setenv("RATE_LIMIT", "")
+	again, err := Load()
+	if err != nil {
+		t.Fatalf("Load of printed config failed: %v", err)
+	}
+	if again.File != path || again.BindAddr != "0.0.0.0" || again.RateLimit != 3 || again.APIPort != "9000" || again.APIKey != "" {
+		t.Errorf("printed config read back as %+v", again)
+	}
+}
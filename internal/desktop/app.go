@@ -1,41 +1,48 @@
 package desktop
 
 import (
+	"context"
+	"log"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/theme"
 
+	"babytracker/internal/config"
 	"babytracker/internal/desktop/tabs"
+	"babytracker/internal/storage"
 )
 
 // App represents the main application structure.
 type App struct {
 	fyneApp fyne.App
 	window  fyne.Window
+	session *tabs.Session
 }
 
 // NewApp creates and initializes a new Baby Tracker application.
-func NewApp() *App {
+func NewApp(cfg *config.Config) *App {
 	myApp := app.New()
 	myApp.SetIcon(theme.AccountIcon())
 
-	myWindow := myApp.NewWindow("Baby Tracker")
+	myWindow := myApp.NewWindow(cfg.AppTitle)
 	myWindow.Resize(fyne.NewSize(800, 600))
 	myWindow.CenterOnScreen()
 
 	return &App{
 		fyneApp: myApp,
 		window:  myWindow,
+		session: &tabs.Session{Config: cfg, Window: myWindow, Profile: cfg.DesktopProfile},
 	}
 }
 
 // CreateMainContent creates and returns the main tabbed interface.
 func (a *App) CreateMainContent() fyne.CanvasObject {
-	feedsTab := tabs.CreateFeedsTab()
-	sleepTab := tabs.CreateSleepTab()
-	growthTab := tabs.CreateGrowthTab()
-	diaperTab := tabs.CreateSusuPotyTab()
+	feedsTab := tabs.CreateFeedsTab(a.session)
+	sleepTab := tabs.CreateSleepTab(a.session)
+	growthTab := tabs.CreateGrowthTab(a.session)
+	diaperTab := tabs.CreateSusuPotyTab(a.session)
 
 	tabsList := container.NewAppTabs(
 		container.NewTabItem("Feeds", feedsTab),
@@ -45,19 +52,46 @@ func (a *App) CreateMainContent() fyne.CanvasObject {
 	)
 	tabsList.SetTabLocation(container.TabLocationTop)
 
-	return tabsList
+	return container.NewBorder(a.createProfileBar(), nil, nil, nil, tabsList)
 }
 
 // SetupWindow configures the main window with content and properties.
+// An encrypted data directory is unlocked first.
 func (a *App) SetupWindow() {
-	content := a.CreateMainContent()
-	a.window.SetContent(content)
+	showMain := func() {
+		a.ensureProfile()
+		a.logMigrations()
+		a.purgeTrash()
+		a.window.SetContent(a.CreateMainContent())
+	}
+	if storage.Locked() {
+		a.window.SetContent(a.createUnlockContent(showMain))
+	} else {
+		showMain()
+	}
 	a.window.SetMaster()
 	a.window.SetCloseIntercept(func() {
 		a.window.Close()
 	})
 }
 
+// logMigrations reports the schema migrations storage ran on open or unlock.
+func (a *App) logMigrations() {
+	for _, m := range storage.AppliedMigrations() {
+		log.Printf("Migrated data directory to schema %d (%s): %d records changed", m.Version, m.Name, m.Changed)
+	}
+}
+
+// purgeTrash drops entries that have outlived TRASH_RETENTION_DAYS in the trash.
+func (a *App) purgeTrash() {
+	ctx := storage.WithActor(context.Background(), storage.Actor{Address: "trash-retention"})
+	if n, err := storage.PurgeTrash(ctx, a.session.Config.TrashRetention()); err != nil {
+		log.Printf("Failed to purge trash: %v", err)
+	} else if n > 0 {
+		log.Printf("Purged %d entries from the trash", n)
+	}
+}
+
 // Run starts the Baby Tracker application. Blocks until closed.
 func (a *App) Run() {
 	a.SetupWindow()
@@ -7,13 +7,13 @@ import (
 )
 
 // CreateMainLayout constructs the primary tabbed interface.
-func CreateMainLayout() *container.AppTabs {
+func CreateMainLayout(session *tabs.Session) *container.AppTabs {
 	mainTabs := container.NewAppTabs()
 
-	mainTabs.Append(container.NewTabItem("Feeds", tabs.CreateFeedsTab()))
-	mainTabs.Append(container.NewTabItem("Sleep", tabs.CreateSleepTab()))
-	mainTabs.Append(container.NewTabItem("Growth", tabs.CreateGrowthTab()))
-	mainTabs.Append(container.NewTabItem("Susu-Poty", tabs.CreateSusuPotyTab()))
+	mainTabs.Append(container.NewTabItem("Feeds", tabs.CreateFeedsTab(session)))
+	mainTabs.Append(container.NewTabItem("Sleep", tabs.CreateSleepTab(session)))
+	mainTabs.Append(container.NewTabItem("Growth", tabs.CreateGrowthTab(session)))
+	mainTabs.Append(container.NewTabItem("Susu-Poty", tabs.CreateSusuPotyTab(session)))
 
 	return mainTabs
 }
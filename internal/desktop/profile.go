@@ -0,0 +1,82 @@
+package desktop
+
+import (
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// ensureProfile registers the configured DESKTOP_PROFILE caregiver on first run
+// so it can be selected and stamped on entries.
+func (a *App) ensureProfile() {
+	name := a.session.Profile
+	if name == "" {
+		return
+	}
+	if c, err := storage.FindCaregiver(name); err == nil {
+		a.session.Profile = c.Name
+		return
+	}
+	if err := storage.SaveCaregiver(a.session.Context(), &models.Caregiver{Name: name}); err != nil {
+		log.Printf("Failed to register desktop profile %q: %v", name, err)
+		a.session.Profile = ""
+	}
+}
+
+// createProfileBar builds the "Logging as" caregiver selector shown above the tabs.
+func (a *App) createProfileBar() fyne.CanvasObject {
+	profileSelect := widget.NewSelect(nil, func(name string) {
+		a.session.Profile = name
+	})
+	profileSelect.PlaceHolder = "Select caregiver..."
+
+	reload := func() {
+		caregivers, err := storage.LoadCaregivers()
+		if err != nil {
+			dialog.ShowError(err, a.window)
+			return
+		}
+		names := make([]string, len(caregivers))
+		for i, c := range caregivers {
+			names[i] = c.Name
+		}
+		profileSelect.Options = names
+		profileSelect.Refresh()
+	}
+	reload()
+	if a.session.Profile != "" {
+		profileSelect.SetSelected(a.session.Profile)
+	}
+
+	addButton := widget.NewButton("Add Caregiver", func() {
+		nameEntry := widget.NewEntry()
+		relationEntry := widget.NewEntry()
+		relationEntry.SetPlaceHolder("Mum, Dad, Nanny... (optional)")
+		items := []*widget.FormItem{
+			widget.NewFormItem("Name", nameEntry),
+			widget.NewFormItem("Relation", relationEntry),
+		}
+		dialog.ShowForm("Add Caregiver", "Add", "Cancel", items, func(ok bool) {
+			name := strings.TrimSpace(nameEntry.Text)
+			if !ok || name == "" {
+				return
+			}
+			c := models.Caregiver{Name: name, Relation: strings.TrimSpace(relationEntry.Text)}
+			if err := storage.SaveCaregiver(a.session.Context(), &c); err != nil {
+				dialog.ShowError(err, a.window)
+				return
+			}
+			reload()
+			profileSelect.SetSelected(c.Name)
+		}, a.window)
+	})
+
+	return container.NewHBox(widget.NewLabel("Logging as:"), profileSelect, addButton)
+}
@@ -0,0 +1,22 @@
+package tabs
+
+import (
+	"time"
+
+	"babytracker/internal/models"
+)
+
+// combineDateTime joins the form's date and time strings into a single instant
+// in the household time zone. An empty or invalid date falls back to today.
+func combineDateTime(dateStr, timeStr string) (time.Time, bool) {
+	parsedTime, err := time.Parse(timeFormat, timeStr)
+	if err != nil {
+		return time.Time{}, false
+	}
+	day, err := time.ParseInLocation(dateFormat, dateStr, models.Location())
+	if err != nil {
+		day = models.Now()
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		parsedTime.Hour(), parsedTime.Minute(), parsedTime.Second(), 0, models.Location()), true
+}
@@ -19,7 +19,8 @@ const (
 )
 
 // CreateFeedsTab creates the feeding tracker interface.
-func CreateFeedsTab() *fyne.Container {
+func CreateFeedsTab(session *Session) *fyne.Container {
+	units := session.Config.Units()
 	dateBinding := binding.NewString()
 	timeBinding := binding.NewString()
 	quantityBinding := binding.NewFloat()
@@ -35,14 +36,14 @@ func CreateFeedsTab() *fyne.Container {
 
 	dateEntry := widget.NewEntryWithData(dateBinding)
 	dateEntry.SetPlaceHolder(dateFormat)
-	dateBinding.Set(time.Now().Format(dateFormat))
+	dateBinding.Set(models.Now().Format(dateFormat))
 
 	timeEntry := widget.NewEntryWithData(timeBinding)
 	timeEntry.SetPlaceHolder(timeFormat + " (24hr format)")
-	timeBinding.Set(time.Now().Format(timeFormat))
+	timeBinding.Set(models.Now().Format(timeFormat))
 
 	quantityEntry := widget.NewEntryWithData(binding.FloatToString(quantityBinding))
-	quantityEntry.SetPlaceHolder("Amount in ml or oz")
+	quantityEntry.SetPlaceHolder("Amount in " + units.Volume)
 
 	notesEntry := widget.NewMultiLineEntry()
 	notesEntry.Bind(notesBinding)
@@ -53,28 +54,20 @@ func CreateFeedsTab() *fyne.Container {
 		&widget.FormItem{Text: "Feed Type", Widget: feedTypeSelect},
 		&widget.FormItem{Text: "Date", Widget: dateEntry},
 		&widget.FormItem{Text: "Time", Widget: timeEntry},
-		&widget.FormItem{Text: "Quantity (optional)", Widget: quantityEntry},
+		&widget.FormItem{Text: fmt.Sprintf("Quantity (%s, optional)", units.Volume), Widget: quantityEntry},
 		&widget.FormItem{Text: "Notes", Widget: notesEntry},
 	)
 
 	logButton := widget.NewButton("Log Feed", func() {
 		dateStr, _ := dateBinding.Get()
 		if dateStr == "" {
-			dateStr = time.Now().Format(dateFormat)
+			dateStr = models.Now().Format(dateFormat)
 		}
 
-		feedTime := time.Now()
+		feedTime := models.Now()
 		if timeStr, _ := timeBinding.Get(); timeStr != "" {
-			if parsedTime, err := time.Parse(timeFormat, timeStr); err == nil {
-				parsedDate, err := time.Parse(dateFormat, dateStr)
-				if err == nil {
-					feedTime = time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(),
-						parsedTime.Hour(), parsedTime.Minute(), parsedTime.Second(), 0, parsedDate.Location())
-				} else {
-					now := time.Now()
-					feedTime = time.Date(now.Year(), now.Month(), now.Day(),
-						parsedTime.Hour(), parsedTime.Minute(), parsedTime.Second(), 0, now.Location())
-				}
+			if t, ok := combineDateTime(dateStr, timeStr); ok {
+				feedTime = t
 			}
 		}
 
@@ -82,14 +75,16 @@ func CreateFeedsTab() *fyne.Container {
 		notes, _ := notesBinding.Get()
 
 		feed := models.FeedEntry{
-			Date:     dateStr,
-			Time:     models.FlexTime{Time: feedTime},
-			Type:     feedTypeSelect.Selected,
-			Quantity: quantity,
-			Notes:    notes,
+			Date:         dateStr,
+			Time:         models.FlexTime{Time: feedTime},
+			Type:         feedTypeSelect.Selected,
+			Quantity:     quantity,
+			QuantityUnit: units.Volume,
+			Notes:        notes,
+			LoggedBy:     session.Profile,
 		}
 
-		err := storage.SaveFeed(&feed)
+		err := storage.SaveFeed(session.Context(), &feed)
 		if err != nil {
 			fmt.Printf("Error saving feed: %v\n", err)
 			return
@@ -98,23 +93,23 @@ func CreateFeedsTab() *fyne.Container {
 		fmt.Printf("Feed logged successfully at %s %s\n", dateStr, feedTime.Format(timeFormat))
 
 		feedTypeSelect.ClearSelected()
-		dateBinding.Set(time.Now().Format(dateFormat))
-		timeBinding.Set(time.Now().Format(timeFormat))
+		dateBinding.Set(models.Now().Format(dateFormat))
+		timeBinding.Set(models.Now().Format(timeFormat))
 		quantityBinding.Set(0)
 		notesBinding.Set("")
 	})
 
 	quickBottleBtn := widget.NewButton("Quick Bottle", func() {
 		feedTypeSelect.SetSelected("Bottle")
-		dateBinding.Set(time.Now().Format(dateFormat))
-		timeBinding.Set(time.Now().Format(timeFormat))
+		dateBinding.Set(models.Now().Format(dateFormat))
+		timeBinding.Set(models.Now().Format(timeFormat))
 		quantityEntry.FocusGained()
 	})
 
 	quickBreastBtn := widget.NewButton("Quick Breast", func() {
 		feedTypeSelect.SetSelected("Breast (Both)")
-		dateBinding.Set(time.Now().Format(dateFormat))
-		timeBinding.Set(time.Now().Format(timeFormat))
+		dateBinding.Set(models.Now().Format(dateFormat))
+		timeBinding.Set(models.Now().Format(timeFormat))
 		notesEntry.FocusGained()
 	})
 
@@ -124,7 +119,9 @@ func CreateFeedsTab() *fyne.Container {
 	recentFeedsLabel.TextStyle.Bold = true
 	recentList := widget.NewLabel("Loading...")
 
+	var recent []recentEntry
 	refreshRecent := func() {
+		recent = nil
 		feeds, err := storage.LoadFeeds()
 		if err != nil || len(feeds) == 0 {
 			recentList.SetText("No feeds logged yet")
@@ -132,12 +129,13 @@ func CreateFeedsTab() *fyne.Container {
 		}
 		lines := ""
 		for i := len(feeds) - 1; i >= 0; i-- {
-			f := feeds[i]
-			lines += fmt.Sprintf("%s %s — %s", f.Date, f.Time.Format("15:04"), f.Type)
+			f := feeds[i].InUnits(units)
+			line := fmt.Sprintf("%s %s — %s", f.Date, f.Time.InHousehold().Format("15:04"), f.Type)
 			if f.Quantity > 0 {
-				lines += fmt.Sprintf(" (%.0fml)", f.Quantity)
+				line += fmt.Sprintf(" (%s)", formatMeasure(f.Quantity, f.QuantityUnit))
 			}
-			lines += "\n"
+			lines += line + byline(f.LoggedBy) + "\n"
+			recent = append(recent, recentEntry{ID: f.ID, Version: models.EntryVersion(f.Version), Label: line})
 		}
 		recentList.SetText(lines)
 	}
@@ -155,6 +153,8 @@ func CreateFeedsTab() *fyne.Container {
 			container.NewVBox(feedForm, quickActions, logButton)),
 		widget.NewSeparator(),
 		widget.NewCard("Recent Activity", "Your recent feeding logs",
-			container.NewVBox(recentFeedsLabel, recentList)),
+			container.NewVBox(recentFeedsLabel, recentList,
+				deleteControls(session, models.ResourceFeeds, &recent, storage.DeleteFeed, refreshRecent),
+				historyButton(session, models.ResourceFeeds))),
 	)
 }
@@ -2,7 +2,6 @@ package tabs
 
 import (
 	"fmt"
-	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -14,7 +13,8 @@ import (
 )
 
 // CreateGrowthTab creates the growth tracking interface.
-func CreateGrowthTab() *fyne.Container {
+func CreateGrowthTab(session *Session) *fyne.Container {
+	units := session.Config.Units()
 	dateBinding := binding.NewString()
 	weightBinding := binding.NewFloat()
 	heightBinding := binding.NewFloat()
@@ -23,16 +23,16 @@ func CreateGrowthTab() *fyne.Container {
 
 	dateEntry := widget.NewEntryWithData(dateBinding)
 	dateEntry.SetPlaceHolder(dateFormat)
-	dateBinding.Set(time.Now().Format(dateFormat))
+	dateBinding.Set(models.Now().Format(dateFormat))
 
 	weightEntry := widget.NewEntryWithData(binding.FloatToString(weightBinding))
-	weightEntry.SetPlaceHolder("Weight in kg")
+	weightEntry.SetPlaceHolder("Weight in " + units.Weight)
 
 	heightEntry := widget.NewEntryWithData(binding.FloatToString(heightBinding))
-	heightEntry.SetPlaceHolder("Height in cm")
+	heightEntry.SetPlaceHolder("Height in " + units.Length)
 
 	headCircEntry := widget.NewEntryWithData(binding.FloatToString(headCircBinding))
-	headCircEntry.SetPlaceHolder("Head circumference in cm")
+	headCircEntry.SetPlaceHolder("Head circumference in " + units.Length)
 
 	notesEntry := widget.NewMultiLineEntry()
 	notesEntry.Bind(notesBinding)
@@ -41,16 +41,16 @@ func CreateGrowthTab() *fyne.Container {
 
 	growthForm := widget.NewForm(
 		&widget.FormItem{Text: "Date", Widget: dateEntry},
-		&widget.FormItem{Text: "Weight (kg)", Widget: weightEntry},
-		&widget.FormItem{Text: "Height (cm)", Widget: heightEntry},
-		&widget.FormItem{Text: "Head Circ. (cm)", Widget: headCircEntry},
+		&widget.FormItem{Text: fmt.Sprintf("Weight (%s)", units.Weight), Widget: weightEntry},
+		&widget.FormItem{Text: fmt.Sprintf("Height (%s)", units.Length), Widget: heightEntry},
+		&widget.FormItem{Text: fmt.Sprintf("Head Circ. (%s)", units.Length), Widget: headCircEntry},
 		&widget.FormItem{Text: "Notes", Widget: notesEntry},
 	)
 
 	logButton := widget.NewButton("Log Growth", func() {
 		dateStr, _ := dateBinding.Get()
 		if dateStr == "" {
-			dateStr = time.Now().Format(dateFormat)
+			dateStr = models.Now().Format(dateFormat)
 		}
 
 		weight, _ := weightBinding.Get()
@@ -63,17 +63,20 @@ func CreateGrowthTab() *fyne.Container {
 			Weight:            weight,
 			Height:            height,
 			HeadCircumference: headCirc,
+			WeightUnit:        units.Weight,
+			LengthUnit:        units.Length,
 			Notes:             notes,
+			LoggedBy:          session.Profile,
 		}
 
-		if err := storage.SaveGrowth(&entry); err != nil {
+		if err := storage.SaveGrowth(session.Context(), &entry); err != nil {
 			fmt.Printf("Error saving growth: %v\n", err)
 			return
 		}
 
 		fmt.Printf("Growth logged for %s\n", dateStr)
 
-		dateBinding.Set(time.Now().Format(dateFormat))
+		dateBinding.Set(models.Now().Format(dateFormat))
 		weightBinding.Set(0)
 		heightBinding.Set(0)
 		headCircBinding.Set(0)
@@ -84,7 +87,9 @@ func CreateGrowthTab() *fyne.Container {
 	recentLabel.TextStyle.Bold = true
 	recentList := widget.NewLabel("Loading...")
 
+	var recent []recentEntry
 	refreshRecent := func() {
+		recent = nil
 		entries, err := storage.LoadGrowth()
 		if err != nil || len(entries) == 0 {
 			recentList.SetText("No growth entries logged yet")
@@ -92,18 +97,19 @@ func CreateGrowthTab() *fyne.Container {
 		}
 		lines := ""
 		for i := len(entries) - 1; i >= 0; i-- {
-			e := entries[i]
+			e := entries[i].InUnits(units)
 			parts := []string{e.Date + " —"}
 			if e.Weight > 0 {
-				parts = append(parts, fmt.Sprintf("%.1fkg", e.Weight))
+				parts = append(parts, formatMeasure(e.Weight, e.WeightUnit))
 			}
 			if e.Height > 0 {
-				parts = append(parts, fmt.Sprintf("%.1fcm", e.Height))
+				parts = append(parts, formatMeasure(e.Height, e.LengthUnit))
 			}
 			if e.HeadCircumference > 0 {
-				parts = append(parts, fmt.Sprintf("HC %.1fcm", e.HeadCircumference))
+				parts = append(parts, "HC "+formatMeasure(e.HeadCircumference, e.LengthUnit))
 			}
-			lines += fmt.Sprintf("%s\n", joinParts(parts))
+			lines += fmt.Sprintf("%s%s\n", joinParts(parts), byline(e.LoggedBy))
+			recent = append(recent, recentEntry{ID: e.ID, Version: models.EntryVersion(e.Version), Label: joinParts(parts)})
 		}
 		recentList.SetText(lines)
 	}
@@ -120,10 +126,20 @@ func CreateGrowthTab() *fyne.Container {
 			container.NewVBox(growthForm, logButton)),
 		widget.NewSeparator(),
 		widget.NewCard("Recent Activity", "Your recent growth logs",
-			container.NewVBox(recentLabel, recentList)),
+			container.NewVBox(recentLabel, recentList,
+				deleteControls(session, models.ResourceGrowth, &recent, storage.DeleteGrowth, refreshRecent),
+				historyButton(session, models.ResourceGrowth))),
 	)
 }
 
+// formatMeasure renders a value with its unit; ml are shown whole, everything else to one decimal.
+func formatMeasure(v float64, unit string) string {
+	if unit == models.UnitML {
+		return fmt.Sprintf("%.0f%s", v, unit)
+	}
+	return fmt.Sprintf("%.1f%s", v, unit)
+}
+
 func joinParts(parts []string) string {
 	result := ""
 	for i, p := range parts {
@@ -0,0 +1,101 @@
+package tabs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"babytracker/internal/models"
+	"babytracker/internal/storage"
+)
+
+// historyButton opens the change history of one entry of the resource,
+// read from the audit log so deleted entries can be looked up too.
+func historyButton(session *Session, resource string) *widget.Button {
+	return widget.NewButton("History", func() {
+		trail, err := storage.LoadAudit(models.AuditFilter{Resource: resource})
+		if err != nil {
+			dialog.ShowError(err, session.Window)
+			return
+		}
+		showHistory(session, trail)
+	})
+}
+
+// showHistory lets the user pick an entry and lists every change made to it.
+func showHistory(session *Session, trail []models.AuditEntry) {
+	byID := map[int][]models.AuditEntry{}
+	for _, e := range trail {
+		byID[e.EntityID] = append(byID[e.EntityID], e)
+	}
+	ids := make([]int, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+
+	options := make([]string, len(ids))
+	optionID := map[string]int{}
+	for i, id := range ids {
+		changes := byID[id]
+		label := fmt.Sprintf("#%d", id)
+		if last := changes[len(changes)-1]; last.Action == models.AuditDelete {
+			label += " (deleted)"
+		}
+		options[i] = label
+		optionID[label] = id
+	}
+
+	details := widget.NewLabel("")
+	details.Wrapping = fyne.TextWrapWord
+	entrySelect := widget.NewSelect(options, func(label string) {
+		lines := make([]string, 0, len(byID[optionID[label]]))
+		for _, e := range byID[optionID[label]] {
+			lines = append(lines, describeChange(e))
+		}
+		details.SetText(strings.Join(lines, "\n"))
+	})
+	entrySelect.PlaceHolder = "Select entry..."
+	if len(options) == 0 {
+		details.SetText("No changes recorded yet")
+	} else {
+		entrySelect.SetSelectedIndex(0)
+	}
+
+	content := container.NewBorder(entrySelect, nil, nil, nil, container.NewVScroll(details))
+	d := dialog.NewCustom("Entry History", "Close", content, session.Window)
+	d.Resize(fyne.NewSize(520, 420))
+	d.Show()
+}
+
+// describeChange renders one audit entry: when, what and by whom, then the
+// fields an update changed.
+func describeChange(e models.AuditEntry) string {
+	who := e.Actor
+	if who == "" {
+		who = models.UnattributedCaregiver
+	}
+	if e.Address != "" {
+		who += " (" + e.Address + ")"
+	}
+	line := fmt.Sprintf("%s — %s by %s", e.Time.In(models.Location()).Format("2006-01-02 15:04"), e.Action, who)
+	if e.Action != models.AuditUpdate {
+		return line
+	}
+	for _, c := range e.Changes() {
+		line += fmt.Sprintf("\n    %s: %s → %s", c.Field, orNone(c.Before), orNone(c.After))
+	}
+	return line
+}
+
+func orNone(raw []byte) string {
+	if len(raw) == 0 {
+		return "(none)"
+	}
+	return string(raw)
+}
@@ -0,0 +1,30 @@
+package tabs
+
+import (
+	"context"
+
+	"fyne.io/fyne/v2"
+
+	"babytracker/internal/config"
+	"babytracker/internal/storage"
+)
+
+// Session holds the state shared by every tab in the window.
+type Session struct {
+	Config  *config.Config
+	Window  fyne.Window // Parent for dialogs
+	Profile string      // Caregiver currently logging; stamped on new entries as LoggedBy
+}
+
+// Context attributes storage changes to the current profile in the audit log.
+func (s *Session) Context() context.Context {
+	return storage.WithActor(context.Background(), storage.Actor{Name: s.Profile, Address: "desktop"})
+}
+
+// byline renders the caregiver suffix for a recent-activity line.
+func byline(loggedBy string) string {
+	if loggedBy == "" {
+		return ""
+	}
+	return " · " + loggedBy
+}
@@ -14,7 +14,7 @@ import (
 )
 
 // CreateSleepTab creates the sleep tracking interface.
-func CreateSleepTab() *fyne.Container {
+func CreateSleepTab(session *Session) *fyne.Container {
 	dateBinding := binding.NewString()
 	startTimeBinding := binding.NewString()
 	endTimeBinding := binding.NewString()
@@ -34,11 +34,11 @@ func CreateSleepTab() *fyne.Container {
 
 	dateEntry := widget.NewEntryWithData(dateBinding)
 	dateEntry.SetPlaceHolder(dateFormat)
-	dateBinding.Set(time.Now().Format(dateFormat))
+	dateBinding.Set(models.Now().Format(dateFormat))
 
 	startTimeEntry := widget.NewEntryWithData(startTimeBinding)
 	startTimeEntry.SetPlaceHolder("HH:MM:SS (24hr)")
-	startTimeBinding.Set(time.Now().Format(timeFormat))
+	startTimeBinding.Set(models.Now().Format(timeFormat))
 
 	endTimeEntry := widget.NewEntryWithData(endTimeBinding)
 	endTimeEntry.SetPlaceHolder("HH:MM:SS (24hr, optional)")
@@ -60,28 +60,23 @@ func CreateSleepTab() *fyne.Container {
 	logButton := widget.NewButton("Log Sleep", func() {
 		dateStr, _ := dateBinding.Get()
 		if dateStr == "" {
-			dateStr = time.Now().Format(dateFormat)
+			dateStr = models.Now().Format(dateFormat)
 		}
 
-		startTime := time.Now()
+		startTime := models.Now()
 		if startStr, _ := startTimeBinding.Get(); startStr != "" {
-			if parsed, err := time.Parse(timeFormat, startStr); err == nil {
-				if parsedDate, err := time.Parse(dateFormat, dateStr); err == nil {
-					startTime = time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(),
-						parsed.Hour(), parsed.Minute(), parsed.Second(), 0, parsedDate.Location())
-				}
+			if t, ok := combineDateTime(dateStr, startStr); ok {
+				startTime = t
 			}
 		}
 
 		var endTime time.Time
 		var duration int
 		if endStr, _ := endTimeBinding.Get(); endStr != "" {
-			if parsed, err := time.Parse(timeFormat, endStr); err == nil {
-				if parsedDate, err := time.Parse(dateFormat, dateStr); err == nil {
-					endTime = time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(),
-						parsed.Hour(), parsed.Minute(), parsed.Second(), 0, parsedDate.Location())
-					duration = int(endTime.Sub(startTime).Minutes())
-				}
+			if t, ok := combineDateTime(dateStr, endStr); ok {
+				endTime = t
+				// Elapsed time, so a DST change during the night is counted correctly
+				duration = int(endTime.Sub(startTime).Minutes())
 			}
 		}
 
@@ -95,9 +90,10 @@ func CreateSleepTab() *fyne.Container {
 			Type:      sleepTypeSelect.Selected,
 			Quality:   qualitySelect.Selected,
 			Notes:     notes,
+			LoggedBy:  session.Profile,
 		}
 
-		if err := storage.SaveSleep(&entry); err != nil {
+		if err := storage.SaveSleep(session.Context(), &entry); err != nil {
 			fmt.Printf("Error saving sleep: %v\n", err)
 			return
 		}
@@ -106,22 +102,22 @@ func CreateSleepTab() *fyne.Container {
 
 		sleepTypeSelect.ClearSelected()
 		qualitySelect.ClearSelected()
-		dateBinding.Set(time.Now().Format(dateFormat))
-		startTimeBinding.Set(time.Now().Format(timeFormat))
+		dateBinding.Set(models.Now().Format(dateFormat))
+		startTimeBinding.Set(models.Now().Format(timeFormat))
 		endTimeBinding.Set("")
 		notesBinding.Set("")
 	})
 
 	quickNapBtn := widget.NewButton("Quick Nap", func() {
 		sleepTypeSelect.SetSelected("Nap")
-		dateBinding.Set(time.Now().Format(dateFormat))
-		startTimeBinding.Set(time.Now().Format(timeFormat))
+		dateBinding.Set(models.Now().Format(dateFormat))
+		startTimeBinding.Set(models.Now().Format(timeFormat))
 	})
 
 	quickNightBtn := widget.NewButton("Quick Night", func() {
 		sleepTypeSelect.SetSelected("Night")
-		dateBinding.Set(time.Now().Format(dateFormat))
-		startTimeBinding.Set(time.Now().Format(timeFormat))
+		dateBinding.Set(models.Now().Format(dateFormat))
+		startTimeBinding.Set(models.Now().Format(timeFormat))
 	})
 
 	quickActions := container.NewHBox(quickNapBtn, quickNightBtn)
@@ -130,7 +126,9 @@ func CreateSleepTab() *fyne.Container {
 	recentLabel.TextStyle.Bold = true
 	recentList := widget.NewLabel("Loading...")
 
+	var recent []recentEntry
 	refreshRecent := func() {
+		recent = nil
 		entries, err := storage.LoadSleep()
 		if err != nil || len(entries) == 0 {
 			recentList.SetText("No sleep entries logged yet")
@@ -139,14 +137,15 @@ func CreateSleepTab() *fyne.Container {
 		lines := ""
 		for i := len(entries) - 1; i >= 0; i-- {
 			e := entries[i]
-			line := fmt.Sprintf("%s %s — %s", e.Date, e.StartTime.Format("15:04"), e.Type)
+			line := fmt.Sprintf("%s %s — %s", e.Date, e.StartTime.InHousehold().Format("15:04"), e.Type)
 			if e.Duration > 0 {
 				line += fmt.Sprintf(" (%dmin)", e.Duration)
 			}
 			if e.Quality != "" {
 				line += fmt.Sprintf(" [%s]", e.Quality)
 			}
-			lines += line + "\n"
+			lines += line + byline(e.LoggedBy) + "\n"
+			recent = append(recent, recentEntry{ID: e.ID, Version: models.EntryVersion(e.Version), Label: line})
 		}
 		recentList.SetText(lines)
 	}
@@ -163,6 +162,8 @@ func CreateSleepTab() *fyne.Container {
 			container.NewVBox(sleepForm, quickActions, logButton)),
 		widget.NewSeparator(),
 		widget.NewCard("Recent Activity", "Your recent sleep logs",
-			container.NewVBox(recentLabel, recentList)),
+			container.NewVBox(recentLabel, recentList,
+				deleteControls(session, models.ResourceSleep, &recent, storage.DeleteSleep, refreshRecent),
+				historyButton(session, models.ResourceSleep))),
 	)
 }
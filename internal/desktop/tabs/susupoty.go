@@ -2,7 +2,6 @@ package tabs
 
 import (
 	"fmt"
-	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -14,7 +13,7 @@ import (
 )
 
 // CreateSusuPotyTab creates the diaper tracking interface.
-func CreateSusuPotyTab() *fyne.Container {
+func CreateSusuPotyTab(session *Session) *fyne.Container {
 	dateBinding := binding.NewString()
 	timeBinding := binding.NewString()
 	notesBinding := binding.NewString()
@@ -27,11 +26,11 @@ func CreateSusuPotyTab() *fyne.Container {
 
 	dateEntry := widget.NewEntryWithData(dateBinding)
 	dateEntry.SetPlaceHolder(dateFormat)
-	dateBinding.Set(time.Now().Format(dateFormat))
+	dateBinding.Set(models.Now().Format(dateFormat))
 
 	timeEntry := widget.NewEntryWithData(timeBinding)
 	timeEntry.SetPlaceHolder(timeFormat + " (24hr format)")
-	timeBinding.Set(time.Now().Format(timeFormat))
+	timeBinding.Set(models.Now().Format(timeFormat))
 
 	notesEntry := widget.NewMultiLineEntry()
 	notesEntry.Bind(notesBinding)
@@ -48,29 +47,27 @@ func CreateSusuPotyTab() *fyne.Container {
 	logButton := widget.NewButton("Log Change", func() {
 		dateStr, _ := dateBinding.Get()
 		if dateStr == "" {
-			dateStr = time.Now().Format(dateFormat)
+			dateStr = models.Now().Format(dateFormat)
 		}
 
-		changeTime := time.Now()
+		changeTime := models.Now()
 		if timeStr, _ := timeBinding.Get(); timeStr != "" {
-			if parsedTime, err := time.Parse(timeFormat, timeStr); err == nil {
-				if parsedDate, err := time.Parse(dateFormat, dateStr); err == nil {
-					changeTime = time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(),
-						parsedTime.Hour(), parsedTime.Minute(), parsedTime.Second(), 0, parsedDate.Location())
-				}
+			if t, ok := combineDateTime(dateStr, timeStr); ok {
+				changeTime = t
 			}
 		}
 
 		notes, _ := notesBinding.Get()
 
 		entry := models.DiaperEntry{
-			Date:  dateStr,
-			Time:  models.FlexTime{Time: changeTime},
-			Type:  diaperTypeSelect.Selected,
-			Notes: notes,
+			Date:     dateStr,
+			Time:     models.FlexTime{Time: changeTime},
+			Type:     diaperTypeSelect.Selected,
+			Notes:    notes,
+			LoggedBy: session.Profile,
 		}
 
-		if err := storage.SaveDiaper(&entry); err != nil {
+		if err := storage.SaveDiaper(session.Context(), &entry); err != nil {
 			fmt.Printf("Error saving diaper change: %v\n", err)
 			return
 		}
@@ -78,21 +75,21 @@ func CreateSusuPotyTab() *fyne.Container {
 		fmt.Printf("Diaper change logged: %s on %s\n", entry.Type, dateStr)
 
 		diaperTypeSelect.ClearSelected()
-		dateBinding.Set(time.Now().Format(dateFormat))
-		timeBinding.Set(time.Now().Format(timeFormat))
+		dateBinding.Set(models.Now().Format(dateFormat))
+		timeBinding.Set(models.Now().Format(timeFormat))
 		notesBinding.Set("")
 	})
 
 	quickWetBtn := widget.NewButton("Quick Wet", func() {
 		diaperTypeSelect.SetSelected("Wet")
-		dateBinding.Set(time.Now().Format(dateFormat))
-		timeBinding.Set(time.Now().Format(timeFormat))
+		dateBinding.Set(models.Now().Format(dateFormat))
+		timeBinding.Set(models.Now().Format(timeFormat))
 	})
 
 	quickDirtyBtn := widget.NewButton("Quick Dirty", func() {
 		diaperTypeSelect.SetSelected("Dirty")
-		dateBinding.Set(time.Now().Format(dateFormat))
-		timeBinding.Set(time.Now().Format(timeFormat))
+		dateBinding.Set(models.Now().Format(dateFormat))
+		timeBinding.Set(models.Now().Format(timeFormat))
 	})
 
 	quickActions := container.NewHBox(quickWetBtn, quickDirtyBtn)
@@ -101,7 +98,9 @@ func CreateSusuPotyTab() *fyne.Container {
 	recentLabel.TextStyle.Bold = true
 	recentList := widget.NewLabel("Loading...")
 
+	var recent []recentEntry
 	refreshRecent := func() {
+		recent = nil
 		entries, err := storage.LoadDiapers()
 		if err != nil || len(entries) == 0 {
 			recentList.SetText("No diaper changes logged yet")
@@ -110,7 +109,9 @@ func CreateSusuPotyTab() *fyne.Container {
 		lines := ""
 		for i := len(entries) - 1; i >= 0; i-- {
 			e := entries[i]
-			lines += fmt.Sprintf("%s %s — %s\n", e.Date, e.Time.Format("15:04"), e.Type)
+			line := fmt.Sprintf("%s %s — %s", e.Date, e.Time.InHousehold().Format("15:04"), e.Type)
+			lines += line + byline(e.LoggedBy) + "\n"
+			recent = append(recent, recentEntry{ID: e.ID, Version: models.EntryVersion(e.Version), Label: line})
 		}
 		recentList.SetText(lines)
 	}
@@ -127,6 +128,8 @@ func CreateSusuPotyTab() *fyne.Container {
 			container.NewVBox(diaperForm, quickActions, logButton)),
 		widget.NewSeparator(),
 		widget.NewCard("Recent Activity", "Your recent diaper logs",
-			container.NewVBox(recentLabel, recentList)),
+			container.NewVBox(recentLabel, recentList,
+				deleteControls(session, models.ResourceDiapers, &recent, storage.DeleteDiaper, refreshRecent),
+				historyButton(session, models.ResourceDiapers))),
 	)
 }
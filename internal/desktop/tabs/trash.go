@@ -0,0 +1,83 @@
+package tabs
+
+import (
+	"context"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"babytracker/internal/storage"
+)
+
+// undoWindow is how long the Undo button stays up after a delete. The entry
+// can still be restored from the trash afterwards (GET /api/trash).
+const undoWindow = 10 * time.Second
+
+// recentEntry is one line of a tab's recent activity, offered for deletion.
+type recentEntry struct {
+	ID      int
+	Version int // Version shown, so a delete fails if the entry changed meanwhile
+	Label   string
+}
+
+// deleteControls returns a "Delete..." button that moves one of the recent
+// entries to the trash, followed by an Undo bar that restores it.
+// recent is kept up to date by the tab's refresh function.
+func deleteControls(session *Session, resource string, recent *[]recentEntry,
+	del func(ctx context.Context, id, ifVersion int) error, refresh func()) fyne.CanvasObject {
+	undoLabel := widget.NewLabel("")
+	undoButton := widget.NewButton("Undo", nil)
+	undoBar := container.NewHBox(undoLabel, undoButton)
+	undoBar.Hide()
+
+	var deletedID int
+	var hideTimer *time.Timer
+	undoButton.OnTapped = func() {
+		if hideTimer != nil {
+			hideTimer.Stop()
+		}
+		undoBar.Hide()
+		if err := storage.RestoreEntry(session.Context(), resource, deletedID); err != nil {
+			dialog.ShowError(err, session.Window)
+			return
+		}
+		refresh()
+	}
+
+	deleteButton := widget.NewButton("Delete...", func() {
+		if len(*recent) == 0 {
+			return
+		}
+		options := make([]string, len(*recent))
+		for i, e := range *recent {
+			options[i] = e.Label
+		}
+		entrySelect := widget.NewSelect(options, nil)
+		entrySelect.PlaceHolder = "Select entry..."
+		dialog.ShowCustomConfirm("Delete Entry", "Delete", "Cancel", entrySelect, func(ok bool) {
+			i := entrySelect.SelectedIndex()
+			if !ok || i < 0 {
+				return
+			}
+			entry := (*recent)[i]
+			if err := del(session.Context(), entry.ID, entry.Version); err != nil {
+				dialog.ShowError(err, session.Window)
+				return
+			}
+			refresh()
+
+			deletedID = entry.ID
+			undoLabel.SetText("Deleted " + entry.Label)
+			undoBar.Show()
+			if hideTimer != nil {
+				hideTimer.Stop()
+			}
+			hideTimer = time.AfterFunc(undoWindow, func() { fyne.Do(undoBar.Hide) })
+		}, session.Window)
+	})
+
+	return container.NewVBox(deleteButton, undoBar)
+}
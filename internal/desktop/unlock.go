@@ -0,0 +1,35 @@
+package desktop
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"babytracker/internal/storage"
+)
+
+// createUnlockContent asks for the passphrase of an encrypted data directory
+// before any data is loaded, then calls onUnlocked.
+func (a *App) createUnlockContent(onUnlocked func()) fyne.CanvasObject {
+	passphrase := widget.NewPasswordEntry()
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+
+	unlock := func() {
+		if err := storage.Unlock(passphrase.Text); err != nil {
+			status.SetText(err.Error())
+			passphrase.SetText("")
+			return
+		}
+		onUnlocked()
+	}
+	passphrase.OnSubmitted = func(string) { unlock() }
+
+	form := widget.NewForm(widget.NewFormItem("Passphrase", passphrase))
+	form.SubmitText = "Unlock"
+	form.OnSubmit = unlock
+
+	title := widget.NewLabelWithStyle("Your baby data is encrypted", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	box := container.NewVBox(title, form, status)
+	return container.NewCenter(container.NewGridWrap(fyne.NewSize(400, 180), box))
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Audit actions, one per kind of mutation.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"  // Entries: moved to the trash
+	AuditRestore = "restore" // Entries: brought back from the trash
+	AuditPurge   = "purge"   // Entries: removed from the trash for good
+)
+
+// Audited resources beyond the shareable entry resources.
+const (
+	ResourceCaregivers = "caregivers"
+	ResourceUsers      = "users"
+	ResourceTokens     = "tokens"
+)
+
+// AuditEntry records one change made through storage. Before is empty for
+// creates and After is empty for deletes.
+type AuditEntry struct {
+	Time     time.Time       `json:"time"`
+	Actor    string          `json:"actor,omitempty"`   // Caregiver or account name; empty when anonymous
+	Address  string          `json:"address,omitempty"` // Client IP, or "desktop"
+	Action   string          `json:"action"`            // create, update, delete, restore or purge
+	Resource string          `json:"resource"`          // feeds, sleep, growth, diapers, caregivers, users, tokens
+	EntityID int             `json:"entity_id"`
+	Before   json.RawMessage `json:"before,omitempty"`
+	After    json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Resource string
+	EntityID int
+	Actor    string // Case-insensitive
+	Action   string
+	Since    time.Time // Inclusive
+	Until    time.Time // Exclusive
+}
+
+// Matches reports whether e passes every set field of the filter.
+func (f AuditFilter) Matches(e AuditEntry) bool {
+	switch {
+	case f.Resource != "" && e.Resource != f.Resource:
+		return false
+	case f.EntityID != 0 && e.EntityID != f.EntityID:
+		return false
+	case f.Actor != "" && !SameName(e.Actor, f.Actor):
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// FieldChange is one top-level field that differs between Before and After.
+type FieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"` // Absent when the field was added
+	After  json.RawMessage `json:"after,omitempty"`  // Absent when the field was removed
+}
+
+// Changes lists the top-level fields that differ between Before and After,
+// sorted by field name. Creates and deletes list every field that was set.
+// The version counter and UID are bookkeeping, not edits, and are left out.
+func (e AuditEntry) Changes() []FieldChange {
+	var before, after map[string]json.RawMessage
+	_ = json.Unmarshal(e.Before, &before)
+	_ = json.Unmarshal(e.After, &after)
+
+	fields := map[string]bool{}
+	for k := range before {
+		fields[k] = true
+	}
+	for k := range after {
+		fields[k] = true
+	}
+	var changes []FieldChange
+	for k := range fields {
+		b, a := before[k], after[k]
+		if k == "version" || k == "uid" || bytes.Equal(b, a) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: k, Before: b, After: a})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAuditFilter_Matches(t *testing.T) {
+	at := time.Date(2026, 4, 6, 9, 0, 0, 0, time.UTC)
+	e := AuditEntry{Time: at, Actor: "Asha", Action: AuditDelete, Resource: ResourceFeeds, EntityID: 3}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   bool
+	}{
+		{"empty matches all", AuditFilter{}, true},
+		{"resource and id", AuditFilter{Resource: ResourceFeeds, EntityID: 3}, true},
+		{"other id", AuditFilter{EntityID: 4}, false},
+		{"actor is case-insensitive", AuditFilter{Actor: "asha"}, true},
+		{"other action", AuditFilter{Action: AuditCreate}, false},
+		{"since is inclusive", AuditFilter{Since: at}, true},
+		{"until is exclusive", AuditFilter{Until: at}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Matches(e); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAuditEntry_Changes(t *testing.T) {
+	e := AuditEntry{
+		Before: json.RawMessage(`{"id":1,"type":"Bottle","quantity":120,"notes":"fussy","version":1}`),
+		After:  json.RawMessage(`{"id":1,"type":"Bottle","quantity":150,"version":2}`),
+	}
+	changes := e.Changes()
+	if len(changes) != 2 {
+		t.Fatalf("got %d changes, want 2: %+v", len(changes), changes)
+	}
+	if changes[0].Field != "notes" || changes[0].After != nil {
+		t.Errorf("expected notes to be removed first, got %+v", changes[0])
+	}
+	if changes[1].Field != "quantity" || string(changes[1].Before) != "120" || string(changes[1].After) != "150" {
+		t.Errorf("unexpected quantity change: %+v", changes[1])
+	}
+}
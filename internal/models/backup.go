@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Backup labels. Scheduled backups are rotated by the retention policy; the
+// others are kept until deleted by hand.
+const (
+	BackupScheduled = "scheduled"
+	BackupManual    = "manual"
+	BackupRestore   = "pre-restore" // Taken just before a restore replaces the data files
+	BackupRepair    = "pre-repair"  // Taken just before fsck rewrites damaged files
+)
+
+// Backup describes a compressed snapshot of the data directory. The same
+// structure is stored inside the archive as its manifest.
+type Backup struct {
+	Name      string       `json:"name"`  // Archive file name in the backups directory
+	Label     string       `json:"label"` // scheduled, manual, pre-restore, pre-repair or pre-migration-v<N>
+	Created   time.Time    `json:"created"`
+	Schema    int          `json:"schema"`         // Data directory schema version when taken
+	Encrypted bool         `json:"encrypted"`      // Files were taken as stored, still encrypted
+	Size      int64        `json:"size,omitempty"` // Archive size in bytes; not part of the manifest
+	Files     []BackupFile `json:"files"`
+}
+
+// BackupFile is one data file in a backup, with the checksum it is verified against.
+type BackupFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"` // Hex digest of the file as stored
+}
+
+// BackupPolicy is how many scheduled backups to keep: the newest of each of
+// the last Hourly hours, Daily days and Weekly ISO weeks that have one. The
+// newest backup is always kept; an all-zero policy keeps everything.
+type BackupPolicy struct {
+	Hourly int
+	Daily  int
+	Weekly int
+}
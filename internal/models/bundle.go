@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// BundleFormat is the version of the export bundle layout.
+const BundleFormat = 1
+
+// Bundle is a portable copy of every entry, for moving a household's data to
+// another device or merging two. Entries are matched by UID on import; their
+// integer IDs are local to the data directory they came from.
+type Bundle struct {
+	Format     int           `json:"format"`
+	ExportedAt time.Time     `json:"exported_at"`
+	Feeds      []FeedEntry   `json:"feeds"`
+	Sleep      []SleepEntry  `json:"sleep"`
+	Growth     []GrowthEntry `json:"growth"`
+	Diapers    []DiaperEntry `json:"diapers"`
+}
+
+// ImportResult counts what an import did, per resource.
+type ImportResult struct {
+	Added   map[string]int `json:"added"`   // New entries, given local IDs
+	Skipped map[string]int `json:"skipped"` // Entries whose UID is already here
+}
@@ -0,0 +1,12 @@
+package models
+
+// Caregiver is a person who logs entries (parent, nanny, grandparent...).
+// Entries reference caregivers by Name through their LoggedBy field.
+type Caregiver struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`               // Unique, shown on entries
+	Relation string `json:"relation,omitempty"` // e.g. Mum, Dad, Nanny
+}
+
+// UnattributedCaregiver is the summary breakdown key for entries with no LoggedBy.
+const UnattributedCaregiver = "unattributed"
@@ -2,11 +2,14 @@ package models
 
 // DiaperEntry represents a single diaper change record.
 type DiaperEntry struct {
-	ID    int      `json:"id"`
-	Date  string   `json:"date"` // YYYY-MM-DD
-	Time  FlexTime `json:"time"` // When the change occurred
-	Type  string   `json:"type"` // wet, dirty, mixed
-	Notes string   `json:"notes"`
+	ID       int      `json:"id"`
+	UID      string   `json:"uid,omitempty"` // Globally unique, sortable ID (ULID)
+	Date     string   `json:"date"`          // YYYY-MM-DD
+	Time     FlexTime `json:"time"`          // When the change occurred
+	Type     string   `json:"type"`          // wet, dirty, mixed
+	Notes    string   `json:"notes"`
+	LoggedBy string   `json:"logged_by,omitempty"` // Caregiver who logged the change
+	Version  int      `json:"version"`             // Incremented on every update, for ETag / If-Match
 }
 
 // Diaper type constants
@@ -25,3 +28,10 @@ func (d *DiaperEntry) IsWet() bool {
 func (d *DiaperEntry) IsDirty() bool {
 	return d.Type == DiaperTypeDirty || d.Type == DiaperTypeMixed
 }
+
+// SyncDate derives Date from Time in the household time zone (no-op when Time is unset).
+func (d *DiaperEntry) SyncDate() {
+	if date := d.Time.DateString(); date != "" {
+		d.Date = date
+	}
+}
@@ -7,13 +7,18 @@ package models
 // This structure captures all relevant information about a baby's feeding,
 // including timing, quantity, type, and contextual notes for comprehensive tracking.
 type FeedEntry struct {
-	ID       int      `json:"id"`       // Unique identifier for database storage
-	Date     string   `json:"date"`     // Date of the feeding (YYYY-MM-DD)
-	Time     FlexTime `json:"time"`     // When the feeding occurred
-	Type     string   `json:"type"`     // Type of feed (bottle, breast, solid)
-	Quantity float64  `json:"quantity"` // Amount consumed (ml/oz), 0 if not applicable
-	Notes    string   `json:"notes"`    // Additional observations or comments
-	Duration int      `json:"duration"` // Feeding duration in minutes (for breastfeeding)
+	ID           int      `json:"id"`                      // Unique identifier for database storage
+	UID          string   `json:"uid,omitempty"`           // Globally unique, sortable ID (ULID), stable across devices and exports
+	Date         string   `json:"date"`                    // Date of the feeding (YYYY-MM-DD)
+	Time         FlexTime `json:"time"`                    // When the feeding occurred
+	Type         string   `json:"type"`                    // Type of feed (bottle, breast, solid)
+	Quantity     float64  `json:"quantity"`                // Amount consumed in QuantityUnit, 0 if not applicable
+	QuantityUnit string   `json:"quantity_unit,omitempty"` // Unit of a bottle feed's Quantity (ml or oz); always ml once stored, empty means ml. Empty for other feeds
+	EnteredUnit  string   `json:"entered_unit,omitempty"`  // Unit the caregiver originally entered a bottle feed's quantity in
+	Notes        string   `json:"notes"`                   // Additional observations or comments
+	Duration     int      `json:"duration"`                // Feeding duration in minutes (for breastfeeding)
+	LoggedBy     string   `json:"logged_by,omitempty"`     // Caregiver who logged the feed
+	Version      int      `json:"version"`                 // Incremented on every update, for ETag / If-Match
 }
 
 // FeedType constants for consistent feed categorization
@@ -39,7 +44,49 @@ func (f *FeedEntry) IsBreastFeed() bool {
 }
 
 // HasQuantity checks if this feed type typically has a measurable quantity
-// Useful for form validation and data display logic
+// Useful for form validation and data display logic; only a bottle feed's is a volume
 func (f *FeedEntry) HasQuantity() bool {
 	return f.IsBottleFeed() || f.Type == FeedTypeSolid
 }
+
+// SyncDate derives Date from Time in the household time zone
+// Keeps the two fields from disagreeing near midnight; no-op when Time is unset
+func (f *FeedEntry) SyncDate() {
+	if d := f.Time.DateString(); d != "" {
+		f.Date = d
+	}
+}
+
+// Canonicalize converts a bottle feed's Quantity to millilitres for storage
+// The unit it arrived in is kept in EnteredUnit so it can be shown back the same way.
+// Other feeds' quantities (a solid's spoonfuls, say) aren't volumes and are kept as entered
+func (f *FeedEntry) Canonicalize() error {
+	if !f.IsBottleFeed() {
+		f.QuantityUnit, f.EnteredUnit = "", ""
+		return nil
+	}
+	unit := f.QuantityUnit
+	if unit == "" {
+		unit = UnitML
+	}
+	ml, err := ToML(f.Quantity, unit)
+	if err != nil {
+		return err
+	}
+	if unit != UnitML || f.EnteredUnit == "" {
+		f.EnteredUnit = unit
+	}
+	f.Quantity = ml
+	f.QuantityUnit = UnitML
+	return nil
+}
+
+// InUnits returns a copy of a stored (canonical) entry with a bottle feed's Quantity expressed in u.Volume
+func (f FeedEntry) InUnits(u Units) FeedEntry {
+	if !f.IsBottleFeed() {
+		return f
+	}
+	f.Quantity = FromML(f.Quantity, u.Volume)
+	f.QuantityUnit = u.Volume
+	return f
+}
@@ -8,12 +8,13 @@ import (
 
 // FlexTime wraps time.Time with flexible JSON unmarshaling.
 // Accepts both RFC3339 ("2006-01-02T15:04:05Z") and timezone-less ("2006-01-02T15:04:05") formats.
+// Timezone-less values are interpreted in the household time zone (see SetLocation).
 type FlexTime struct {
 	time.Time
 }
 
-var flexTimeFormats = []string{
-	time.RFC3339,
+// flexTimeZonelessFormats are parsed with time.ParseInLocation in the household zone.
+var flexTimeZonelessFormats = []string{
 	"2006-01-02T15:04:05",
 	"2006-01-02T15:04",
 }
@@ -24,8 +25,12 @@ func (ft *FlexTime) UnmarshalJSON(data []byte) error {
 		ft.Time = time.Time{}
 		return nil
 	}
-	for _, layout := range flexTimeFormats {
-		if t, err := time.Parse(layout, s); err == nil {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		ft.Time = t
+		return nil
+	}
+	for _, layout := range flexTimeZonelessFormats {
+		if t, err := time.ParseInLocation(layout, s, Location()); err == nil {
 			ft.Time = t
 			return nil
 		}
@@ -39,3 +44,17 @@ func (ft FlexTime) MarshalJSON() ([]byte, error) {
 	}
 	return []byte(fmt.Sprintf("%q", ft.Time.Format(time.RFC3339))), nil
 }
+
+// DateString returns the YYYY-MM-DD date of the time in the household time zone,
+// or "" for the zero time.
+func (ft FlexTime) DateString() string {
+	if ft.Time.IsZero() {
+		return ""
+	}
+	return ft.Time.In(Location()).Format(time.DateOnly)
+}
+
+// InHousehold returns the time in the household time zone.
+func (ft FlexTime) InHousehold() time.Time {
+	return ft.Time.In(Location())
+}
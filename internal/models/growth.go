@@ -3,11 +3,18 @@ package models
 // GrowthEntry represents a single growth measurement record.
 type GrowthEntry struct {
 	ID                int     `json:"id"`
-	Date              string  `json:"date"`      // YYYY-MM-DD
-	Weight            float64 `json:"weight"`    // kg
-	Height            float64 `json:"height"`    // cm
-	HeadCircumference float64 `json:"head_circ"` // cm
+	UID               string  `json:"uid,omitempty"`                 // Globally unique, sortable ID (ULID)
+	Date              string  `json:"date"`                          // YYYY-MM-DD
+	Weight            float64 `json:"weight"`                        // In WeightUnit (kg once stored)
+	Height            float64 `json:"height"`                        // In LengthUnit (cm once stored)
+	HeadCircumference float64 `json:"head_circ"`                     // In LengthUnit (cm once stored)
+	WeightUnit        string  `json:"weight_unit,omitempty"`         // kg or lb; empty means kg
+	LengthUnit        string  `json:"length_unit,omitempty"`         // cm or in, for height and head circumference; empty means cm
+	EnteredWeightUnit string  `json:"entered_weight_unit,omitempty"` // Unit the weight was originally entered in
+	EnteredLengthUnit string  `json:"entered_length_unit,omitempty"` // Unit the lengths were originally entered in
 	Notes             string  `json:"notes"`
+	LoggedBy          string  `json:"logged_by,omitempty"` // Caregiver who logged the measurement
+	Version           int     `json:"version"`             // Incremented on every update, for ETag / If-Match
 }
 
 // HasWeight checks if weight was recorded.
@@ -24,3 +31,46 @@ func (g *GrowthEntry) HasHeight() bool {
 func (g *GrowthEntry) HasHeadCircumference() bool {
 	return g.HeadCircumference > 0
 }
+
+// Canonicalize converts measurements to kg and cm for storage,
+// remembering the units they were entered in.
+func (g *GrowthEntry) Canonicalize() error {
+	weightUnit := g.WeightUnit
+	if weightUnit == "" {
+		weightUnit = UnitKg
+	}
+	lengthUnit := g.LengthUnit
+	if lengthUnit == "" {
+		lengthUnit = UnitCm
+	}
+	weight, err := ToKg(g.Weight, weightUnit)
+	if err != nil {
+		return err
+	}
+	height, err := ToCm(g.Height, lengthUnit)
+	if err != nil {
+		return err
+	}
+	head, err := ToCm(g.HeadCircumference, lengthUnit)
+	if err != nil {
+		return err
+	}
+	if weightUnit != UnitKg || g.EnteredWeightUnit == "" {
+		g.EnteredWeightUnit = weightUnit
+	}
+	if lengthUnit != UnitCm || g.EnteredLengthUnit == "" {
+		g.EnteredLengthUnit = lengthUnit
+	}
+	g.Weight, g.Height, g.HeadCircumference = weight, height, head
+	g.WeightUnit, g.LengthUnit = UnitKg, UnitCm
+	return nil
+}
+
+// InUnits returns a copy of a stored (canonical) entry with measurements in u.Weight and u.Length.
+func (g GrowthEntry) InUnits(u Units) GrowthEntry {
+	g.Weight = FromKg(g.Weight, u.Weight)
+	g.Height = FromCm(g.Height, u.Length)
+	g.HeadCircumference = FromCm(g.HeadCircumference, u.Length)
+	g.WeightUnit, g.LengthUnit = u.Weight, u.Length
+	return g
+}
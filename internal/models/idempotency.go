@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IdempotencyKey remembers a write made under a client-generated key, so a
+// retried write is answered without being applied twice. Batch operations
+// record the entry they produced; requests sent with an Idempotency-Key header
+// record the response.
+type IdempotencyKey struct {
+	Key      string          `json:"key"`
+	Action   string          `json:"action,omitempty"`   // Batch op: create, update or delete
+	Resource string          `json:"resource,omitempty"` // Batch op: feeds, sleep, growth or diapers
+	ID       int             `json:"id,omitempty"`       // Batch op: the entry the write created or changed
+	Actor    string          `json:"actor,omitempty"`    // Header: who sent the request; nobody else can replay it
+	Request  string          `json:"request,omitempty"`  // Header: method and path, e.g. "POST /api/feeds"
+	Digest   string          `json:"digest,omitempty"`   // Header: SHA-256 of the request body
+	Status   int             `json:"status,omitempty"`   // Header: response status
+	Response json.RawMessage `json:"response,omitempty"` // Header: response body
+	Withheld bool            `json:"withheld,omitempty"` // Header: the response held a secret, so it was not kept
+	Time     time.Time       `json:"time"`
+}
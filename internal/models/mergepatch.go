@@ -0,0 +1,64 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrPatchNotObject is returned by MergePatch for a patch that is not a JSON
+// object; such a patch would replace the whole entry rather than edit it.
+var ErrPatchNotObject = errors.New("merge patch must be a JSON object")
+
+// MergePatch applies a JSON Merge Patch (RFC 7386) to a JSON document: patch
+// members replace the target's, null removes a member, and nested objects merge
+// recursively. Numbers pass through unchanged.
+func MergePatch(target, patch json.RawMessage) (json.RawMessage, error) {
+	p, err := decodeJSON(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	if _, ok := p.(map[string]any); !ok {
+		return nil, ErrPatchNotObject
+	}
+	t, err := decodeJSON(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch target: %w", err)
+	}
+	return json.Marshal(mergeValue(t, p))
+}
+
+// mergeValue is the MergePatch algorithm from RFC 7386 section 2.
+func mergeValue(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergeValue(t[k], v)
+		}
+	}
+	return t
+}
+
+// decodeJSON decodes a single JSON value, keeping numbers as json.Number.
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name, target, patch, want string
+	}{
+		{"replace a member", `{"a":"b","n":1}`, `{"a":"c"}`, `{"a":"c","n":1}`},
+		{"add a member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null removes", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"nested objects merge", `{"a":{"b":"c","d":"e"}}`, `{"a":{"d":null,"f":1}}`, `{"a":{"b":"c","f":1}}`},
+		{"arrays are replaced", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{"numbers keep their precision", `{"q":120.5}`, `{"notes":"x"}`, `{"notes":"x","q":120.5}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch(json.RawMessage(tt.target), json.RawMessage(tt.patch))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMergePatch_Invalid(t *testing.T) {
+	if _, err := MergePatch(json.RawMessage(`{}`), json.RawMessage(`["a"]`)); !errors.Is(err, ErrPatchNotObject) {
+		t.Errorf("array patch: got %v, want ErrPatchNotObject", err)
+	}
+	if _, err := MergePatch(json.RawMessage(`{}`), json.RawMessage(`{"a":`)); err == nil {
+		t.Error("malformed patch: expected an error")
+	}
+}
@@ -0,0 +1,9 @@
+package models
+
+import "strings"
+
+// SameName compares people's names (users, caregivers, the child)
+// case-insensitively, ignoring surrounding spaces.
+func SameName(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// EntryQuery selects a page of a resource's entries, newest first. Zero
+// fields match everything.
+type EntryQuery struct {
+	From     string // Inclusive YYYY-MM-DD lower bound on the entry date
+	To       string // Inclusive YYYY-MM-DD upper bound on the entry date
+	LoggedBy string // Case-insensitive caregiver name
+	Limit    int    // Page size, 0 = all
+	Offset   int    // Matching entries to skip, newest first
+}
+
+// Validate checks that From and To are dates and in order.
+func (q EntryQuery) Validate() error {
+	for _, d := range []string{q.From, q.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, d); err != nil {
+			return fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", d)
+		}
+	}
+	if q.From != "" && q.To != "" && q.To < q.From {
+		return fmt.Errorf("to (%s) is before from (%s)", q.To, q.From)
+	}
+	return nil
+}
+
+// Filtered reports whether the query filters entries rather than only paging them.
+func (q EntryQuery) Filtered() bool {
+	return q.From != "" || q.To != "" || q.LoggedBy != ""
+}
+
+// Matches reports whether an entry with the given date and caregiver passes
+// the query's filters. YYYY-MM-DD dates compare correctly as strings.
+func (q EntryQuery) Matches(date, loggedBy string) bool {
+	switch {
+	case q.From != "" && date < q.From:
+		return false
+	case q.To != "" && date > q.To:
+		return false
+	case q.LoggedBy != "" && !SameName(loggedBy, q.LoggedBy):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RevisionInitial marks a revision holding an entry's state from before the
+// audit log recorded its first change.
+const RevisionInitial = "initial"
+
+// Revision is one state of an entry, reconstructed from the audit log.
+type Revision struct {
+	Revision int             `json:"revision"` // 1-based, oldest first
+	Time     time.Time       `json:"time"`     // Zero for RevisionInitial
+	Actor    string          `json:"actor,omitempty"`
+	Action   string          `json:"action"`            // Audit action that produced this state, or RevisionInitial
+	Entry    json.RawMessage `json:"entry,omitempty"`   // The entry after the change; empty once deleted or purged
+	Changes  []FieldChange   `json:"changes,omitempty"` // Fields that differ from the previous state
+}
+
+// Revisions turns an entry's audit trail (oldest first) into its revision list.
+// current is the entry as stored now (nil if it is not in its data file); it
+// stands in as the initial revision for entries the audit log has never seen.
+// When the trail starts with anything but a create, the state before that first
+// change becomes the initial revision.
+func Revisions(trail []AuditEntry, current json.RawMessage) []Revision {
+	var revs []Revision
+	if len(trail) == 0 {
+		if current != nil {
+			revs = append(revs, Revision{Revision: 1, Action: RevisionInitial, Entry: current})
+		}
+		return revs
+	}
+	if first := trail[0]; first.Action != AuditCreate && first.Before != nil {
+		revs = append(revs, Revision{Revision: 1, Action: RevisionInitial, Entry: first.Before})
+	}
+	var last json.RawMessage // Most recent existing state, for diffs across a delete/restore
+	if len(revs) > 0 {
+		last = revs[0].Entry
+	}
+	for _, e := range trail {
+		rev := Revision{Revision: len(revs) + 1, Time: e.Time, Actor: e.Actor, Action: e.Action, Entry: e.After}
+		if e.After != nil {
+			rev.Changes = AuditEntry{Before: last, After: e.After}.Changes()
+			last = e.After
+		}
+		revs = append(revs, rev)
+	}
+	return revs
+}
+
+// StateAt returns an entry's state at instant t given its audit trail (oldest
+// first) and current state, or nil if it did not exist then or isn't known to
+// have. since is when the audit log begins: an entry that predates it is taken
+// to be unchanged from then until its first recorded change, and unknown before.
+func StateAt(trail []AuditEntry, current json.RawMessage, t, since time.Time) json.RawMessage {
+	for i := len(trail) - 1; i >= 0; i-- {
+		if !trail[i].Time.After(t) {
+			return trail[i].After
+		}
+	}
+	if t.Before(since) {
+		return nil
+	}
+	if len(trail) == 0 {
+		return current
+	}
+	return trail[0].Before
+}
+
+// EntryVersion returns an entry's stored version for ETags and If-Match checks,
+// counting entries saved before versions were tracked (0) as version 1.
+func EntryVersion(v int) int {
+	return max(v, 1)
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRevisions(t *testing.T) {
+	t0 := time.Date(2026, 4, 6, 9, 0, 0, 0, time.UTC)
+	trail := []AuditEntry{
+		{Time: t0, Actor: "Asha", Action: AuditCreate, After: json.RawMessage(`{"id":1,"quantity":120}`)},
+		{Time: t0.Add(time.Hour), Actor: "Ben", Action: AuditUpdate,
+			Before: json.RawMessage(`{"id":1,"quantity":120}`), After: json.RawMessage(`{"id":1,"quantity":150}`)},
+		{Time: t0.Add(2 * time.Hour), Action: AuditDelete, Before: json.RawMessage(`{"id":1,"quantity":150}`)},
+		{Time: t0.Add(3 * time.Hour), Action: AuditRestore, After: json.RawMessage(`{"id":1,"quantity":150}`)},
+	}
+	revs := Revisions(trail, json.RawMessage(`{"id":1,"quantity":150}`))
+	if len(revs) != 4 {
+		t.Fatalf("got %d revisions, want 4: %+v", len(revs), revs)
+	}
+	if revs[1].Revision != 2 || revs[1].Actor != "Ben" || len(revs[1].Changes) != 1 || revs[1].Changes[0].Field != "quantity" {
+		t.Errorf("unexpected update revision: %+v", revs[1])
+	}
+	if revs[2].Entry != nil || revs[2].Changes != nil {
+		t.Errorf("delete revision should have no entry or changes: %+v", revs[2])
+	}
+	if len(revs[3].Changes) != 0 {
+		t.Errorf("restore diffs against the state before the delete, got %+v", revs[3].Changes)
+	}
+}
+
+func TestRevisions_PredatesAuditLog(t *testing.T) {
+	if revs := Revisions(nil, json.RawMessage(`{"id":1}`)); len(revs) != 1 || revs[0].Action != RevisionInitial {
+		t.Errorf("untouched entry: got %+v, want a single initial revision", revs)
+	}
+	if revs := Revisions(nil, nil); len(revs) != 0 {
+		t.Errorf("unknown entry: got %+v, want none", revs)
+	}
+	trail := []AuditEntry{{Action: AuditUpdate, Before: json.RawMessage(`{"id":1,"notes":"a"}`), After: json.RawMessage(`{"id":1,"notes":"b"}`)}}
+	revs := Revisions(trail, json.RawMessage(`{"id":1,"notes":"b"}`))
+	if len(revs) != 2 || revs[0].Action != RevisionInitial || string(revs[0].Entry) != `{"id":1,"notes":"a"}` {
+		t.Errorf("got %+v, want the pre-update state as the initial revision", revs)
+	}
+}
+
+func TestStateAt(t *testing.T) {
+	t0 := time.Date(2026, 4, 6, 9, 0, 0, 0, time.UTC)
+	trail := []AuditEntry{
+		{Time: t0, Action: AuditUpdate, Before: json.RawMessage(`"v1"`), After: json.RawMessage(`"v2"`)},
+		{Time: t0.Add(time.Hour), Action: AuditDelete, Before: json.RawMessage(`"v2"`)},
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		{"before the first change", t0.Add(-time.Minute), `"v1"`},
+		{"before the audit log", t0.Add(-2 * time.Hour), ``},
+		{"at the change", t0, `"v2"`},
+		{"after the delete", t0.Add(2 * time.Hour), ``},
+	}
+	since := t0.Add(-time.Hour)
+	for _, tt := range tests {
+		if got := StateAt(trail, nil, tt.at, since); string(got) != tt.want {
+			t.Errorf("%s: StateAt = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if got := StateAt(nil, json.RawMessage(`"now"`), t0, since); string(got) != `"now"` {
+		t.Errorf("no trail: StateAt = %s, want the current state", got)
+	}
+	if got := StateAt(nil, json.RawMessage(`"now"`), since.Add(-time.Minute), since); got != nil {
+		t.Errorf("no trail, before the audit log: StateAt = %s, want nil", got)
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Resources a share link can expose.
+const (
+	ResourceFeeds   = "feeds"
+	ResourceSleep   = "sleep"
+	ResourceDiapers = "diapers"
+	ResourceGrowth  = "growth"
+)
+
+// AllResources lists every shareable resource, in report order.
+var AllResources = []string{ResourceFeeds, ResourceSleep, ResourceDiapers, ResourceGrowth}
+
+// MaxShareDays caps a share link's date range, since every view of the link
+// summarizes each day in it.
+const MaxShareDays = 366
+
+// ShareClaims describe what a read-only share link grants. They are signed into
+// the link itself, so nothing is stored server-side and links cannot be widened.
+type ShareClaims struct {
+	Child     string    `json:"child"`     // Child the link was issued for (CHILD_NAME)
+	Resources []string  `json:"resources"` // Subset of AllResources
+	From      string    `json:"from"`      // First day included, YYYY-MM-DD
+	To        string    `json:"to"`        // Last day included, YYYY-MM-DD
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// Validate checks that the claims name known resources and a sensible date range.
+func (c ShareClaims) Validate() error {
+	if len(c.Resources) == 0 {
+		return errors.New("at least one resource is required")
+	}
+	for _, r := range c.Resources {
+		if !slices.Contains(AllResources, r) {
+			return fmt.Errorf("unknown resource %q (expected feeds, sleep, diapers or growth)", r)
+		}
+	}
+	from, err := time.Parse("2006-01-02", c.From)
+	if err != nil {
+		return fmt.Errorf("invalid from date %q", c.From)
+	}
+	to, err := time.Parse("2006-01-02", c.To)
+	if err != nil {
+		return fmt.Errorf("invalid to date %q", c.To)
+	}
+	if to.Before(from) {
+		return errors.New("to date is before from date")
+	}
+	if to.Sub(from) >= MaxShareDays*24*time.Hour {
+		return fmt.Errorf("a share link covers at most %d days", MaxShareDays)
+	}
+	if c.ExpiresAt.IsZero() {
+		return errors.New("expiry is required")
+	}
+	return nil
+}
+
+// Allows reports whether the link grants access to the resource.
+func (c ShareClaims) Allows(resource string) bool {
+	return slices.Contains(c.Resources, resource)
+}
+
+// Covers reports whether a YYYY-MM-DD date falls inside the shared range.
+func (c ShareClaims) Covers(date string) bool {
+	return date >= c.From && date <= c.To
+}
+
+// Days returns every date in the range, newest first.
+func (c ShareClaims) Days() []string {
+	from, err := time.Parse("2006-01-02", c.From)
+	if err != nil {
+		return nil
+	}
+	to, err := time.Parse("2006-01-02", c.To)
+	if err != nil {
+		return nil
+	}
+	var days []string
+	for d := to; !d.Before(from); d = d.AddDate(0, 0, -1) {
+		days = append(days, d.Format("2006-01-02"))
+	}
+	return days
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShareClaimsValidate(t *testing.T) {
+	valid := ShareClaims{
+		Resources: []string{ResourceFeeds},
+		From:      "2026-04-01",
+		To:        "2026-04-30",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid claims, got %v", err)
+	}
+
+	bad := []func(c *ShareClaims){
+		func(c *ShareClaims) { c.Resources = nil },
+		func(c *ShareClaims) { c.Resources = []string{"passwords"} },
+		func(c *ShareClaims) { c.From = "April" },
+		func(c *ShareClaims) { c.To = "2026-03-01" },
+		func(c *ShareClaims) { c.From, c.To = "0001-01-01", "9999-12-31" },
+		func(c *ShareClaims) { c.From, c.To = "2026-04-01", "2027-04-02" },
+		func(c *ShareClaims) { c.ExpiresAt = time.Time{} },
+	}
+	year := valid
+	year.From, year.To = "2026-01-01", "2027-01-01"
+	if err := year.Validate(); err != nil {
+		t.Errorf("expected %d days to be allowed, got %v", MaxShareDays, err)
+	}
+	for i, mutate := range bad {
+		c := valid
+		mutate(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestShareClaimsDays(t *testing.T) {
+	c := ShareClaims{From: "2026-02-27", To: "2026-03-02"}
+	want := []string{"2026-03-02", "2026-03-01", "2026-02-28", "2026-02-27"}
+	got := c.Days()
+	if len(got) != len(want) {
+		t.Fatalf("Days() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Days()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+	if !c.Covers("2026-02-28") || c.Covers("2026-03-03") {
+		t.Error("Covers disagrees with the range")
+	}
+}
@@ -3,13 +3,16 @@ package models
 // SleepEntry represents a single sleep session record.
 type SleepEntry struct {
 	ID        int      `json:"id"`
-	Date      string   `json:"date"`       // YYYY-MM-DD
-	StartTime FlexTime `json:"start_time"` // When sleep began
-	EndTime   FlexTime `json:"end_time"`   // When sleep ended
-	Duration  int      `json:"duration"`   // Duration in minutes
-	Type      string   `json:"type"`       // nap, night
-	Quality   string   `json:"quality"`    // good, fair, poor
+	UID       string   `json:"uid,omitempty"` // Globally unique, sortable ID (ULID)
+	Date      string   `json:"date"`          // YYYY-MM-DD
+	StartTime FlexTime `json:"start_time"`    // When sleep began
+	EndTime   FlexTime `json:"end_time"`      // When sleep ended
+	Duration  int      `json:"duration"`      // Duration in minutes
+	Type      string   `json:"type"`          // nap, night
+	Quality   string   `json:"quality"`       // good, fair, poor
 	Notes     string   `json:"notes"`
+	LoggedBy  string   `json:"logged_by,omitempty"` // Caregiver who logged the sleep
+	Version   int      `json:"version"`             // Incremented on every update, for ETag / If-Match
 }
 
 // Sleep type constants
@@ -34,3 +37,11 @@ func (s *SleepEntry) IsNap() bool {
 func (s *SleepEntry) IsNightSleep() bool {
 	return s.Type == SleepTypeNight
 }
+
+// SyncDate derives Date from StartTime in the household time zone (no-op when StartTime is unset).
+// A night sleep belongs to the day it started on.
+func (s *SleepEntry) SyncDate() {
+	if d := s.StartTime.DateString(); d != "" {
+		s.Date = d
+	}
+}
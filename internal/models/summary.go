@@ -0,0 +1,132 @@
+package models
+
+import "time"
+
+// DailySummary aggregates one calendar day of activity in the household time zone.
+type DailySummary struct {
+	Date          string  `json:"date"`           // YYYY-MM-DD
+	Feeds         int     `json:"feeds"`          // Number of feeds that started this day
+	FeedQuantity  float64 `json:"feed_quantity"`  // Total volume of bottle feeds, in QuantityUnit
+	QuantityUnit  string  `json:"quantity_unit"`  // ml unless converted with InUnits
+	BreastMinutes int     `json:"breast_minutes"` // Total breastfeeding duration
+	SleepMinutes  int     `json:"sleep_minutes"`  // Sleep falling inside this day (overnight sleep is split)
+	Naps          int     `json:"naps"`           // Naps that started this day
+	Diapers       int     `json:"diapers"`        // Diaper changes this day
+	WetDiapers    int     `json:"wet_diapers"`    // Wet or mixed
+	DirtyDiapers  int     `json:"dirty_diapers"`  // Dirty or mixed
+
+	ByCaregiver map[string]*CaregiverTotals `json:"by_caregiver"` // Keyed by LoggedBy (UnattributedCaregiver when empty)
+}
+
+// CaregiverTotals counts the entries one caregiver logged on the summary day.
+type CaregiverTotals struct {
+	Feeds   int `json:"feeds"`
+	Sleeps  int `json:"sleeps"` // Sessions that started this day
+	Diapers int `json:"diapers"`
+}
+
+// caregiver returns the breakdown bucket for a LoggedBy value, creating it on first use.
+func (s *DailySummary) caregiver(loggedBy string) *CaregiverTotals {
+	if loggedBy == "" {
+		loggedBy = UnattributedCaregiver
+	}
+	t, ok := s.ByCaregiver[loggedBy]
+	if !ok {
+		t = &CaregiverTotals{}
+		s.ByCaregiver[loggedBy] = t
+	}
+	return t
+}
+
+// SummarizeDay builds the summary for the given YYYY-MM-DD date.
+// Entries are assigned to the day by their timestamp, using day boundaries in the
+// household time zone; entries without a timestamp fall back to their Date field.
+func SummarizeDay(date string, feeds []FeedEntry, sleeps []SleepEntry, diapers []DiaperEntry) (DailySummary, error) {
+	start, end, err := DayBounds(date)
+	if err != nil {
+		return DailySummary{}, err
+	}
+	s := DailySummary{Date: date, QuantityUnit: UnitML, ByCaregiver: map[string]*CaregiverTotals{}}
+
+	for _, f := range feeds {
+		if !onDay(f.Time, f.Date, date, start, end) {
+			continue
+		}
+		s.Feeds++
+		s.caregiver(f.LoggedBy).Feeds++
+		if f.IsBottleFeed() {
+			s.FeedQuantity += f.Quantity
+		}
+		if f.IsBreastFeed() {
+			s.BreastMinutes += f.Duration
+		}
+	}
+
+	for _, e := range sleeps {
+		if onDay(e.StartTime, e.Date, date, start, end) {
+			s.caregiver(e.LoggedBy).Sleeps++
+			if e.IsNap() {
+				s.Naps++
+			}
+		}
+		s.SleepMinutes += sleepMinutesWithin(e, date, start, end)
+	}
+
+	for _, d := range diapers {
+		if !onDay(d.Time, d.Date, date, start, end) {
+			continue
+		}
+		s.Diapers++
+		s.caregiver(d.LoggedBy).Diapers++
+		if d.IsWet() {
+			s.WetDiapers++
+		}
+		if d.IsDirty() {
+			s.DirtyDiapers++
+		}
+	}
+
+	return s, nil
+}
+
+// InUnits returns a copy with FeedQuantity expressed in u.Volume.
+func (s DailySummary) InUnits(u Units) DailySummary {
+	s.FeedQuantity = FromML(s.FeedQuantity, u.Volume)
+	s.QuantityUnit = u.Volume
+	return s
+}
+
+// onDay reports whether a timestamped entry falls in [start, end),
+// falling back to comparing the stored date when the timestamp is unset.
+func onDay(t FlexTime, entryDate, date string, start, end time.Time) bool {
+	if t.IsZero() {
+		return entryDate == date
+	}
+	return !t.Before(start) && t.Before(end)
+}
+
+// sleepMinutesWithin returns how many minutes of a sleep session overlap [start, end).
+// Sessions without an end time are measured from StartTime using Duration.
+func sleepMinutesWithin(e SleepEntry, date string, start, end time.Time) int {
+	if e.StartTime.IsZero() {
+		if e.Date == date {
+			return e.Duration
+		}
+		return 0
+	}
+	from := e.StartTime.Time
+	to := e.EndTime.Time
+	if to.IsZero() {
+		to = from.Add(time.Duration(e.Duration) * time.Minute)
+	}
+	if from.Before(start) {
+		from = start
+	}
+	if to.After(end) {
+		to = end
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return int(to.Sub(from).Minutes())
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeDay_Totals(t *testing.T) {
+	loc := withLocation(t, "America/New_York")
+	at := func(day, hour, min int) FlexTime {
+		return FlexTime{Time: time.Date(2026, 4, day, hour, min, 0, 0, loc)}
+	}
+	feeds := []FeedEntry{
+		{Time: at(6, 0, 30), Type: FeedTypeBottle, Quantity: 120},
+		{Time: at(6, 23, 50), Type: FeedTypeBreastLeft, Duration: 15},
+		{Time: at(7, 0, 10), Type: FeedTypeBottle, Quantity: 90}, // next day
+		{Date: "2026-04-06", Type: FeedTypeSolid, Quantity: 30},  // no timestamp, falls back to Date
+	}
+	sleeps := []SleepEntry{
+		// 21:00 on the 5th to 07:00 on the 6th: 7 hours fall on the 6th
+		{StartTime: at(5, 21, 0), EndTime: at(6, 7, 0), Type: SleepTypeNight},
+		{StartTime: at(6, 13, 0), Duration: 45, Type: SleepTypeNap},
+	}
+	diapers := []DiaperEntry{
+		{Time: at(6, 8, 0), Type: DiaperTypeWet},
+		{Time: at(6, 12, 0), Type: DiaperTypeMixed},
+		{Time: at(7, 1, 0), Type: DiaperTypeDirty},
+	}
+
+	s, err := SummarizeDay("2026-04-06", feeds, sleeps, diapers)
+	if err != nil {
+		t.Fatalf("SummarizeDay failed: %v", err)
+	}
+	if s.Feeds != 3 {
+		t.Errorf("Feeds = %d, want 3", s.Feeds)
+	}
+	if s.FeedQuantity != 120 { // The solid's quantity isn't a volume
+		t.Errorf("FeedQuantity = %v, want 120", s.FeedQuantity)
+	}
+	if s.BreastMinutes != 15 {
+		t.Errorf("BreastMinutes = %d, want 15", s.BreastMinutes)
+	}
+	if s.SleepMinutes != 7*60+45 {
+		t.Errorf("SleepMinutes = %d, want %d", s.SleepMinutes, 7*60+45)
+	}
+	if s.Naps != 1 {
+		t.Errorf("Naps = %d, want 1", s.Naps)
+	}
+	if s.Diapers != 2 || s.WetDiapers != 2 || s.DirtyDiapers != 1 {
+		t.Errorf("diapers = %d/%d wet/%d dirty, want 2/2/1", s.Diapers, s.WetDiapers, s.DirtyDiapers)
+	}
+}
+
+func TestSummarizeDay_DSTNight(t *testing.T) {
+	loc := withLocation(t, "Europe/London")
+	// Clocks go back at 02:00 on 2026-10-25: 20:00 to 08:00 local is 13 elapsed hours
+	sleeps := []SleepEntry{{
+		StartTime: FlexTime{Time: time.Date(2026, 10, 24, 20, 0, 0, 0, loc)},
+		EndTime:   FlexTime{Time: time.Date(2026, 10, 25, 8, 0, 0, 0, loc)},
+		Type:      SleepTypeNight,
+	}}
+	before, err := SummarizeDay("2026-10-24", nil, sleeps, nil)
+	if err != nil {
+		t.Fatalf("SummarizeDay failed: %v", err)
+	}
+	after, err := SummarizeDay("2026-10-25", nil, sleeps, nil)
+	if err != nil {
+		t.Fatalf("SummarizeDay failed: %v", err)
+	}
+	if before.SleepMinutes != 4*60 {
+		t.Errorf("24th SleepMinutes = %d, want %d", before.SleepMinutes, 4*60)
+	}
+	if after.SleepMinutes != 9*60 {
+		t.Errorf("25th SleepMinutes = %d, want %d", after.SleepMinutes, 9*60)
+	}
+}
+
+func TestSummarizeDay_InvalidDate(t *testing.T) {
+	if _, err := SummarizeDay("06/04/2026", nil, nil, nil); err == nil {
+		t.Error("expected error for invalid date")
+	}
+}
+
+func TestSummarizeDay_ByCaregiver(t *testing.T) {
+	feeds := []FeedEntry{
+		{Date: "2026-04-06", Type: FeedTypeBottle, LoggedBy: "Asha"},
+		{Date: "2026-04-06", Type: FeedTypeBottle, LoggedBy: "Ravi"},
+		{Date: "2026-04-06", Type: FeedTypeBottle},
+	}
+	sleeps := []SleepEntry{{Date: "2026-04-06", Type: SleepTypeNap, Duration: 30, LoggedBy: "Ravi"}}
+	diapers := []DiaperEntry{{Date: "2026-04-06", Type: DiaperTypeWet, LoggedBy: "Asha"}}
+
+	s, err := SummarizeDay("2026-04-06", feeds, sleeps, diapers)
+	if err != nil {
+		t.Fatalf("SummarizeDay failed: %v", err)
+	}
+	if got := s.ByCaregiver["Asha"]; got == nil || got.Feeds != 1 || got.Diapers != 1 {
+		t.Errorf("Asha totals = %+v, want 1 feed and 1 diaper", got)
+	}
+	if got := s.ByCaregiver["Ravi"]; got == nil || got.Feeds != 1 || got.Sleeps != 1 {
+		t.Errorf("Ravi totals = %+v, want 1 feed and 1 sleep", got)
+	}
+	if got := s.ByCaregiver[UnattributedCaregiver]; got == nil || got.Feeds != 1 {
+		t.Errorf("unattributed totals = %+v, want 1 feed", got)
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// location is the household time zone. Zone-less timestamps are interpreted in it,
+// entry dates are derived in it, and day boundaries for summaries are computed in it.
+var location atomic.Pointer[time.Location]
+
+// SetLocation sets the household time zone. A nil location resets it to time.Local.
+// Call this early in main() before any entries are parsed.
+func SetLocation(loc *time.Location) {
+	location.Store(loc)
+}
+
+// Location returns the household time zone (time.Local if none was configured).
+func Location() *time.Location {
+	if loc := location.Load(); loc != nil {
+		return loc
+	}
+	return time.Local
+}
+
+// Now returns the current time in the household time zone.
+func Now() time.Time {
+	return time.Now().In(Location())
+}
+
+// DayBounds returns the half-open interval [start, end) covering the given
+// YYYY-MM-DD date in the household time zone. Days that contain a DST
+// transition are 23 or 25 hours long.
+func DayBounds(date string) (start, end time.Time, err error) {
+	loc := Location()
+	d, err := time.ParseInLocation(time.DateOnly, date, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q (expected YYYY-MM-DD): %w", date, err)
+	}
+	start = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+	end = time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, loc)
+	return start, end, nil
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// withLocation sets the household zone for the duration of a test.
+func withLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	SetLocation(loc)
+	t.Cleanup(func() { SetLocation(nil) })
+	return loc
+}
+
+func TestFlexTimeZonelessUsesHouseholdZone(t *testing.T) {
+	withLocation(t, "America/New_York")
+	var ft FlexTime
+	if err := json.Unmarshal([]byte(`"2026-04-06T23:30:00"`), &ft); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Date(2026, 4, 7, 3, 30, 0, 0, time.UTC)
+	if !ft.Time.Equal(want) {
+		t.Errorf("expected %v, got %v", want, ft.Time.UTC())
+	}
+	if got := ft.DateString(); got != "2026-04-06" {
+		t.Errorf("DateString = %q, want 2026-04-06", got)
+	}
+}
+
+func TestFlexTimeRFC3339KeepsOffset(t *testing.T) {
+	withLocation(t, "Asia/Kolkata")
+	var ft FlexTime
+	if err := json.Unmarshal([]byte(`"2026-04-06T20:00:00Z"`), &ft); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 20:00 UTC is already the next morning in India
+	if got := ft.DateString(); got != "2026-04-07" {
+		t.Errorf("DateString = %q, want 2026-04-07", got)
+	}
+}
+
+func TestFlexTimeDateStringZero(t *testing.T) {
+	if got := (FlexTime{}).DateString(); got != "" {
+		t.Errorf("expected empty date for zero time, got %q", got)
+	}
+}
+
+func TestDayBoundsDST(t *testing.T) {
+	withLocation(t, "Europe/London")
+	tests := []struct {
+		date  string
+		hours float64
+	}{
+		{"2026-03-29", 23}, // clocks go forward
+		{"2026-10-25", 25}, // clocks go back
+		{"2026-06-01", 24},
+	}
+	for _, tt := range tests {
+		start, end, err := DayBounds(tt.date)
+		if err != nil {
+			t.Fatalf("DayBounds(%s): %v", tt.date, err)
+		}
+		if got := end.Sub(start).Hours(); got != tt.hours {
+			t.Errorf("DayBounds(%s) spans %v hours, want %v", tt.date, got, tt.hours)
+		}
+		if start.Hour() != 0 || end.Hour() != 0 {
+			t.Errorf("DayBounds(%s) not at local midnight: %v - %v", tt.date, start, end)
+		}
+	}
+}
+
+func TestDayBoundsInvalid(t *testing.T) {
+	if _, _, err := DayBounds("banana"); err == nil {
+		t.Error("expected error for invalid date")
+	}
+}
+
+func TestFlexTimeZonelessInDSTGap(t *testing.T) {
+	withLocation(t, "America/New_York")
+	// 02:30 does not exist on 2026-03-08; it must still parse onto the same calendar day
+	var ft FlexTime
+	if err := json.Unmarshal([]byte(`"2026-03-08T02:30"`), &ft); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ft.DateString(); got != "2026-03-08" {
+		t.Errorf("DateString = %q, want 2026-03-08", got)
+	}
+}
+
+func TestSyncDate(t *testing.T) {
+	withLocation(t, "America/Los_Angeles")
+	// 06:30 UTC on the 7th is still the evening of the 6th in Los Angeles
+	ts := FlexTime{Time: time.Date(2026, 4, 7, 6, 30, 0, 0, time.UTC)}
+
+	f := FeedEntry{Date: "2026-04-07", Time: ts}
+	f.SyncDate()
+	if f.Date != "2026-04-06" {
+		t.Errorf("feed Date = %q, want 2026-04-06", f.Date)
+	}
+	s := SleepEntry{Date: "2026-04-07", StartTime: ts}
+	s.SyncDate()
+	if s.Date != "2026-04-06" {
+		t.Errorf("sleep Date = %q, want 2026-04-06", s.Date)
+	}
+	d := DiaperEntry{Date: "2026-04-05"}
+	d.SyncDate()
+	if d.Date != "2026-04-05" {
+		t.Errorf("expected SyncDate to keep Date when Time is unset, got %q", d.Date)
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TrashItem is a deleted entry kept in its resource's trash until restored or
+// purged after the retention period.
+type TrashItem struct {
+	Resource  string          `json:"resource"` // feeds, sleep, growth or diapers
+	ID        int             `json:"id"`       // The entry's ID, reserved while it is in the trash
+	DeletedAt time.Time       `json:"deleted_at"`
+	DeletedBy string          `json:"deleted_by,omitempty"`
+	Entry     json.RawMessage `json:"entry"` // The entry as it was when deleted
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"crypto/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UIDLength is the length of a UID in characters.
+const UIDLength = 26
+
+// crockford is the Crockford base32 alphabet ULIDs are written in.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+	uidMu   sync.Mutex
+	lastUID [16]byte // Most recent UID, for monotonic ordering within a millisecond
+)
+
+// NewUID returns a new ULID: a 48-bit millisecond timestamp followed by 80
+// random bits, in Crockford base32. UIDs sort by creation time, and identify
+// an entry across devices, children and exported bundles where integer IDs clash.
+func NewUID() string {
+	return NewUIDAt(time.Now())
+}
+
+// NewUIDAt returns a UID stamped with t, so entries given one after the fact
+// still sort by when they happened. Within one millisecond UIDs keep increasing.
+func NewUIDAt(t time.Time) string {
+	var u [16]byte
+	ms := uint64(max(t.UnixMilli(), 0))
+	for i := 5; i >= 0; i-- {
+		u[i] = byte(ms)
+		ms >>= 8
+	}
+	uidMu.Lock()
+	defer uidMu.Unlock()
+	if [6]byte(u[:6]) == [6]byte(lastUID[:6]) {
+		// Same millisecond: increment the random part of the previous UID
+		u = lastUID
+		for i := 15; i >= 6; i-- {
+			u[i]++
+			if u[i] != 0 {
+				break
+			}
+		}
+	} else if _, err := rand.Read(u[6:]); err != nil {
+		panic("models: crypto/rand failed: " + err.Error())
+	}
+	lastUID = u
+	return encodeUID(u)
+}
+
+// encodeUID writes 128 bits as 26 base32 characters, most significant first.
+func encodeUID(u [16]byte) string {
+	var out [UIDLength]byte
+	// 130 bits of output: the first character carries only the top 3 bits
+	bit := -2
+	for i := range out {
+		var v byte
+		for j := 0; j < 5; j++ {
+			v <<= 1
+			if b := bit + j; b >= 0 && u[b/8]&(0x80>>(b%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockford[v]
+		bit += 5
+	}
+	return string(out[:])
+}
+
+// NormalizeUID upper-cases a UID and checks it is one. Crockford base32 is
+// case-insensitive, so "01hv…" and "01HV…" name the same entry.
+func NormalizeUID(s string) (string, bool) {
+	if len(s) != UIDLength {
+		return "", false
+	}
+	s = strings.ToUpper(s)
+	if s[0] > '7' { // More than 128 bits
+		return "", false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(crockford, s[i]) < 0 {
+			return "", false
+		}
+	}
+	return s, true
+}
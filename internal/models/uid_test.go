@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewUID(t *testing.T) {
+	a := NewUID()
+	b := NewUID()
+	if len(a) != UIDLength || a == b || a >= b {
+		t.Errorf("expected two increasing %d-character UIDs, got %q and %q", UIDLength, a, b)
+	}
+	if got, ok := NormalizeUID(a); !ok || got != a {
+		t.Errorf("NormalizeUID(%q) = %q, %v", a, got, ok)
+	}
+
+	// The timestamp prefix orders UIDs by time; the Unix epoch is all zeros
+	if got := NewUIDAt(time.UnixMilli(0)); got[:10] != "0000000000" {
+		t.Errorf("NewUIDAt(epoch) = %q, want a 0000000000 prefix", got)
+	}
+	if early, late := NewUIDAt(time.Date(2026, 4, 6, 9, 0, 0, 0, time.UTC)), NewUIDAt(time.Date(2026, 4, 6, 9, 0, 1, 0, time.UTC)); early >= late {
+		t.Errorf("expected %q < %q", early, late)
+	}
+	// Known encoding from the ULID spec: 1469918176385 ms is 01ARYZ6S41
+	if got := NewUIDAt(time.UnixMilli(1469918176385)); got[:10] != "01ARYZ6S41" {
+		t.Errorf("NewUIDAt timestamp = %q, want 01ARYZ6S41", got[:10])
+	}
+}
+
+func TestNormalizeUID(t *testing.T) {
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"01arz3ndektsv4rrffq69g5fav", "01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+		{"81ARZ3NDEKTSV4RRFFQ69G5FAV", "", false}, // overflows 128 bits
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAU", "", false}, // U is not in the alphabet
+		{"01ARZ3NDEK", "", false},
+		{"42", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := NormalizeUID(tt.in); got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeUID(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
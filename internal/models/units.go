@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// Measurement units. Entries are stored canonically in ml, kg and cm;
+// the imperial units are accepted on input and offered on output.
+const (
+	UnitML = "ml"
+	UnitOz = "oz"
+	UnitKg = "kg"
+	UnitLb = "lb"
+	UnitCm = "cm"
+	UnitIn = "in"
+)
+
+// Unit systems accepted by ParseUnitSystem (and the API's ?units= parameter).
+const (
+	UnitSystemMetric   = "metric"
+	UnitSystemImperial = "imperial"
+)
+
+// Conversion factors to the canonical unit.
+const (
+	mlPerOz = 29.5735295625 // US fluid ounce
+	kgPerLb = 0.45359237
+	cmPerIn = 2.54
+)
+
+// Units selects the unit used for each kind of measurement.
+type Units struct {
+	Volume string // ml or oz
+	Weight string // kg or lb
+	Length string // cm or in
+}
+
+var (
+	// MetricUnits are the canonical storage units.
+	MetricUnits = Units{Volume: UnitML, Weight: UnitKg, Length: UnitCm}
+	// ImperialUnits are the US customary equivalents.
+	ImperialUnits = Units{Volume: UnitOz, Weight: UnitLb, Length: UnitIn}
+)
+
+// ParseUnitSystem maps "metric" or "imperial" to a Units set. Empty means metric.
+func ParseUnitSystem(s string) (Units, error) {
+	switch s {
+	case "", UnitSystemMetric:
+		return MetricUnits, nil
+	case UnitSystemImperial:
+		return ImperialUnits, nil
+	}
+	return Units{}, fmt.Errorf("unknown unit system %q (expected metric or imperial)", s)
+}
+
+// Validate checks that every unit is one this package can convert.
+func (u Units) Validate() error {
+	if u.Volume != UnitML && u.Volume != UnitOz {
+		return fmt.Errorf("unknown volume unit %q (expected ml or oz)", u.Volume)
+	}
+	if u.Weight != UnitKg && u.Weight != UnitLb {
+		return fmt.Errorf("unknown weight unit %q (expected kg or lb)", u.Weight)
+	}
+	if u.Length != UnitCm && u.Length != UnitIn {
+		return fmt.Errorf("unknown length unit %q (expected cm or in)", u.Length)
+	}
+	return nil
+}
+
+// ToML converts a volume in the given unit to millilitres. Empty unit means ml.
+func ToML(v float64, unit string) (float64, error) {
+	switch unit {
+	case "", UnitML:
+		return v, nil
+	case UnitOz:
+		return v * mlPerOz, nil
+	}
+	return 0, fmt.Errorf("unknown volume unit %q (expected ml or oz)", unit)
+}
+
+// FromML converts millilitres to the given volume unit, rounded for display.
+func FromML(ml float64, unit string) float64 {
+	if unit == UnitOz {
+		return round2(ml / mlPerOz)
+	}
+	return ml
+}
+
+// ToKg converts a weight in the given unit to kilograms. Empty unit means kg.
+func ToKg(v float64, unit string) (float64, error) {
+	switch unit {
+	case "", UnitKg:
+		return v, nil
+	case UnitLb:
+		return v * kgPerLb, nil
+	}
+	return 0, fmt.Errorf("unknown weight unit %q (expected kg or lb)", unit)
+}
+
+// FromKg converts kilograms to the given weight unit, rounded for display.
+func FromKg(kg float64, unit string) float64 {
+	if unit == UnitLb {
+		return round2(kg / kgPerLb)
+	}
+	return kg
+}
+
+// ToCm converts a length in the given unit to centimetres. Empty unit means cm.
+func ToCm(v float64, unit string) (float64, error) {
+	switch unit {
+	case "", UnitCm:
+		return v, nil
+	case UnitIn:
+		return v * cmPerIn, nil
+	}
+	return 0, fmt.Errorf("unknown length unit %q (expected cm or in)", unit)
+}
+
+// FromCm converts centimetres to the given length unit, rounded for display.
+func FromCm(cm float64, unit string) float64 {
+	if unit == UnitIn {
+		return round2(cm / cmPerIn)
+	}
+	return cm
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseUnitSystem(t *testing.T) {
+	if u, err := ParseUnitSystem(""); err != nil || u != MetricUnits {
+		t.Errorf("ParseUnitSystem(\"\") = %+v, %v; want metric", u, err)
+	}
+	if u, err := ParseUnitSystem("imperial"); err != nil || u != ImperialUnits {
+		t.Errorf("ParseUnitSystem(imperial) = %+v, %v; want imperial", u, err)
+	}
+	if _, err := ParseUnitSystem("nautical"); err == nil {
+		t.Error("expected error for unknown unit system")
+	}
+}
+
+func TestUnitConversions(t *testing.T) {
+	ml, err := ToML(4, UnitOz)
+	if err != nil || math.Abs(ml-118.29) > 0.01 {
+		t.Errorf("ToML(4oz) = %v, %v; want ~118.29", ml, err)
+	}
+	if got := FromML(ml, UnitOz); got != 4 {
+		t.Errorf("FromML round trip = %v, want 4", got)
+	}
+	kg, _ := ToKg(10, UnitLb)
+	if got := FromKg(kg, UnitLb); got != 10 {
+		t.Errorf("FromKg round trip = %v, want 10", got)
+	}
+	cm, _ := ToCm(20, UnitIn)
+	if cm != 50.8 {
+		t.Errorf("ToCm(20in) = %v, want 50.8", cm)
+	}
+	if _, err := ToML(1, "cup"); err == nil {
+		t.Error("expected error for unknown volume unit")
+	}
+}
+
+func TestFeedEntry_Canonicalize(t *testing.T) {
+	f := FeedEntry{Type: FeedTypeBottle, Quantity: 4, QuantityUnit: UnitOz}
+	if err := f.Canonicalize(); err != nil {
+		t.Fatalf("Canonicalize failed: %v", err)
+	}
+	if f.QuantityUnit != UnitML || f.EnteredUnit != UnitOz {
+		t.Errorf("units = %s/%s, want ml/oz", f.QuantityUnit, f.EnteredUnit)
+	}
+	// Canonicalizing a stored entry again must not convert twice or forget the entered unit
+	ml := f.Quantity
+	if err := f.Canonicalize(); err != nil {
+		t.Fatalf("Canonicalize failed: %v", err)
+	}
+	if f.Quantity != ml || f.EnteredUnit != UnitOz {
+		t.Errorf("second Canonicalize changed entry: %v %s", f.Quantity, f.EnteredUnit)
+	}
+	if got := f.InUnits(ImperialUnits); got.Quantity != 4 || got.QuantityUnit != UnitOz {
+		t.Errorf("InUnits(imperial) = %v %s, want 4 oz", got.Quantity, got.QuantityUnit)
+	}
+
+	legacy := FeedEntry{Type: FeedTypeBottle, Quantity: 120}
+	if err := legacy.Canonicalize(); err != nil || legacy.Quantity != 120 || legacy.EnteredUnit != UnitML {
+		t.Errorf("legacy entry = %v %s (%v), want 120 ml", legacy.Quantity, legacy.EnteredUnit, err)
+	}
+
+	bad := FeedEntry{Type: FeedTypeBottle, Quantity: 1, QuantityUnit: "cup"}
+	if err := bad.Canonicalize(); err == nil {
+		t.Error("expected error for unknown unit")
+	}
+
+	// A solid's quantity isn't a volume: it is kept as entered, in no unit
+	solid := FeedEntry{Type: FeedTypeSolid, Quantity: 3, QuantityUnit: UnitOz}
+	if err := solid.Canonicalize(); err != nil || solid.Quantity != 3 || solid.QuantityUnit != "" || solid.EnteredUnit != "" {
+		t.Errorf("solid feed = %v %q/%q (%v), want 3 with no unit", solid.Quantity, solid.QuantityUnit, solid.EnteredUnit, err)
+	}
+	if got := solid.InUnits(ImperialUnits); got.Quantity != 3 || got.QuantityUnit != "" {
+		t.Errorf("solid InUnits(imperial) = %v %q, want 3 unconverted", got.Quantity, got.QuantityUnit)
+	}
+}
+
+func TestGrowthEntry_Canonicalize(t *testing.T) {
+	g := GrowthEntry{Weight: 11, Height: 22, HeadCircumference: 15, WeightUnit: UnitLb, LengthUnit: UnitIn}
+	if err := g.Canonicalize(); err != nil {
+		t.Fatalf("Canonicalize failed: %v", err)
+	}
+	if math.Abs(g.Weight-4.99) > 0.01 || g.Height != 55.88 {
+		t.Errorf("canonical = %vkg %vcm, want ~4.99kg 55.88cm", g.Weight, g.Height)
+	}
+	if g.EnteredWeightUnit != UnitLb || g.EnteredLengthUnit != UnitIn {
+		t.Errorf("entered units = %s/%s, want lb/in", g.EnteredWeightUnit, g.EnteredLengthUnit)
+	}
+	back := g.InUnits(ImperialUnits)
+	if back.Weight != 11 || back.Height != 22 || back.HeadCircumference != 15 {
+		t.Errorf("InUnits(imperial) = %v/%v/%v, want 11/22/15", back.Weight, back.Height, back.HeadCircumference)
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Account roles, from least to most privileged.
+const (
+	RoleViewer    = "viewer"    // Read-only access to entries and summaries
+	RoleCaregiver = "caregiver" // Can log, edit and delete entries
+	RoleOwner     = "owner"     // Full access, including managing users and tokens
+)
+
+var roleRank = map[string]int{RoleViewer: 1, RoleCaregiver: 2, RoleOwner: 3}
+
+// ValidateRole checks that role is one of the known account roles.
+func ValidateRole(role string) error {
+	if _, ok := roleRank[role]; !ok {
+		return fmt.Errorf("unknown role %q (expected owner, caregiver or viewer)", role)
+	}
+	return nil
+}
+
+// User is an API account. Its Name doubles as the caregiver name stamped
+// on entries the user logs.
+type User struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"` // Unique (case-insensitive)
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HasRole reports whether the user's role is at least min.
+func (u User) HasRole(min string) bool {
+	return roleRank[u.Role] >= roleRank[min]
+}
+
+// APIToken is a bearer token belonging to a user. Only the SHA-256 hash of the
+// token is stored; the plaintext is shown once when the token is created.
+type APIToken struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Label     string    `json:"label,omitempty"` // e.g. "Dad's phone"
+	Hash      string    `json:"hash,omitempty"`  // Hex SHA-256 of the token; cleared in API responses
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Redacted returns a copy without the hash, for API responses and the audit log.
+func (t APIToken) Redacted() APIToken {
+	t.Hash = ""
+	return t
+}
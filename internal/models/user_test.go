@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestUserHasRole(t *testing.T) {
+	tests := []struct {
+		role, min string
+		want      bool
+	}{
+		{RoleOwner, RoleOwner, true},
+		{RoleOwner, RoleViewer, true},
+		{RoleCaregiver, RoleCaregiver, true},
+		{RoleCaregiver, RoleOwner, false},
+		{RoleViewer, RoleViewer, true},
+		{RoleViewer, RoleCaregiver, false},
+		{"admin", RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := (User{Role: tt.role}).HasRole(tt.min); got != tt.want {
+			t.Errorf("User{Role: %q}.HasRole(%q) = %v, want %v", tt.role, tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRole(t *testing.T) {
+	for _, role := range []string{RoleOwner, RoleCaregiver, RoleViewer} {
+		if err := ValidateRole(role); err != nil {
+			t.Errorf("ValidateRole(%q) = %v, want nil", role, err)
+		}
+	}
+	if err := ValidateRole("admin"); err == nil {
+		t.Error("expected error for unknown role")
+	}
+}
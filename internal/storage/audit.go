@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+// auditFileName is the append-only change log. One JSON entry per line; in an
+// encrypted directory each line is sealed on its own and base64-encoded.
+const auditFileName = "audit.jsonl"
+
+// Actor identifies who is making a change, for the audit log.
+type Actor struct {
+	Name    string // Caregiver or account name ("" when anonymous)
+	Address string // Client IP for API requests, "desktop" for the desktop app
+}
+
+type actorKey struct{}
+
+// WithActor returns a context that attributes storage changes to a.
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// ActorFrom returns the actor stored by WithActor (zero if none).
+func ActorFrom(ctx context.Context) Actor {
+	a, _ := ctx.Value(actorKey{}).(Actor)
+	return a
+}
+
+// record appends an audit entry for a change that has just been saved.
+// before and after are nil for creates and deletes respectively.
+// The caller holds sm.mu.
+func (sm *StorageManager) record(ctx context.Context, action, resource string, id int, before, after any) error {
+	actor := ActorFrom(ctx)
+	e := models.AuditEntry{
+		Time:     time.Now().UTC(),
+		Actor:    actor.Name,
+		Address:  actor.Address,
+		Action:   action,
+		Resource: resource,
+		EntityID: id,
+	}
+	var err error
+	if before != nil {
+		if e.Before, err = json.Marshal(before); err != nil {
+			return fmt.Errorf("failed to marshal audit entry: %w", err)
+		}
+	}
+	if after != nil {
+		if e.After, err = json.Marshal(after); err != nil {
+			return fmt.Errorf("failed to marshal audit entry: %w", err)
+		}
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	if err := sm.appendAudit(line); err != nil {
+		return fmt.Errorf("change saved but not audited: %w", err)
+	}
+	return nil
+}
+
+// appendAudit writes one line to the audit log and syncs it.
+func (sm *StorageManager) appendAudit(line []byte) error {
+	if sm.closed {
+		return ErrClosed
+	}
+	if sm.encrypted {
+		if sm.aead == nil {
+			return ErrLocked
+		}
+		sealed, err := seal(sm.aead, auditFileName, line)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", auditFileName, err)
+		}
+		line = []byte(base64.StdEncoding.EncodeToString(sealed))
+	}
+	f, err := os.OpenFile(filepath.Join(sm.dataDir, auditFileName), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", auditFileName, err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", auditFileName, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync %s: %w", auditFileName, err)
+	}
+	return f.Close()
+}
+
+// readAuditLine decodes one line of the audit log, decrypting it when sealed.
+func (sm *StorageManager) readAuditLine(line []byte) (models.AuditEntry, error) {
+	var e models.AuditEntry
+	if !bytes.HasPrefix(line, []byte("{")) {
+		sealed, err := base64.StdEncoding.DecodeString(string(line))
+		if err != nil || !isEncrypted(sealed) {
+			return e, fmt.Errorf("unrecognised line in %s", auditFileName)
+		}
+		if sm.aead == nil {
+			return e, ErrLocked
+		}
+		if line, err = unseal(sm.aead, auditFileName, sealed); err != nil {
+			return e, err
+		}
+	}
+	if err := json.Unmarshal(line, &e); err != nil {
+		return e, fmt.Errorf("failed to parse %s: %w", auditFileName, err)
+	}
+	return e, nil
+}
+
+// LoadAudit returns the audit entries matching f, oldest first.
+func LoadAudit(f models.AuditFilter) ([]models.AuditEntry, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock() // don't read a line that is still being appended
+	defer sm.mu.Unlock()
+	return sm.loadAudit(f)
+}
+
+// loadAudit is LoadAudit for a caller that holds sm.mu.
+func (sm *StorageManager) loadAudit(f models.AuditFilter) ([]models.AuditEntry, error) {
+	file, err := os.Open(filepath.Join(sm.dataDir, auditFileName))
+	if os.IsNotExist(err) {
+		return []models.AuditEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", auditFileName, err)
+	}
+	defer file.Close()
+
+	entries := []models.AuditEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 4<<20) // before/after of a large entry can exceed the default 64KB
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		e, err := sm.readAuditLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if f.Matches(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", auditFileName, err)
+	}
+	return entries, nil
+}
+
+// auditStart returns the time of the audit log's first record, or now if the
+// log is empty. The caller holds sm.mu.
+func (sm *StorageManager) auditStart() (time.Time, error) {
+	file, err := os.Open(filepath.Join(sm.dataDir, auditFileName))
+	if os.IsNotExist(err) {
+		return time.Now(), nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read %s: %w", auditFileName, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 4<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		e, err := sm.readAuditLine(line)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return e.Time, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, fmt.Errorf("failed to read %s: %w", auditFileName, err)
+	}
+	return time.Now(), nil
+}
+
+// encryptAuditLog seals any plaintext lines of the audit log in place.
+// Used by EncryptDataDir; reports whether the file was rewritten.
+func (sm *StorageManager) encryptAuditLog() (bool, error) {
+	path := filepath.Join(sm.dataDir, auditFileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", auditFileName, err)
+	}
+	var out bytes.Buffer
+	changed := false
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if bytes.HasPrefix(line, []byte("{")) {
+			sealed, err := seal(sm.aead, auditFileName, line)
+			if err != nil {
+				return false, fmt.Errorf("failed to encrypt %s: %w", auditFileName, err)
+			}
+			line = []byte(base64.StdEncoding.EncodeToString(sealed))
+			changed = true
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, writeFileAtomic(path, out.Bytes())
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"babytracker/internal/models"
+)
+
+func TestAuditTrail(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+
+	asha := WithActor(context.Background(), Actor{Name: "Asha", Address: "192.168.1.20"})
+	ravi := WithActor(context.Background(), Actor{Name: "Ravi", Address: "desktop"})
+
+	feed := &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Quantity: 120}
+	if err := SaveFeed(asha, feed); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	if err := UpdateFeed(ravi, feed.ID, 0, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Quantity: 150}); err != nil {
+		t.Fatalf("UpdateFeed failed: %v", err)
+	}
+	if err := DeleteFeed(ravi, feed.ID, 0); err != nil {
+		t.Fatalf("DeleteFeed failed: %v", err)
+	}
+	if err := SaveDiaper(asha, &models.DiaperEntry{Date: "2026-04-06", Type: models.DiaperTypeWet}); err != nil {
+		t.Fatalf("SaveDiaper failed: %v", err)
+	}
+
+	trail, err := LoadAudit(models.AuditFilter{Resource: models.ResourceFeeds, EntityID: feed.ID})
+	if err != nil {
+		t.Fatalf("LoadAudit failed: %v", err)
+	}
+	if len(trail) != 3 {
+		t.Fatalf("got %d feed audit entries, want 3", len(trail))
+	}
+	create, update, del := trail[0], trail[1], trail[2]
+	if create.Action != models.AuditCreate || create.Actor != "Asha" || create.Address != "192.168.1.20" || create.Before != nil {
+		t.Errorf("unexpected create entry: %+v", create)
+	}
+	changes := update.Changes()
+	if update.Action != models.AuditUpdate || len(changes) != 1 || changes[0].Field != "quantity" ||
+		string(changes[0].Before) != "120" || string(changes[0].After) != "150" {
+		t.Errorf("unexpected update entry: %+v, changes %+v", update, changes)
+	}
+	if del.Action != models.AuditDelete || del.Actor != "Ravi" || del.After != nil || !strings.Contains(string(del.Before), "150") {
+		t.Errorf("unexpected delete entry: %+v", del)
+	}
+
+	byAsha, err := LoadAudit(models.AuditFilter{Actor: "asha"})
+	if err != nil {
+		t.Fatalf("LoadAudit failed: %v", err)
+	}
+	if len(byAsha) != 2 {
+		t.Errorf("got %d entries by Asha, want 2 (feed and diaper)", len(byAsha))
+	}
+}
+
+func TestAuditRedactsTokenHash(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+
+	u := &models.User{Name: "Asha", Role: models.RoleCaregiver}
+	if err := SaveUser(context.Background(), u); err != nil {
+		t.Fatalf("SaveUser failed: %v", err)
+	}
+	if err := SaveToken(context.Background(), &models.APIToken{UserID: u.ID, Hash: "secret-hash"}); err != nil {
+		t.Fatalf("SaveToken failed: %v", err)
+	}
+	if err := DeleteUser(context.Background(), u.ID); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	trail, err := LoadAudit(models.AuditFilter{Resource: models.ResourceTokens})
+	if err != nil {
+		t.Fatalf("LoadAudit failed: %v", err)
+	}
+	if len(trail) != 2 {
+		t.Fatalf("got %d token audit entries, want create + revoke", len(trail))
+	}
+	for _, e := range trail {
+		if strings.Contains(string(e.Before)+string(e.After), "secret-hash") {
+			t.Errorf("token hash leaked into audit log: %+v", e)
+		}
+	}
+}
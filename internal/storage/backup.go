@@ -0,0 +1,505 @@
+package storage
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+// Backups are gzipped tarballs in backups/, named <label>-<UTC time>.tar.gz.
+// The first member is manifest.json (a models.Backup without Size) listing
+// every data file with its SHA-256; the data files follow, taken as stored, so
+// an encrypted directory's backups stay encrypted.
+
+const (
+	backupSuffix       = ".tar.gz"
+	backupManifestName = "manifest.json"
+	backupTimeFormat   = "20060102T150405.000Z"
+)
+
+var (
+	// ErrBackupNotFound is returned for a backup name that isn't in the backups directory.
+	ErrBackupNotFound = errors.New("backup not found")
+	// ErrBadBackup is returned when an archive is damaged or fails its checksums.
+	ErrBadBackup = errors.New("backup archive is damaged")
+	// ErrBackupKeyMismatch is returned when restoring a backup whose files
+	// weren't encrypted with this data directory's key, or at all.
+	ErrBackupKeyMismatch = errors.New("backup does not match the data directory's encryption")
+)
+
+// backupMember reports whether a file in the data directory goes into a backup.
+func backupMember(name string) bool {
+	return name != batchJournalFileName && !strings.HasSuffix(name, ".tmp")
+}
+
+// backupMemberName reports whether an archive member's name is one a backup
+// can hold: a plain file name, or one inside an entry resource's partition directory.
+func backupMemberName(name string) bool {
+	dir, base, nested := strings.Cut(name, "/")
+	if !nested {
+		dir, base = "", name
+	}
+	if nested && !isEntryResource(dir) {
+		return false
+	}
+	return base != "" && base != "." && base != ".." && !strings.ContainsAny(base, "/\\")
+}
+
+// dataFileSums lists the data directory's files with their checksums, sorted by name.
+func (sm *StorageManager) dataFileSums() ([]models.BackupFile, map[string][]byte, error) {
+	names, err := listDataFiles(sm.dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var files []models.BackupFile
+	contents := map[string][]byte{}
+	for _, name := range names {
+		if !backupMember(name) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(sm.dataDir, name))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		sum := sha256.Sum256(data)
+		files = append(files, models.BackupFile{Name: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+		contents[name] = data
+	}
+	return files, contents, nil
+}
+
+// writeBackup archives the data files into backups/ and returns the backup, or
+// nil when there is no household data to back up yet. The caller holds sm.mu.
+func (sm *StorageManager) writeBackup(label string) (*models.Backup, error) {
+	files, contents, err := sm.dataFileSums()
+	if err != nil {
+		return nil, err
+	}
+	empty := true
+	for _, f := range files {
+		if isDataFile(f.Name) {
+			empty = false
+		}
+	}
+	if empty {
+		return nil, nil
+	}
+	schema, err := sm.readSchema()
+	if err != nil {
+		return nil, err
+	}
+	created := time.Now().UTC()
+	b := &models.Backup{
+		Name:      label + "-" + created.Format(backupTimeFormat) + backupSuffix,
+		Label:     label,
+		Created:   created,
+		Schema:    schema,
+		Encrypted: sm.encrypted,
+		Files:     files,
+	}
+	manifest, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal backup manifest: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: created}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := add(backupManifestName, manifest); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	for _, f := range files {
+		if err := add(f.Name, contents[f.Name]); err != nil {
+			return nil, fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	dir := filepath.Join(sm.dataDir, backupDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, b.Name), buf.Bytes()); err != nil {
+		return nil, err
+	}
+	b.Size = int64(buf.Len())
+	return b, nil
+}
+
+// readBackup reads an archive in full and checks every file against the
+// manifest. Any problem is ErrBadBackup.
+func readBackup(path string) (*models.Backup, map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	bad := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s: %s", ErrBadBackup, filepath.Base(path), fmt.Sprintf(format, args...))
+	}
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, nil, bad("%v", err)
+	}
+	tr := tar.NewReader(zr)
+
+	var b *models.Backup
+	contents := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, bad("%v", err)
+		}
+		// Only plain names or entry partitions: nothing may land outside the data directory
+		if hdr.Typeflag != tar.TypeReg || !backupMemberName(hdr.Name) {
+			return nil, nil, bad("unexpected member %q", hdr.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, bad("%v", err)
+		}
+		if b == nil {
+			if hdr.Name != backupManifestName {
+				return nil, nil, bad("no manifest")
+			}
+			if err := json.Unmarshal(data, &b); err != nil || b == nil {
+				return nil, nil, bad("unreadable manifest")
+			}
+			continue
+		}
+		if _, dup := contents[hdr.Name]; dup {
+			return nil, nil, bad("%s appears twice", hdr.Name)
+		}
+		contents[hdr.Name] = data
+	}
+	if b == nil {
+		return nil, nil, bad("no manifest")
+	}
+	if len(contents) != len(b.Files) {
+		return nil, nil, bad("holds %d files, manifest lists %d", len(contents), len(b.Files))
+	}
+	for _, file := range b.Files {
+		data, ok := contents[file.Name]
+		if !ok {
+			return nil, nil, bad("%s is missing", file.Name)
+		}
+		sum := sha256.Sum256(data)
+		if int64(len(data)) != file.Size || hex.EncodeToString(sum[:]) != file.SHA256 {
+			return nil, nil, bad("%s fails its checksum", file.Name)
+		}
+	}
+	return b, contents, nil
+}
+
+// readManifest reads just the manifest at the head of an archive, for listing.
+func readManifest(path string) (*models.Backup, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(zr)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Name != backupManifestName {
+		return nil, fmt.Errorf("%w: no manifest", ErrBadBackup)
+	}
+	var b models.Backup
+	if err := json.NewDecoder(tr).Decode(&b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// listBackups returns the backups newest first. Archives whose manifest can't
+// be read are left out. The caller holds sm.mu.
+func (sm *StorageManager) listBackups() ([]models.Backup, error) {
+	entries, err := os.ReadDir(filepath.Join(sm.dataDir, backupDirName))
+	if os.IsNotExist(err) {
+		return []models.Backup{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	backups := []models.Backup{}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasSuffix(e.Name(), backupSuffix) {
+			continue
+		}
+		path := filepath.Join(sm.dataDir, backupDirName, e.Name())
+		b, err := readManifest(path)
+		if err != nil {
+			continue
+		}
+		b.Name = e.Name()
+		if info, err := e.Info(); err == nil {
+			b.Size = info.Size()
+		}
+		backups = append(backups, *b)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Created.After(backups[j].Created) })
+	return backups, nil
+}
+
+// ListBackups returns the backups in the backups directory, newest first.
+func ListBackups() ([]models.Backup, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.listBackups()
+}
+
+// CreateBackup archives the data directory under label and returns the backup,
+// or nil when there is nothing to back up. A scheduled backup is also skipped
+// when no file has changed since the last scheduled one.
+func CreateBackup(label string) (*models.Backup, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if label == models.BackupScheduled {
+		unchanged, err := sm.unchangedSinceScheduled()
+		if err != nil || unchanged {
+			return nil, err
+		}
+	}
+	return sm.writeBackup(label)
+}
+
+// unchangedSinceScheduled reports whether the data files match the newest
+// scheduled backup. The caller holds sm.mu.
+func (sm *StorageManager) unchangedSinceScheduled() (bool, error) {
+	backups, err := sm.listBackups()
+	if err != nil {
+		return false, err
+	}
+	files, _, err := sm.dataFileSums()
+	if err != nil {
+		return false, err
+	}
+	for _, b := range backups {
+		if b.Label != models.BackupScheduled {
+			continue
+		}
+		if len(b.Files) != len(files) {
+			return false, nil
+		}
+		for i := range files {
+			if files[i] != b.Files[i] {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// PruneBackups deletes the scheduled backups that policy no longer keeps and
+// returns their names. Other backups are only ever deleted by hand.
+func PruneBackups(policy models.BackupPolicy) ([]string, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	backups, err := sm.listBackups()
+	if err != nil {
+		return nil, err
+	}
+	var scheduled []models.Backup
+	for _, b := range backups {
+		if b.Label == models.BackupScheduled {
+			scheduled = append(scheduled, b)
+		}
+	}
+	var removed []string
+	for _, b := range expiredBackups(scheduled, policy) {
+		if err := os.Remove(filepath.Join(sm.dataDir, backupDirName, b.Name)); err != nil {
+			return removed, fmt.Errorf("failed to remove backup %s: %w", b.Name, err)
+		}
+		removed = append(removed, b.Name)
+	}
+	return removed, nil
+}
+
+// expiredBackups returns the backups (newest first) that policy doesn't keep.
+// Each rule keeps the newest backup in each of its most recent buckets, UTC.
+func expiredBackups(backups []models.Backup, policy models.BackupPolicy) []models.Backup {
+	if policy == (models.BackupPolicy{}) || len(backups) == 0 {
+		return nil
+	}
+	keep := map[string]bool{backups[0].Name: true}
+	rules := []struct {
+		count  int
+		bucket func(t time.Time) string
+	}{
+		{policy.Hourly, func(t time.Time) string { return t.Format("2006010215") }},
+		{policy.Daily, func(t time.Time) string { return t.Format("20060102") }},
+		{policy.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+	}
+	for _, r := range rules {
+		seen := map[string]bool{}
+		for _, b := range backups {
+			if len(seen) >= r.count {
+				break
+			}
+			if k := r.bucket(b.Created.UTC()); !seen[k] {
+				seen[k] = true
+				keep[b.Name] = true
+			}
+		}
+	}
+	var expired []models.Backup
+	for _, b := range backups {
+		if !keep[b.Name] {
+			expired = append(expired, b)
+		}
+	}
+	return expired
+}
+
+// checkBackupContents makes sure every file in a backup can be read with this
+// data directory's key before any of them replaces the live copy. The caller holds sm.mu.
+func (sm *StorageManager) checkBackupContents(b *models.Backup, contents map[string][]byte) error {
+	if b.Encrypted != sm.encrypted {
+		return ErrBackupKeyMismatch
+	}
+	for name, data := range contents {
+		switch {
+		case name == auditFileName:
+			for _, line := range bytes.Split(data, []byte("\n")) {
+				if line = bytes.TrimSpace(line); len(line) == 0 {
+					continue
+				}
+				if _, err := sm.readAuditLine(line); err != nil {
+					return fmt.Errorf("%w (%s: %v)", ErrBackupKeyMismatch, name, err)
+				}
+			}
+		case strings.HasSuffix(name, ".json"):
+			if isEncrypted(data) {
+				plaintext, err := unseal(sm.aead, name, data)
+				if err != nil {
+					return fmt.Errorf("%w (%v)", ErrBackupKeyMismatch, err)
+				}
+				data = plaintext
+			}
+			if !json.Valid(data) {
+				return fmt.Errorf("%w: %s is not valid JSON", ErrBadBackup, name)
+			}
+		}
+	}
+	return nil
+}
+
+// RestoreBackup replaces the data files with those in the named backup. The
+// archive is verified against its checksums and every file decrypted and
+// parsed first, then the current files are backed up as pre-restore. Files the
+// backup doesn't have are removed; the keyfile is never replaced. An older
+// schema is migrated unless migrations are manual.
+func RestoreBackup(name string) (*models.Backup, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.closed {
+		return nil, ErrClosed
+	}
+	if sm.encrypted && sm.aead == nil {
+		return nil, ErrLocked
+	}
+	if name != filepath.Base(name) || !strings.HasSuffix(name, backupSuffix) {
+		return nil, ErrBackupNotFound
+	}
+	b, contents, err := readBackup(filepath.Join(sm.dataDir, backupDirName, name))
+	if os.IsNotExist(err) {
+		return nil, ErrBackupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.Schema > SchemaVersion() {
+		return nil, fmt.Errorf("%w (backup is schema %d; this build supports up to %d)", ErrSchemaTooNew, b.Schema, SchemaVersion())
+	}
+	if err := sm.checkBackupContents(b, contents); err != nil {
+		return nil, err
+	}
+	if _, err := sm.writeBackup(models.BackupRestore); err != nil {
+		return nil, fmt.Errorf("refusing to restore without a backup of the current data: %w", err)
+	}
+
+	current, _, err := sm.dataFileSums()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range current {
+		if _, kept := contents[f.Name]; kept || f.Name == keyFileName {
+			continue
+		}
+		if err := os.Remove(filepath.Join(sm.dataDir, f.Name)); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", f.Name, err)
+		}
+	}
+	for _, f := range b.Files {
+		if f.Name == keyFileName {
+			continue
+		}
+		if err := writeFileAtomic(filepath.Join(sm.dataDir, f.Name), contents[f.Name]); err != nil {
+			return nil, fmt.Errorf("restore stopped part way (the pre-restore backup has the old files): %w", err)
+		}
+	}
+	if !sm.manualMigrations {
+		migrated, err := sm.migrate()
+		sm.migrated = append(sm.migrated, migrated...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
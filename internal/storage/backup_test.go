@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+// setupBackupStorage returns a fresh global storage directory.
+func setupBackupStorage(t *testing.T) string {
+	t.Helper()
+	origGlobal := globalStorage
+	t.Cleanup(func() { globalStorage = origGlobal })
+	dir := t.TempDir()
+	if err := Init(dir); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	return dir
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	dir := setupBackupStorage(t)
+	ctx := context.Background()
+	if b, err := CreateBackup(models.BackupManual); err != nil || b != nil {
+		t.Fatalf("backup of an empty directory = %+v, %v; want nothing", b, err)
+	}
+	if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle}); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	b, err := CreateBackup(models.BackupManual)
+	if err != nil || b == nil {
+		t.Fatalf("CreateBackup = %+v, %v", b, err)
+	}
+	if b.Schema != SchemaVersion() || len(b.Files) == 0 {
+		t.Errorf("unexpected manifest: %+v", b)
+	}
+
+	// A scheduled backup is skipped only when nothing changed since the last scheduled one
+	if s, err := CreateBackup(models.BackupScheduled); err != nil || s == nil {
+		t.Fatalf("first scheduled backup = %+v, %v", s, err)
+	}
+	if s, err := CreateBackup(models.BackupScheduled); err != nil || s != nil {
+		t.Errorf("unchanged scheduled backup = %+v, %v; want skipped", s, err)
+	}
+
+	// Later changes, including a file the backup doesn't have, are rolled back
+	if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-07", Type: models.FeedTypeBottle}); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	if err := SaveDiaper(ctx, &models.DiaperEntry{Date: "2026-04-07", Type: models.DiaperTypeWet}); err != nil {
+		t.Fatalf("SaveDiaper failed: %v", err)
+	}
+	if _, err := RestoreBackup(b.Name); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	if feeds, _ := LoadFeeds(); len(feeds) != 1 || feeds[0].Date != "2026-04-06" {
+		t.Errorf("feeds after restore: %+v", feeds)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "diapers.json")); !os.IsNotExist(err) {
+		t.Errorf("expected diapers.json to be removed, got %v", err)
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(backups) != 3 || backups[0].Label != models.BackupRestore || backups[0].Size == 0 {
+		t.Errorf("expected the pre-restore backup first of 3, got %+v", backups)
+	}
+
+	if _, err := RestoreBackup("../feeds.json"); !errors.Is(err, ErrBackupNotFound) {
+		t.Errorf("expected ErrBackupNotFound, got %v", err)
+	}
+}
+
+func TestRestoreRefusesDamagedBackup(t *testing.T) {
+	dir := setupBackupStorage(t)
+	ctx := context.Background()
+	if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle}); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	b, err := CreateBackup(models.BackupManual)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	// Same manifest, altered feeds.json
+	manifest, _ := json.Marshal(b)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, f := range append([]models.BackupFile{{Name: backupManifestName}}, b.Files...) {
+		data := manifest
+		if f.Name != backupManifestName {
+			data, _ = os.ReadFile(filepath.Join(dir, f.Name))
+		}
+		if f.Name == "feeds/2026-04.json" {
+			data = []byte("[]")
+		}
+		_ = tw.WriteHeader(&tar.Header{Name: f.Name, Mode: 0600, Size: int64(len(data))})
+		_, _ = tw.Write(data)
+	}
+	tw.Close()
+	zw.Close()
+	if err := os.WriteFile(filepath.Join(dir, backupDirName, b.Name), buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RestoreBackup(b.Name); !errors.Is(err, ErrBadBackup) {
+		t.Fatalf("expected ErrBadBackup, got %v", err)
+	}
+	if feeds, _ := LoadFeeds(); len(feeds) != 1 {
+		t.Errorf("a refused restore changed the data: %+v", feeds)
+	}
+	if backups, _ := ListBackups(); len(backups) != 1 {
+		t.Errorf("a refused restore should not take a pre-restore backup: %+v", backups)
+	}
+}
+
+func TestRestoreEncryptedBackup(t *testing.T) {
+	dir := setupEncryptedStorage(t)
+	ctx := context.Background()
+	if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle}); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	plain, err := CreateBackup(models.BackupManual)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	if _, err := EncryptDataDir("correct horse"); err != nil {
+		t.Fatalf("EncryptDataDir failed: %v", err)
+	}
+	if _, err := RestoreBackup(plain.Name); !errors.Is(err, ErrBackupKeyMismatch) {
+		t.Errorf("restoring a plaintext backup into an encrypted directory: got %v", err)
+	}
+
+	sealed, err := CreateBackup(models.BackupManual)
+	if err != nil || !sealed.Encrypted {
+		t.Fatalf("CreateBackup = %+v, %v", sealed, err)
+	}
+	if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-07", Type: models.FeedTypeBottle}); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	if _, err := RestoreBackup(sealed.Name); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	if feeds, err := LoadFeeds(); err != nil || len(feeds) != 1 {
+		t.Errorf("feeds after restore = %+v, %v", feeds, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, keyFileName)); err != nil {
+		t.Errorf("keyfile missing after restore: %v", err)
+	}
+}
+
+func TestExpiredBackups(t *testing.T) {
+	// Hourly backups over 20 days, newest first
+	now := time.Date(2026, 4, 20, 12, 30, 0, 0, time.UTC)
+	var backups []models.Backup
+	for i := 0; i < 20*24; i++ {
+		created := now.Add(-time.Duration(i) * time.Hour)
+		backups = append(backups, models.Backup{Name: created.Format(backupTimeFormat), Created: created})
+	}
+
+	if expired := expiredBackups(backups, models.BackupPolicy{}); expired != nil {
+		t.Errorf("an empty policy should keep everything, expired %d", len(expired))
+	}
+
+	expired := expiredBackups(backups, models.BackupPolicy{Hourly: 6, Daily: 7, Weekly: 4})
+	kept := map[string]bool{}
+	for _, b := range backups {
+		kept[b.Name] = true
+	}
+	for _, b := range expired {
+		delete(kept, b.Name)
+	}
+	// 6 hourly (today), 6 more daily (the newest of today is already kept),
+	// then the newest of the weeks of Apr 6 and Mar 30 (Apr 13-19 is covered by daily)
+	if len(kept) != 6+6+2 {
+		t.Errorf("kept %d backups, want 14", len(kept))
+	}
+	for _, want := range []time.Time{now, now.Add(-5 * time.Hour), time.Date(2026, 4, 14, 23, 30, 0, 0, time.UTC), time.Date(2026, 4, 12, 23, 30, 0, 0, time.UTC)} {
+		if !kept[want.Format(backupTimeFormat)] {
+			t.Errorf("expected the backup from %s to be kept", want)
+		}
+	}
+}
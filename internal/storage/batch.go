@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"babytracker/internal/models"
+)
+
+// batchJournalFileName holds the files as they were before the running batch
+// wrote them. It only exists while a batch is being applied; finding one when
+// the data directory is opened means a batch was cut short, and it is rolled back.
+const batchJournalFileName = "batch.journal"
+
+// ErrUnknownRef is returned for a batch operation whose Ref names no earlier create.
+var ErrUnknownRef = errors.New("ref does not name an applied create")
+
+// BatchOp is one write in an ApplyBatch call.
+type BatchOp struct {
+	Key       string // Client idempotency key; an op whose key was already applied is skipped
+	Actor     string // Who sent the op; Key and Ref only match keys the same actor sent
+	Action    string // models.AuditCreate, AuditUpdate or AuditDelete
+	Resource  string // feeds, sleep, growth or diapers
+	ID        int    // Entry to update or delete
+	Ref       string // Or: the key of an earlier create, whose entry is updated or deleted
+	IfVersion int    // Optional version precondition for updates and deletes
+	Entry     any    // *models.FeedEntry etc. for creates and updates; ID and Version are filled in
+}
+
+// BatchResult is the outcome of one BatchOp.
+type BatchResult struct {
+	ID       int  // The entry created, updated or deleted
+	Replayed bool // The op's key had already been applied, so nothing was written
+}
+
+// BatchError reports the operation that made a batch fail.
+type BatchError struct {
+	Index int // Position of the failing op
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// batchJournal is the contents of batchJournalFileName.
+type batchJournal struct {
+	Files     map[string][]byte `json:"files"`      // Raw (possibly encrypted) contents; nil if the file did not exist
+	AuditSize int64             `json:"audit_size"` // Length of the audit log
+}
+
+// ApplyBatch applies ops in order as a single unit: if any op fails, every file
+// it touched, the audit log included, is put back as it was and the returned
+// *BatchError names the op. Ops whose Key was applied before (by this or an
+// earlier batch) are skipped and reported as replayed, so a client can resend
+// a queue of offline writes safely.
+func ApplyBatch(ctx context.Context, ops []BatchOp) ([]BatchResult, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.closed {
+		return nil, ErrClosed
+	}
+	if err := sm.beginBatch(); err != nil {
+		return nil, err
+	}
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		res, err := sm.applyBatchOp(ctx, op)
+		if err != nil {
+			if rerr := sm.recoverBatch(); rerr != nil {
+				return nil, fmt.Errorf("%w (rollback failed: %v)", &BatchError{Index: i, Err: err}, rerr)
+			}
+			return nil, &BatchError{Index: i, Err: err}
+		}
+		results[i] = res
+	}
+	if err := sm.rememberRequest(ctx, "", "", 0); err != nil {
+		if rerr := sm.recoverBatch(); rerr != nil {
+			return nil, fmt.Errorf("%w (rollback failed: %v)", err, rerr)
+		}
+		return nil, err
+	}
+	if err := sm.endBatch(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// applyBatchOp applies one op. The caller holds sm.mu.
+func (sm *StorageManager) applyBatchOp(ctx context.Context, op BatchOp) (BatchResult, error) {
+	if op.Key != "" {
+		rec, ok, err := sm.findKey(op.Actor, op.Key)
+		if err != nil {
+			return BatchResult{}, err
+		}
+		if ok {
+			if rec.Action != op.Action || rec.Resource != op.Resource {
+				return BatchResult{}, fmt.Errorf("%w: %q", ErrKeyReused, op.Key)
+			}
+			return BatchResult{ID: rec.ID, Replayed: true}, nil
+		}
+	}
+	id := op.ID
+	if op.Ref != "" {
+		rec, ok, err := sm.findKey(op.Actor, op.Ref)
+		if err != nil {
+			return BatchResult{}, err
+		}
+		if !ok || rec.Action != models.AuditCreate || rec.Resource != op.Resource {
+			return BatchResult{}, fmt.Errorf("%w: %q", ErrUnknownRef, op.Ref)
+		}
+		id = rec.ID
+	}
+	if op.Action != models.AuditDelete && entryResource(op.Entry) != op.Resource {
+		return BatchResult{}, fmt.Errorf("%T is not a %s entry", op.Entry, op.Resource)
+	}
+
+	var err error
+	switch op.Action {
+	case models.AuditCreate:
+		id, err = sm.createEntry(ctx, op.Entry)
+	case models.AuditUpdate:
+		err = sm.updateEntry(ctx, id, op.IfVersion, op.Entry)
+	case models.AuditDelete:
+		err = sm.deleteEntry(ctx, op.Resource, id, op.IfVersion)
+	default:
+		err = fmt.Errorf("unknown action %q", op.Action)
+	}
+	if err != nil {
+		return BatchResult{}, err
+	}
+	if op.Key != "" {
+		if err := sm.rememberKey(models.IdempotencyKey{Key: op.Key, Action: op.Action, Resource: op.Resource, ID: id, Actor: op.Actor}); err != nil {
+			return BatchResult{}, err
+		}
+	}
+	return BatchResult{ID: id}, nil
+}
+
+// entryResource returns the resource an entry pointer belongs to.
+func entryResource(entry any) string {
+	switch entry.(type) {
+	case *models.FeedEntry:
+		return models.ResourceFeeds
+	case *models.SleepEntry:
+		return models.ResourceSleep
+	case *models.GrowthEntry:
+		return models.ResourceGrowth
+	case *models.DiaperEntry:
+		return models.ResourceDiapers
+	}
+	return ""
+}
+
+// createEntry saves a new entry of any resource, returning its ID. The caller holds sm.mu.
+func (sm *StorageManager) createEntry(ctx context.Context, entry any) (int, error) {
+	switch e := entry.(type) {
+	case *models.FeedEntry:
+		err := sm.saveFeed(ctx, e)
+		return e.ID, err
+	case *models.SleepEntry:
+		err := sm.saveSleep(ctx, e)
+		return e.ID, err
+	case *models.GrowthEntry:
+		err := sm.saveGrowth(ctx, e)
+		return e.ID, err
+	case *models.DiaperEntry:
+		err := sm.saveDiaper(ctx, e)
+		return e.ID, err
+	}
+	return 0, fmt.Errorf("cannot create a %T", entry)
+}
+
+// updateEntry replaces an entry of any resource. The caller holds sm.mu.
+func (sm *StorageManager) updateEntry(ctx context.Context, id, ifVersion int, entry any) error {
+	switch e := entry.(type) {
+	case *models.FeedEntry:
+		return sm.updateFeed(ctx, id, ifVersion, e)
+	case *models.SleepEntry:
+		return sm.updateSleep(ctx, id, ifVersion, e)
+	case *models.GrowthEntry:
+		return sm.updateGrowth(ctx, id, ifVersion, e)
+	case *models.DiaperEntry:
+		return sm.updateDiaper(ctx, id, ifVersion, e)
+	}
+	return fmt.Errorf("cannot update a %T", entry)
+}
+
+// deleteEntry moves an entry of any resource to the trash. The caller holds sm.mu.
+func (sm *StorageManager) deleteEntry(ctx context.Context, resource string, id, ifVersion int) error {
+	switch resource {
+	case models.ResourceFeeds:
+		return sm.deleteFeed(ctx, id, ifVersion)
+	case models.ResourceSleep:
+		return sm.deleteSleep(ctx, id, ifVersion)
+	case models.ResourceGrowth:
+		return sm.deleteGrowth(ctx, id, ifVersion)
+	case models.ResourceDiapers:
+		return sm.deleteDiaper(ctx, id, ifVersion)
+	}
+	return fmt.Errorf("unknown resource %q", resource)
+}
+
+// beginBatch starts the journal that lets a batch be rolled back: the audit
+// log's length now, and then each file's contents before the batch first writes
+// it (see journalFile). The caller holds sm.mu.
+func (sm *StorageManager) beginBatch() error {
+	j := &batchJournal{Files: map[string][]byte{}}
+	if info, err := os.Stat(filepath.Join(sm.dataDir, auditFileName)); err == nil {
+		j.AuditSize = info.Size()
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to stat %s: %w", auditFileName, err)
+	}
+	if err := sm.writeJournal(j); err != nil {
+		return err
+	}
+	sm.journal = j
+	return nil
+}
+
+// journalFile records a file's contents in the running batch's journal before
+// the batch first writes it, so only the files a batch touches are copied.
+// The caller holds sm.mu.
+func (sm *StorageManager) journalFile(name string) error {
+	if _, ok := sm.journal.Files[name]; ok {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(sm.dataDir, name))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	sm.journal.Files[name] = data
+	return sm.writeJournal(sm.journal)
+}
+
+// writeJournal saves the journal; it must be on disk before the write it covers.
+func (sm *StorageManager) writeJournal(j *batchJournal) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", batchJournalFileName, err)
+	}
+	return writeFileAtomic(filepath.Join(sm.dataDir, batchJournalFileName), data)
+}
+
+// endBatch commits a batch by removing its journal, rolling the batch back if
+// that fails. The caller holds sm.mu.
+func (sm *StorageManager) endBatch() error {
+	sm.journal = nil
+	if err := os.Remove(filepath.Join(sm.dataDir, batchJournalFileName)); err != nil {
+		if rerr := sm.recoverBatch(); rerr != nil {
+			return fmt.Errorf("failed to finish batch: %w (rollback failed: %v)", err, rerr)
+		}
+		return fmt.Errorf("failed to finish batch: %w", err)
+	}
+	return nil
+}
+
+// recoverBatch rolls back an unfinished batch: it restores the files saved in
+// the journal, truncates the audit log to its old length and removes the journal.
+// Does nothing when there is no journal. The caller holds sm.mu, or is opening the directory.
+func (sm *StorageManager) recoverBatch() error {
+	sm.journal = nil
+	journalPath := filepath.Join(sm.dataDir, batchJournalFileName)
+	data, err := os.ReadFile(journalPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", batchJournalFileName, err)
+	}
+	var j batchJournal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", batchJournalFileName, err)
+	}
+	for name, contents := range j.Files {
+		path := filepath.Join(sm.dataDir, name)
+		if contents == nil {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to roll back %s: %w", name, err)
+			}
+			continue
+		}
+		if err := writeFileAtomic(path, contents); err != nil {
+			return fmt.Errorf("failed to roll back %s: %w", name, err)
+		}
+	}
+	auditPath := filepath.Join(sm.dataDir, auditFileName)
+	if err := os.Truncate(auditPath, j.AuditSize); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to roll back %s: %w", auditFileName, err)
+	}
+	return os.Remove(journalPath)
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"babytracker/internal/models"
+)
+
+func TestApplyBatch(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+	ctx := context.Background()
+
+	ops := []BatchOp{
+		{Key: "k1", Action: models.AuditCreate, Resource: models.ResourceFeeds,
+			Entry: &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Quantity: 90}},
+		{Key: "k2", Action: models.AuditUpdate, Resource: models.ResourceFeeds, Ref: "k1",
+			Entry: &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Quantity: 120}},
+		{Key: "k3", Action: models.AuditCreate, Resource: models.ResourceDiapers,
+			Entry: &models.DiaperEntry{Date: "2026-04-06", Type: models.DiaperTypeWet}},
+	}
+	results, err := ApplyBatch(ctx, ops)
+	if err != nil {
+		t.Fatalf("ApplyBatch failed: %v", err)
+	}
+	if results[0].ID != 1 || results[1].ID != 1 || results[2].ID != 1 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	feeds, _ := LoadFeeds()
+	if len(feeds) != 1 || feeds[0].Quantity != 120 || feeds[0].Version != 2 {
+		t.Errorf("unexpected feeds: %+v", feeds)
+	}
+
+	// Replaying the same queue writes nothing
+	results, err = ApplyBatch(ctx, ops)
+	if err != nil {
+		t.Fatalf("replayed ApplyBatch failed: %v", err)
+	}
+	if !results[0].Replayed || !results[2].Replayed || results[0].ID != 1 {
+		t.Errorf("expected replayed results, got %+v", results)
+	}
+	if feeds, _ := LoadFeeds(); len(feeds) != 1 {
+		t.Errorf("replay created duplicates: %+v", feeds)
+	}
+	if _, err := os.Stat(filepath.Join(sm.dataDir, batchJournalFileName)); !os.IsNotExist(err) {
+		t.Errorf("journal left behind after a successful batch")
+	}
+}
+
+func TestApplyBatchRollsBack(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+	ctx := context.Background()
+
+	if err := SaveSleep(ctx, &models.SleepEntry{Date: "2026-04-06", Type: models.SleepTypeNap}); err != nil {
+		t.Fatalf("SaveSleep failed: %v", err)
+	}
+	auditBefore, _ := LoadAudit(models.AuditFilter{})
+
+	_, err := ApplyBatch(ctx, []BatchOp{
+		{Key: "a", Action: models.AuditCreate, Resource: models.ResourceFeeds,
+			Entry: &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle}},
+		{Action: models.AuditDelete, Resource: models.ResourceSleep, ID: 1},
+		{Action: models.AuditUpdate, Resource: models.ResourceSleep, ID: 7,
+			Entry: &models.SleepEntry{Date: "2026-04-06", Type: models.SleepTypeNap}},
+	})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 2 || !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a not-found BatchError for op 2, got %v", err)
+	}
+
+	if feeds, _ := LoadFeeds(); len(feeds) != 0 {
+		t.Errorf("create was not rolled back: %+v", feeds)
+	}
+	if sleep, _ := LoadSleep(); len(sleep) != 1 {
+		t.Errorf("delete was not rolled back: %+v", sleep)
+	}
+	if trash, _ := LoadTrash(models.ResourceSleep); len(trash) != 0 {
+		t.Errorf("trash was not rolled back: %+v", trash)
+	}
+	if audit, _ := LoadAudit(models.AuditFilter{}); len(audit) != len(auditBefore) {
+		t.Errorf("audit log has %d entries after rollback, want %d", len(audit), len(auditBefore))
+	}
+	// The key of the rolled-back create is free again
+	if _, ok, _ := sm.findKey("", "a"); ok {
+		t.Error("idempotency key of a rolled-back op was kept")
+	}
+}
+
+func TestOpenRollsBackInterruptedBatch(t *testing.T) {
+	dir := t.TempDir()
+	sm, err := NewStorageManagerWithDir(dir)
+	if err != nil {
+		t.Fatalf("NewStorageManagerWithDir failed: %v", err)
+	}
+	if err := saveJSON(sm, "feeds.json", []models.FeedEntry{{ID: 1, Type: models.FeedTypeBottle}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.beginBatch(); err != nil {
+		t.Fatalf("beginBatch failed: %v", err)
+	}
+	// Crash mid-batch: one file rewritten, another created
+	if err := saveJSON(sm, "feeds.json", []models.FeedEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveJSON(sm, "diapers/2026-04.json", []models.DiaperEntry{{ID: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	sm, err = NewStorageManagerWithDir(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if feeds, _ := loadJSON[models.FeedEntry](sm, "feeds.json"); len(feeds) != 1 {
+		t.Errorf("feeds.json not restored: %+v", feeds)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "diapers", "2026-04.json")); !os.IsNotExist(err) {
+		t.Errorf("diapers/2026-04.json created by the interrupted batch should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, batchJournalFileName)); !os.IsNotExist(err) {
+		t.Errorf("journal should be removed after recovery")
+	}
+}
@@ -0,0 +1,360 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Encryption at rest (FINDING-09).
+//
+// A random 256-bit data key (DEK) encrypts every data file with AES-256-GCM,
+// using the file name as associated data so files can't be swapped. The DEK is
+// stored in keyfile.json wrapped by a key-encryption key derived from the
+// passphrase with Argon2id. Changing the passphrase (Rekey) only rewraps the DEK.
+
+const keyFileName = "keyfile.json"
+
+// encMagic prefixes every encrypted file. Files without it are read as plaintext,
+// which lets an interrupted EncryptDataDir migration be resumed.
+var encMagic = []byte("BTENC\x00\x01\n")
+
+var (
+	// ErrLocked is returned when the data directory is encrypted and Unlock has not been called.
+	ErrLocked = errors.New("storage is encrypted and locked; unlock it with the passphrase")
+	// ErrBadPassphrase is returned when the passphrase does not unwrap the data key.
+	ErrBadPassphrase = errors.New("incorrect passphrase")
+)
+
+// KDFParams are the Argon2id cost parameters recorded in the keyfile.
+type KDFParams struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"` // KiB
+	Threads uint8  `json:"threads"`
+}
+
+// DefaultKDFParams follow the RFC 9106 second recommended option (64 MiB).
+// Tests lower them to keep runs fast.
+var DefaultKDFParams = KDFParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// keyFile is the on-disk form of the wrapped data key.
+type keyFile struct {
+	Version    int       `json:"version"`
+	KDF        string    `json:"kdf"`
+	Params     KDFParams `json:"params"`
+	Salt       []byte    `json:"salt"`
+	Nonce      []byte    `json:"nonce"`
+	WrappedDEK []byte    `json:"wrapped_dek"`
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func deriveKEK(passphrase string, salt []byte, p KDFParams) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, p.Time, p.Memory, p.Threads, 32)
+}
+
+// wrapDEK seals dek under a fresh salt and passphrase-derived key.
+func wrapDEK(dek []byte, passphrase string, p KDFParams) (*keyFile, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	kf := &keyFile{Version: 1, KDF: "argon2id", Params: p, Salt: make([]byte, 16)}
+	if _, err := rand.Read(kf.Salt); err != nil {
+		return nil, err
+	}
+	kek, err := newGCM(deriveKEK(passphrase, kf.Salt, p))
+	if err != nil {
+		return nil, err
+	}
+	kf.Nonce = make([]byte, kek.NonceSize())
+	if _, err := rand.Read(kf.Nonce); err != nil {
+		return nil, err
+	}
+	kf.WrappedDEK = kek.Seal(nil, kf.Nonce, dek, []byte(keyFileName))
+	return kf, nil
+}
+
+// unwrapDEK recovers the data key, failing with ErrBadPassphrase on a wrong passphrase.
+func (kf *keyFile) unwrapDEK(passphrase string) ([]byte, error) {
+	if kf.KDF != "argon2id" {
+		return nil, fmt.Errorf("unsupported key derivation %q", kf.KDF)
+	}
+	kek, err := newGCM(deriveKEK(passphrase, kf.Salt, kf.Params))
+	if err != nil {
+		return nil, err
+	}
+	dek, err := kek.Open(nil, kf.Nonce, kf.WrappedDEK, []byte(keyFileName))
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return dek, nil
+}
+
+func readKeyFile(dataDir string) (*keyFile, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, keyFileName))
+	if err != nil {
+		return nil, err
+	}
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", keyFileName, err)
+	}
+	return &kf, nil
+}
+
+func writeKeyFile(dataDir string, kf *keyFile) error {
+	data, err := json.MarshalIndent(kf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dataDir, keyFileName), data)
+}
+
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encMagic)
+}
+
+// seal encrypts a file's contents, binding them to its name.
+func seal(aead cipher.AEAD, name string, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append([]byte{}, encMagic...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, []byte(name)), nil
+}
+
+// unseal decrypts a file written by seal.
+func unseal(aead cipher.AEAD, name string, data []byte) ([]byte, error) {
+	data = data[len(encMagic):]
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%s is truncated", name)
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s (corrupted or from another directory): %w", name, err)
+	}
+	return plaintext, nil
+}
+
+// readData returns the plaintext of a data file, decrypting it when needed.
+// The caller handles os.IsNotExist.
+func (sm *StorageManager) readData(name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(sm.dataDir, name))
+	if err != nil {
+		return nil, err
+	}
+	if !isEncrypted(data) {
+		return data, nil
+	}
+	if sm.aead == nil {
+		return nil, ErrLocked
+	}
+	return unseal(sm.aead, name, data)
+}
+
+// writeData atomically writes a data file, encrypting it when the directory is encrypted.
+// A locked encrypted directory refuses writes rather than mixing in plaintext.
+// During a batch the file is journaled first.
+func (sm *StorageManager) writeData(name string, plaintext []byte) error {
+	if sm.closed {
+		return ErrClosed
+	}
+	if sm.journal != nil {
+		if err := sm.journalFile(name); err != nil {
+			return err
+		}
+	}
+	data := plaintext
+	if sm.encrypted {
+		if sm.aead == nil {
+			return ErrLocked
+		}
+		var err error
+		if data, err = seal(sm.aead, name, plaintext); err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", name, err)
+		}
+	}
+	return writeFileAtomic(filepath.Join(sm.dataDir, name), data)
+}
+
+// unlock derives the data key from the passphrase and enables encryption.
+func (sm *StorageManager) unlock(passphrase string) error {
+	kf, err := readKeyFile(sm.dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", keyFileName, err)
+	}
+	dek, err := kf.unwrapDEK(passphrase)
+	if err != nil {
+		return err
+	}
+	aead, err := newGCM(dek)
+	if err != nil {
+		return err
+	}
+	sm.aead = aead
+	sm.encrypted = true
+	return nil
+}
+
+// Encrypted reports whether the data directory has been encrypted.
+func Encrypted() bool {
+	sm, err := getStorage()
+	if err != nil {
+		return false
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.encrypted
+}
+
+// Locked reports whether the data directory is encrypted and not yet unlocked.
+func Locked() bool {
+	sm, err := getStorage()
+	if err != nil {
+		return false
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.encrypted && sm.aead == nil
+}
+
+// Unlock opens an encrypted data directory with its passphrase and runs any
+// pending migrations. Call it after Init and before any Save/Load calls.
+func Unlock(passphrase string) error {
+	sm, err := getStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if err := sm.unlock(passphrase); err != nil {
+		return err
+	}
+	// Migrations wait for the key, since they read and rewrite the data files
+	if !sm.manualMigrations {
+		if sm.migrated, err = sm.migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rekey changes the passphrase of an encrypted data directory. The data key is
+// unchanged, so no data file is rewritten.
+func Rekey(oldPassphrase, newPassphrase string) error {
+	sm, err := getStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	kf, err := readKeyFile(sm.dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", keyFileName, err)
+	}
+	dek, err := kf.unwrapDEK(oldPassphrase)
+	if err != nil {
+		return err
+	}
+	rewrapped, err := wrapDEK(dek, newPassphrase, DefaultKDFParams)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(sm.dataDir, rewrapped)
+}
+
+// EncryptDataDir encrypts a plaintext data directory in place. Run it offline
+// (no API server or desktop app using the directory).
+//
+// The keyfile is written first, then each file is encrypted to a temp file,
+// verified by decrypting it, and renamed over the original. If interrupted,
+// running it again with the same passphrase finishes the remaining files.
+func EncryptDataDir(passphrase string) (encrypted int, err error) {
+	sm, err := getStorage()
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if _, err := os.Stat(filepath.Join(sm.dataDir, keyFileName)); os.IsNotExist(err) {
+		dek := make([]byte, 32)
+		if _, err := rand.Read(dek); err != nil {
+			return 0, err
+		}
+		kf, err := wrapDEK(dek, passphrase, DefaultKDFParams)
+		if err != nil {
+			return 0, err
+		}
+		if err := writeKeyFile(sm.dataDir, kf); err != nil {
+			return 0, err
+		}
+	}
+	if err := sm.unlock(passphrase); err != nil {
+		return 0, err
+	}
+
+	names, err := listDataFiles(sm.dataDir)
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range names {
+		if !isDataFile(name) {
+			continue
+		}
+		path := filepath.Join(sm.dataDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return encrypted, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if isEncrypted(data) {
+			continue
+		}
+		sealed, err := seal(sm.aead, name, data)
+		if err != nil {
+			return encrypted, fmt.Errorf("failed to encrypt %s: %w", name, err)
+		}
+		if check, err := unseal(sm.aead, name, sealed); err != nil || !bytes.Equal(check, data) {
+			return encrypted, fmt.Errorf("verification of encrypted %s failed; original left untouched", name)
+		}
+		if err := writeFileAtomic(path, sealed); err != nil {
+			return encrypted, err
+		}
+		encrypted++
+	}
+	rewritten, err := sm.encryptAuditLog()
+	if err != nil {
+		return encrypted, err
+	}
+	if rewritten {
+		encrypted++
+	}
+	return encrypted, nil
+}
+
+// isDataFile reports whether a file in the data directory holds household data
+// that belongs under encryption.
+func isDataFile(name string) bool {
+	if name == keyFileName || name == schemaFileName || strings.HasSuffix(name, ".tmp") {
+		return false
+	}
+	return strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".key")
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"babytracker/internal/models"
+)
+
+// setupEncryptedStorage returns a fresh global storage with cheap KDF parameters.
+func setupEncryptedStorage(t *testing.T) string {
+	t.Helper()
+	origParams := DefaultKDFParams
+	DefaultKDFParams = KDFParams{Time: 1, Memory: 64, Threads: 1}
+	origGlobal := globalStorage
+	t.Cleanup(func() {
+		DefaultKDFParams = origParams
+		globalStorage = origGlobal
+	})
+	dir := t.TempDir()
+	if err := Init(dir); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	return dir
+}
+
+func TestEncryptDataDirInPlace(t *testing.T) {
+	dir := setupEncryptedStorage(t)
+	if err := SaveFeed(context.Background(), &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Notes: "spit up"}); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+
+	n, err := EncryptDataDir("correct horse")
+	if err != nil {
+		t.Fatalf("EncryptDataDir failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("encrypted %d files, want 3 (the feeds month, its index and the audit log)", n)
+	}
+	for _, name := range []string{"feeds/2026-04.json", "feeds/index.json", auditFileName} {
+		raw, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		if strings.Contains(string(raw), "spit up") {
+			t.Errorf("%s still contains plaintext", name)
+		}
+	}
+
+	// Rerunning is a no-op once everything is encrypted
+	if n, err := EncryptDataDir("correct horse"); err != nil || n != 0 {
+		t.Errorf("rerun = %d, %v; want 0, nil", n, err)
+	}
+
+	// A fresh process sees an encrypted, locked directory
+	if err := Init(dir); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if !Locked() {
+		t.Fatal("expected storage to be locked after re-init")
+	}
+	if _, err := LoadFeeds(); err == nil {
+		t.Error("expected LoadFeeds to fail while locked")
+	}
+	if err := SaveDiaper(context.Background(), &models.DiaperEntry{Date: "2026-04-06", Type: models.DiaperTypeWet}); err == nil {
+		t.Error("expected writes to be refused while locked")
+	}
+	if err := Unlock("wrong"); err != ErrBadPassphrase {
+		t.Errorf("Unlock(wrong) = %v, want ErrBadPassphrase", err)
+	}
+	if err := Unlock("correct horse"); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	feeds, err := LoadFeeds()
+	if err != nil {
+		t.Fatalf("LoadFeeds failed: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].Notes != "spit up" {
+		t.Errorf("unexpected feeds after unlock: %+v", feeds)
+	}
+	if trail, err := LoadAudit(models.AuditFilter{}); err != nil || len(trail) != 1 {
+		t.Errorf("LoadAudit after unlock = %d entries, %v; want 1, nil", len(trail), err)
+	}
+}
+
+func TestEncryptedFilesBoundToName(t *testing.T) {
+	dir := setupEncryptedStorage(t)
+	if _, err := EncryptDataDir("pw"); err != nil {
+		t.Fatalf("EncryptDataDir failed: %v", err)
+	}
+	if err := SaveFeed(context.Background(), &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle}); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	// Swapping one encrypted file for another must not decrypt
+	data, err := os.ReadFile(filepath.Join(dir, "feeds", "2026-04.json"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "diapers"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "diapers", "2026-04.json"), data, 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := LoadDiapers(); err == nil {
+		t.Error("expected swapped file to fail authentication")
+	}
+}
+
+func TestRekey(t *testing.T) {
+	dir := setupEncryptedStorage(t)
+	if _, err := EncryptDataDir("old"); err != nil {
+		t.Fatalf("EncryptDataDir failed: %v", err)
+	}
+	if err := SaveFeed(context.Background(), &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle}); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	if err := Rekey("wrong", "new"); err != ErrBadPassphrase {
+		t.Errorf("Rekey with wrong passphrase = %v, want ErrBadPassphrase", err)
+	}
+	if err := Rekey("old", "new"); err != nil {
+		t.Fatalf("Rekey failed: %v", err)
+	}
+
+	if err := Init(dir); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if err := Unlock("old"); err != ErrBadPassphrase {
+		t.Errorf("old passphrase after rekey = %v, want ErrBadPassphrase", err)
+	}
+	if err := Unlock("new"); err != nil {
+		t.Fatalf("Unlock(new) failed: %v", err)
+	}
+	if feeds, err := LoadFeeds(); err != nil || len(feeds) != 1 {
+		t.Errorf("LoadFeeds after rekey = %d entries, %v", len(feeds), err)
+	}
+}
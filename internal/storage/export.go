@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+// ErrUnsupportedBundle is returned by Import for a bundle of another format version.
+var ErrUnsupportedBundle = errors.New("unsupported bundle format")
+
+// Export returns every live entry as a bundle. Trashed entries are left out.
+func Export() (*models.Bundle, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	b := &models.Bundle{Format: models.BundleFormat, ExportedAt: time.Now().UTC()}
+	if b.Feeds, err = loadEntries(sm, models.ResourceFeeds, "", "", feedKey); err != nil {
+		return nil, err
+	}
+	if b.Sleep, err = loadEntries(sm, models.ResourceSleep, "", "", sleepKey); err != nil {
+		return nil, err
+	}
+	if b.Growth, err = loadEntries(sm, models.ResourceGrowth, "", "", growthKey); err != nil {
+		return nil, err
+	}
+	if b.Diapers, err = loadEntries(sm, models.ResourceDiapers, "", "", diaperKey); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Import adds a bundle's entries that are not here yet, matched by UID, all or
+// nothing like a batch. Added entries keep their UID, attribution and contents
+// but get a new local ID and start again at version 1; each is audited as a
+// create. Entries without a UID (from an older export) are always added.
+func Import(ctx context.Context, b *models.Bundle) (models.ImportResult, error) {
+	res := models.ImportResult{Added: map[string]int{}, Skipped: map[string]int{}}
+	if b.Format != models.BundleFormat {
+		return res, fmt.Errorf("%w %d (expected %d)", ErrUnsupportedBundle, b.Format, models.BundleFormat)
+	}
+	sm, err := getStorage()
+	if err != nil {
+		return res, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.closed {
+		return res, ErrClosed
+	}
+	if err := sm.beginBatch(); err != nil {
+		return res, err
+	}
+	err = importEntries(sm, ctx, res, models.ResourceFeeds, b.Feeds, sm.saveFeed)
+	if err == nil {
+		err = importEntries(sm, ctx, res, models.ResourceSleep, b.Sleep, sm.saveSleep)
+	}
+	if err == nil {
+		err = importEntries(sm, ctx, res, models.ResourceGrowth, b.Growth, sm.saveGrowth)
+	}
+	if err == nil {
+		err = importEntries(sm, ctx, res, models.ResourceDiapers, b.Diapers, sm.saveDiaper)
+	}
+	if err == nil {
+		err = sm.rememberRequest(ctx, "", "", 0)
+	}
+	if err != nil {
+		if rerr := sm.recoverBatch(); rerr != nil {
+			return res, fmt.Errorf("%w (rollback failed: %v)", err, rerr)
+		}
+		return res, err
+	}
+	return res, sm.endBatch()
+}
+
+// importEntries saves the entries of one resource, skipping UIDs already in use.
+// The caller holds sm.mu.
+func importEntries[T any](sm *StorageManager, ctx context.Context, res models.ImportResult, resource string,
+	entries []T, save func(context.Context, *T) error) error {
+	for i := range entries {
+		err := save(ctx, &entries[i])
+		if errors.Is(err, ErrDuplicateUID) {
+			res.Skipped[resource]++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s entry %d: %w", resource, i, err)
+		}
+		res.Added[resource]++
+	}
+	return nil
+}
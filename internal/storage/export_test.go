@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"babytracker/internal/models"
+)
+
+func TestExportImport(t *testing.T) {
+	origGlobal := globalStorage
+	defer func() { globalStorage = origGlobal }()
+	ctx := context.Background()
+
+	// Device A logs two feeds and a diaper
+	a := setupTestStorage(t)
+	globalStorage = a
+	for _, q := range []float64{90, 120} {
+		if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Quantity: q, LoggedBy: "Asha"}); err != nil {
+			t.Fatalf("SaveFeed failed: %v", err)
+		}
+	}
+	if err := SaveDiaper(ctx, &models.DiaperEntry{Date: "2026-04-06", Type: models.DiaperTypeWet}); err != nil {
+		t.Fatalf("SaveDiaper failed: %v", err)
+	}
+	bundle, err := Export()
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	// Device B already has a feed of its own, so A's IDs clash with B's
+	b := setupTestStorage(t)
+	globalStorage = b
+	if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-05", Type: models.FeedTypeSolid}); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	res, err := Import(ctx, bundle)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if res.Added[models.ResourceFeeds] != 2 || res.Added[models.ResourceDiapers] != 1 {
+		t.Errorf("unexpected import result: %+v", res)
+	}
+	feeds, _ := LoadFeeds()
+	if len(feeds) != 3 || feeds[1].ID != 2 || feeds[1].UID != bundle.Feeds[0].UID || feeds[1].LoggedBy != "Asha" {
+		t.Errorf("expected A's feeds added with new IDs and their UIDs, got %+v", feeds)
+	}
+
+	// Importing again adds nothing
+	res, err = Import(ctx, bundle)
+	if err != nil || res.Skipped[models.ResourceFeeds] != 2 || res.Added[models.ResourceFeeds] != 0 {
+		t.Errorf("re-import = %+v, %v; want everything skipped", res, err)
+	}
+
+	// A bad entry rolls back the whole import
+	bundle.Feeds = append(bundle.Feeds, models.FeedEntry{Date: "2026-04-07", Type: models.FeedTypeBottle, UID: models.NewUID()})
+	bundle.Diapers = append(bundle.Diapers, models.DiaperEntry{Date: "2026-04-07", Type: models.DiaperTypeWet, UID: "bogus"})
+	if _, err := Import(ctx, bundle); !errors.Is(err, ErrInvalidUID) {
+		t.Errorf("expected ErrInvalidUID, got %v", err)
+	}
+	if feeds, _ := LoadFeeds(); len(feeds) != 3 {
+		t.Errorf("failed import left %d feeds, want 3", len(feeds))
+	}
+
+	bundle.Format = 99
+	if _, err := Import(ctx, bundle); !errors.Is(err, ErrUnsupportedBundle) {
+		t.Errorf("expected ErrUnsupportedBundle, got %v", err)
+	}
+}
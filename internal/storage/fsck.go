@@ -0,0 +1,649 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+// FsckFile reports what Fsck found in one data file.
+type FsckFile struct {
+	File     string   `json:"file"`
+	Records  int      `json:"records"`            // Records that could be read
+	Dropped  int      `json:"dropped"`            // Unreadable stretches that salvage skipped
+	Problems []string `json:"problems,omitempty"` // Everything wrong, including what was dropped
+	Repaired bool     `json:"repaired,omitempty"` // A repaired copy replaced the file
+}
+
+// FsckReport is the result of Fsck.
+type FsckReport struct {
+	Files  []FsckFile `json:"files"`
+	Backup string     `json:"backup,omitempty"` // Backup of the originals, taken before repairing
+}
+
+// Healthy reports whether no file had a problem.
+func (r *FsckReport) Healthy() bool {
+	for _, f := range r.Files {
+		if len(f.Problems) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fsckResult is one file's findings and, when it needs one, how to write its
+// repaired copy.
+type fsckResult struct {
+	FsckFile
+	write func() error
+}
+
+// Fsck checks every data file. Arrays are read one element at a time, so the
+// readable records of a truncated or partly overwritten file are salvaged;
+// duplicate IDs and impossible values are reported. With repair, the data
+// directory is first backed up, then each damaged file is rewritten from what
+// was salvaged, duplicate entry IDs and UIDs are renumbered, and the audit log
+// loses its unreadable lines. Values are reported but never changed.
+func Fsck(repair bool) (*FsckReport, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.closed {
+		return nil, ErrClosed
+	}
+	if sm.encrypted && sm.aead == nil {
+		return nil, ErrLocked
+	}
+
+	var results []*fsckResult
+	entryChecks := []func() ([]*fsckResult, error){
+		func() ([]*fsckResult, error) {
+			return fsckEntries(sm, models.ResourceFeeds, func(e *models.FeedEntry) (*int, *string, string) { return &e.ID, &e.UID, e.Date }, checkFeed)
+		},
+		func() ([]*fsckResult, error) {
+			return fsckEntries(sm, models.ResourceSleep, func(e *models.SleepEntry) (*int, *string, string) { return &e.ID, &e.UID, e.Date }, checkSleep)
+		},
+		func() ([]*fsckResult, error) {
+			return fsckEntries(sm, models.ResourceGrowth, func(e *models.GrowthEntry) (*int, *string, string) { return &e.ID, &e.UID, e.Date }, checkGrowth)
+		},
+		func() ([]*fsckResult, error) {
+			return fsckEntries(sm, models.ResourceDiapers, func(e *models.DiaperEntry) (*int, *string, string) { return &e.ID, &e.UID, e.Date }, checkDiaper)
+		},
+	}
+	for _, check := range entryChecks {
+		res, err := check()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res...)
+	}
+
+	var checks []func() (*fsckResult, error)
+	for _, resource := range models.AllResources {
+		name := trashFileName(resource)
+		checks = append(checks, func() (*fsckResult, error) {
+			return fsckArray(sm, name, func(t models.TrashItem) int { return t.ID })
+		})
+	}
+	checks = append(checks,
+		func() (*fsckResult, error) {
+			return fsckArray(sm, "caregivers.json", func(c models.Caregiver) int { return c.ID })
+		},
+		func() (*fsckResult, error) {
+			return fsckArray(sm, "users.json", func(u models.User) int { return u.ID })
+		},
+		func() (*fsckResult, error) {
+			return fsckArray(sm, "tokens.json", func(t models.APIToken) int { return t.ID })
+		},
+		func() (*fsckResult, error) { return fsckIdempotency(sm) },
+		func() (*fsckResult, error) { return fsckAudit(sm) },
+		func() (*fsckResult, error) { return fsckSchema(sm) },
+	)
+
+	for _, check := range checks {
+		res, err := check()
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			results = append(results, res)
+		}
+	}
+
+	report := &FsckReport{}
+	needsRepair := false
+	for _, res := range results {
+		needsRepair = needsRepair || res.write != nil
+	}
+	if repair && needsRepair {
+		b, err := sm.writeBackup(models.BackupRepair)
+		if err != nil {
+			return nil, fmt.Errorf("refusing to repair without a backup: %w", err)
+		}
+		if b != nil {
+			report.Backup = b.Name
+		}
+	}
+	for _, res := range results {
+		if repair && res.write != nil {
+			if err := res.write(); err != nil {
+				return nil, fmt.Errorf("failed to repair %s: %w", res.File, err)
+			}
+			res.Repaired = true
+		}
+		report.Files = append(report.Files, res.FsckFile)
+	}
+	return report, nil
+}
+
+// fsckRead returns a file's plaintext, or a result reporting why it can't be
+// read. Both are nil for a file that doesn't exist.
+func fsckRead(sm *StorageManager, name string) ([]byte, *fsckResult, error) {
+	data, err := sm.readData(name)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		if _, statErr := os.Stat(filepath.Join(sm.dataDir, name)); statErr != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		// Decryption covers the whole file, so nothing in it can be trusted
+		res := &fsckResult{FsckFile: FsckFile{File: name}}
+		res.Problems = append(res.Problems, fmt.Sprintf("unreadable (%v); restore it from a backup", err))
+		return nil, res, nil
+	}
+	return data, nil, nil
+}
+
+// salvageArray decodes the elements of a JSON array one at a time. An element
+// that can't be decoded, or that valid rejects, is skipped up to the next place
+// an element could start. It returns the readable elements and how many
+// stretches it skipped.
+func salvageArray[T any](data []byte, valid func(T) bool) ([]T, int) {
+	items := []T{}
+	skipped := 0
+	start := bytes.IndexByte(data, '[')
+	if start < 0 {
+		// saveJSON writes an empty slice that was never allocated as null
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && string(trimmed) != "null" {
+			skipped++
+		}
+		return items, skipped
+	}
+	pos := start + 1
+	for {
+		for pos < len(data) && (data[pos] == ',' || data[pos] == ' ' || data[pos] == '\n' || data[pos] == '\r' || data[pos] == '\t') {
+			pos++
+		}
+		if pos >= len(data) || data[pos] == ']' {
+			return items, skipped
+		}
+		var item T
+		dec := json.NewDecoder(bytes.NewReader(data[pos:]))
+		if data[pos] == '{' && dec.Decode(&item) == nil && valid(item) {
+			items = append(items, item)
+			pos += int(dec.InputOffset())
+			continue
+		}
+		skipped++
+		if pos = nextElementStart(data, pos+1); pos < 0 {
+			return items, skipped
+		}
+	}
+}
+
+// nextElementStart finds the next '{' at or after from that could begin an
+// array element rather than a nested object: one that follows a ',' or '['
+// (ignoring whitespace), or that starts a line at saveJSON's element indent.
+// Returns -1 when there is none.
+func nextElementStart(data []byte, from int) int {
+	for i := from; i < len(data); i++ {
+		if data[i] != '{' {
+			continue
+		}
+		if bytes.HasSuffix(data[:i], []byte("\n  ")) {
+			return i
+		}
+		prev := bytes.TrimRight(data[:i], " \n\r\t")
+		if len(prev) > 0 && (prev[len(prev)-1] == ',' || prev[len(prev)-1] == '[') {
+			return i
+		}
+	}
+	return -1
+}
+
+// salvageResult starts a file's result from its salvaged records and reports
+// whether the file needs rewriting even if nothing else is wrong with it.
+func salvageResult[T any](name string, data []byte, items []T, skipped int) (*fsckResult, bool) {
+	res := &fsckResult{FsckFile: FsckFile{File: name, Records: len(items), Dropped: skipped}}
+	if skipped > 0 {
+		res.Problems = append(res.Problems, fmt.Sprintf("%d unreadable stretch(es) skipped; %d records salvaged", skipped, len(items)))
+	}
+	var strict []T
+	if err := json.Unmarshal(data, &strict); err != nil {
+		if skipped == 0 {
+			res.Problems = append(res.Problems, fmt.Sprintf("not valid JSON (%v), but every record was salvaged", err))
+		}
+		return res, true
+	}
+	return res, skipped > 0
+}
+
+// fsckArray checks a data file holding a JSON array of records with an ID,
+// reporting duplicate IDs. Repair drops what can't be read.
+func fsckArray[T any](sm *StorageManager, name string, id func(T) int) (*fsckResult, error) {
+	data, unreadable, err := fsckRead(sm, name)
+	if data == nil {
+		return unreadable, err
+	}
+	items, skipped := salvageArray(data, func(t T) bool { return id(t) > 0 })
+	res, rewrite := salvageResult(name, data, items, skipped)
+	seen := map[int]bool{}
+	for _, item := range items {
+		if seen[id(item)] {
+			res.Problems = append(res.Problems, fmt.Sprintf("id %d appears more than once", id(item)))
+		}
+		seen[id(item)] = true
+	}
+	if rewrite {
+		res.write = func() error { return saveJSON(sm, name, items) }
+	}
+	return res, nil
+}
+
+// fsckIdempotency checks idempotency.json, whose records are keyed by Key.
+func fsckIdempotency(sm *StorageManager) (*fsckResult, error) {
+	data, unreadable, err := fsckRead(sm, idempotencyFileName)
+	if data == nil {
+		return unreadable, err
+	}
+	items, skipped := salvageArray(data, func(k models.IdempotencyKey) bool { return k.Key != "" })
+	res, rewrite := salvageResult(idempotencyFileName, data, items, skipped)
+	if rewrite {
+		res.write = func() error { return saveJSON(sm, idempotencyFileName, items) }
+	}
+	return res, nil
+}
+
+// entryFile is one file of a resource's entries as fsckEntries found it.
+type entryFile[T any] struct {
+	part  string // Month, "" for the single file of schema 1
+	res   *fsckResult
+	items []T
+	dirty bool // Must be rewritten
+}
+
+// fsckEntries checks a resource's entries: each month file (or, before
+// migration 2, the single file) for what salvage keeps and impossible values;
+// across them duplicate IDs and UIDs, entries filed under the wrong month and
+// stale copies left by an interrupted move; and the index. fields points at an
+// entry's ID and UID, so repair can give later duplicates new ones, and gives
+// its date, so repair can move a misfiled entry.
+func fsckEntries[T any](sm *StorageManager, resource string, fields func(e *T) (*int, *string, string), check func(e T) []string) ([]*fsckResult, error) {
+	var results []*fsckResult
+	var files []*entryFile[T]
+	byName := map[string]*entryFile[T]{}
+	unreadableParts := map[string]bool{}
+	names := map[string]string{} // file name -> month
+	legacy := legacyEntryFiles[resource]
+	if _, err := os.Stat(filepath.Join(sm.dataDir, legacy)); err == nil {
+		names[legacy] = ""
+	} else {
+		parts, err := sm.partitionFiles(resource)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			names[partitionFile(resource, part)] = part
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		data, unreadable, err := fsckRead(sm, name)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			if unreadable != nil {
+				results = append(results, unreadable)
+				unreadableParts[names[name]] = true
+			}
+			continue
+		}
+		items, skipped := salvageArray(data, func(e T) bool {
+			id, _, _ := fields(&e)
+			return *id > 0
+		})
+		res, rewrite := salvageResult(name, data, items, skipped)
+		f := &entryFile[T]{part: names[name], res: res, items: items, dirty: rewrite}
+		files = append(files, f)
+		byName[name] = f
+		results = append(results, res)
+	}
+
+	// The index says which copy of a moved entry is current. It only holds
+	// IDs, months and UIDs, so repair rebuilds rather than salvages it.
+	_, legacyLayout := names[legacy]
+	var stored *partitionIndex
+	var indexRes *fsckResult
+	if !legacyLayout {
+		name := indexFile(resource)
+		data, unreadable, err := fsckRead(sm, name)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case unreadable != nil:
+			indexRes = &fsckResult{FsckFile: FsckFile{File: name}}
+			indexRes.Problems = append(indexRes.Problems, "unreadable; repair rebuilds it")
+		case data != nil:
+			indexRes = &fsckResult{FsckFile: FsckFile{File: name}}
+			stored = &partitionIndex{}
+			if err := json.Unmarshal(data, stored); err != nil {
+				indexRes.Problems = append(indexRes.Problems, fmt.Sprintf("not valid JSON (%v); repair rebuilds it", err))
+			} else if stored.NextID == 0 || stored.Months == nil || stored.UIDs == nil {
+				indexRes.Problems = append(indexRes.Problems, "incomplete, without its next ID, months or UIDs; repair rebuilds it")
+			}
+			indexRes.Records = len(stored.Months)
+		case len(names) > 0:
+			indexRes = &fsckResult{FsckFile: FsckFile{File: name}}
+			indexRes.Problems = append(indexRes.Problems, "missing; repair rebuilds it")
+		}
+	}
+
+	// New IDs must not collide with trashed entries, which keep theirs, nor
+	// with purged ones, whose audit trail does
+	var ids []int
+	if stored != nil && stored.NextID > 1 {
+		ids = append(ids, stored.NextID-1)
+	}
+	if audited, err := sm.lastAuditedID(resource); err == nil && audited > 0 { // fsckAudit reports a damaged log
+		ids = append(ids, audited)
+	}
+	copies := map[int]int{}
+	indexedCopy := map[int]bool{}
+	for _, f := range files {
+		for i := range f.items {
+			id, _, _ := fields(&f.items[i])
+			ids = append(ids, *id)
+			copies[*id]++
+			if stored != nil && f.part != "" && stored.Months[*id] == f.part {
+				indexedCopy[*id] = true
+			}
+		}
+	}
+	var trashed []models.TrashItem
+	if trash, err := sm.readData(trashFileName(resource)); err == nil {
+		trashed, _ = salvageArray(trash, func(t models.TrashItem) bool { return t.ID > 0 })
+		for _, t := range trashed {
+			ids = append(ids, t.ID)
+		}
+	}
+	seenIDs, seenUIDs := map[int]bool{}, map[string]bool{}
+	moves := map[string][]T{}
+	for _, f := range files {
+		kept := f.items[:0:0]
+		for i := range f.items {
+			id, uid, date := fields(&f.items[i])
+			if copies[*id] > 1 && indexedCopy[*id] && stored.Months[*id] != f.part {
+				f.res.Problems = append(f.res.Problems, fmt.Sprintf("entry %d: stale copy, the index has it in %s (repair removes it)", *id, stored.Months[*id]))
+				f.dirty = true
+				continue
+			}
+			for _, p := range check(f.items[i]) {
+				f.res.Problems = append(f.res.Problems, fmt.Sprintf("entry %d: %s", *id, p))
+			}
+			if seenIDs[*id] {
+				newID := nextID(ids)
+				ids = append(ids, newID)
+				f.res.Problems = append(f.res.Problems, fmt.Sprintf("entry %d: duplicate id (repair renumbers it %d)", *id, newID))
+				*id = newID
+				f.dirty = true
+			}
+			seenIDs[*id] = true
+			if *uid != "" { // An empty one is given one by migration 1
+				normalized, valid := models.NormalizeUID(*uid)
+				if !valid || seenUIDs[normalized] {
+					reason := "duplicate uid"
+					if !valid {
+						reason = "invalid uid"
+					}
+					f.res.Problems = append(f.res.Problems, fmt.Sprintf("entry %d: %s %q (repair gives it a new one)", *id, reason, *uid))
+					normalized = models.NewUID()
+					f.dirty = true
+				}
+				*uid = normalized
+				seenUIDs[normalized] = true
+			}
+			if f.part != "" && partitionOf(date) != f.part {
+				f.res.Problems = append(f.res.Problems, fmt.Sprintf("entry %d: dated %q, filed under %s (repair moves it)", *id, date, f.part))
+				moves[partitionOf(date)] = append(moves[partitionOf(date)], f.items[i])
+				f.dirty = true
+				continue
+			}
+			kept = append(kept, f.items[i])
+		}
+		f.items = kept
+	}
+	for part, items := range moves {
+		name := partitionFile(resource, part)
+		f := byName[name]
+		if f == nil {
+			f = &entryFile[T]{part: part, res: &fsckResult{FsckFile: FsckFile{File: name}}}
+			files = append(files, f)
+			byName[name] = f
+			results = append(results, f.res)
+		}
+		f.items = append(f.items, items...)
+		sort.SliceStable(f.items, func(i, j int) bool {
+			a, _, _ := fields(&f.items[i])
+			b, _, _ := fields(&f.items[j])
+			return *a > *b // Newest first, see placeOf
+		})
+		f.dirty = true
+	}
+	for _, f := range files {
+		if f.dirty {
+			name, items := f.res.File, f.items
+			f.res.write = func() error { return saveJSON(sm, name, items) }
+		}
+	}
+
+	// The index repair writes: every entry where it now is with its UID, the
+	// trashed entries' UIDs, and what the old one says about months that
+	// couldn't be read
+	if indexRes != nil {
+		idx := newPartitionIndex()
+		idx.NextID = nextID(ids)
+		if stored != nil {
+			for id, part := range stored.Months {
+				if unreadableParts[part] {
+					idx.Months[id] = part
+				}
+			}
+			for uid, id := range stored.UIDs {
+				if _, ok := idx.Months[id]; ok {
+					idx.UIDs[uid] = id
+				}
+			}
+		}
+		for _, f := range files {
+			for i := range f.items {
+				id, uid, _ := fields(&f.items[i])
+				idx.Months[*id] = f.part
+				if *uid != "" {
+					idx.UIDs[*uid] = *id
+				}
+			}
+		}
+		for _, t := range trashed {
+			if uid := rawUID(t.Entry); uid != "" {
+				idx.UIDs[uid] = t.ID
+			}
+		}
+		if len(indexRes.Problems) == 0 {
+			var missing, dangling, wrong, uids int
+			for id, part := range idx.Months {
+				if got, ok := stored.Months[id]; !ok {
+					missing++
+				} else if got != part {
+					wrong++
+				}
+			}
+			for id := range stored.Months {
+				if _, ok := idx.Months[id]; !ok {
+					dangling++
+				}
+			}
+			for uid, id := range idx.UIDs {
+				if got, ok := stored.UIDs[uid]; !ok || got != id {
+					uids++
+				}
+			}
+			for uid := range stored.UIDs {
+				if _, ok := idx.UIDs[uid]; !ok {
+					uids++
+				}
+			}
+			if missing+dangling+wrong+uids > 0 {
+				indexRes.Problems = append(indexRes.Problems, fmt.Sprintf("%d entries missing, %d without an entry, %d in the wrong month, %d UIDs out of date (repair rebuilds it)", missing, dangling, wrong, uids))
+			}
+			if stored.NextID < idx.NextID {
+				indexRes.Problems = append(indexRes.Problems, fmt.Sprintf("next id %d would reuse an ID (repair raises it to %d)", stored.NextID, idx.NextID))
+			}
+		}
+		if len(indexRes.Problems) > 0 {
+			indexRes.write = func() error { return sm.saveIndex(resource, idx) }
+		}
+		results = append(results, indexRes)
+	}
+	return results, nil
+}
+
+// fsckAudit checks the audit log line by line. Repair drops unreadable lines,
+// such as one torn by a crash mid-append.
+func fsckAudit(sm *StorageManager) (*fsckResult, error) {
+	path := filepath.Join(sm.dataDir, auditFileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", auditFileName, err)
+	}
+	res := &fsckResult{FsckFile: FsckFile{File: auditFileName}}
+	var kept bytes.Buffer
+	for n, line := range bytes.Split(data, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		if _, err := sm.readAuditLine(line); err != nil {
+			res.Dropped++
+			res.Problems = append(res.Problems, fmt.Sprintf("line %d unreadable (%v)", n+1, err))
+			continue
+		}
+		res.Records++
+		kept.Write(line)
+		kept.WriteByte('\n')
+	}
+	if res.Dropped > 0 {
+		res.write = func() error { return writeFileAtomic(path, kept.Bytes()) }
+	}
+	return res, nil
+}
+
+// fsckSchema reports an unreadable schema.json. It isn't repaired: the version
+// it held can't be guessed.
+func fsckSchema(sm *StorageManager) (*fsckResult, error) {
+	if _, err := os.Stat(filepath.Join(sm.dataDir, schemaFileName)); os.IsNotExist(err) {
+		return nil, nil
+	}
+	res := &fsckResult{FsckFile: FsckFile{File: schemaFileName, Records: 1}}
+	if _, err := sm.readSchema(); err != nil {
+		res.Records = 0
+		res.Problems = append(res.Problems, fmt.Sprintf("%v; restore it from a backup", err))
+	}
+	return res, nil
+}
+
+// checkDate reports a date that isn't YYYY-MM-DD or is in the future.
+func checkDate(date string) []string {
+	d, err := time.ParseInLocation(time.DateOnly, date, models.Location())
+	if err != nil {
+		return []string{fmt.Sprintf("date %q is not YYYY-MM-DD", date)}
+	}
+	if d.After(models.Now().AddDate(0, 0, 1)) {
+		return []string{fmt.Sprintf("date %s is in the future", date)}
+	}
+	return nil
+}
+
+func checkFeed(f models.FeedEntry) []string {
+	problems := checkDate(f.Date)
+	if f.Type == "" {
+		problems = append(problems, "no type")
+	}
+	if f.Quantity < 0 {
+		problems = append(problems, fmt.Sprintf("negative quantity %g", f.Quantity))
+	}
+	if f.Duration < 0 {
+		problems = append(problems, fmt.Sprintf("negative duration %d", f.Duration))
+	}
+	return problems
+}
+
+func checkSleep(s models.SleepEntry) []string {
+	problems := checkDate(s.Date)
+	if s.Type == "" {
+		problems = append(problems, "no type")
+	}
+	if !s.StartTime.IsZero() && !s.EndTime.IsZero() && s.EndTime.Before(s.StartTime.Time) {
+		problems = append(problems, "ends before it starts")
+	}
+	if s.Duration < 0 || s.Duration > 24*60 {
+		problems = append(problems, fmt.Sprintf("duration %d minutes", s.Duration))
+	}
+	return problems
+}
+
+// Growth measurements beyond these (kg and cm) can't be a child's.
+const (
+	maxWeightKg = 50
+	maxHeightCm = 200
+	maxHeadCm   = 80
+)
+
+func checkGrowth(g models.GrowthEntry) []string {
+	problems := checkDate(g.Date)
+	for _, m := range []struct {
+		name  string
+		value float64
+		max   float64
+	}{{"weight", g.Weight, maxWeightKg}, {"height", g.Height, maxHeightCm}, {"head circumference", g.HeadCircumference, maxHeadCm}} {
+		if m.value < 0 || m.value > m.max {
+			problems = append(problems, fmt.Sprintf("%s %g out of range", m.name, m.value))
+		}
+	}
+	return problems
+}
+
+func checkDiaper(d models.DiaperEntry) []string {
+	problems := checkDate(d.Date)
+	if d.Type == "" {
+		problems = append(problems, "no type")
+	}
+	return problems
+}
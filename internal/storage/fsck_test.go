@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"babytracker/internal/models"
+)
+
+func TestSalvageArray(t *testing.T) {
+	valid := func(c models.Caregiver) bool { return c.ID > 0 }
+	tests := []struct {
+		name    string
+		data    string
+		ids     []int
+		skipped int
+	}{
+		{"intact", `[{"id": 1}, {"id": 2}]`, []int{1, 2}, 0},
+		{"compact", `[{"id":1},{"id":2}]`, []int{1, 2}, 0},
+		{"null", `null`, nil, 0},
+		{"empty file", ``, nil, 0},
+		{"truncated", "[\n  {\n    \"id\": 1\n  },\n  {\n    \"id\": 2,\n    \"na", []int{1}, 1},
+		{"garbage in the middle", "[\n  {\"id\": 1},\n  {\"id\": 2, \x00\x00\x00\n  {\"id\": 3}\n]", []int{1, 3}, 1},
+		{"wrong type", `[{"id": "one"}, {"id": 2}]`, []int{2}, 1},
+		{"zeroed", "\x00\x00\x00\x00", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, skipped := salvageArray([]byte(tt.data), valid)
+			var ids []int
+			for _, c := range items {
+				ids = append(ids, c.ID)
+			}
+			if len(ids) != len(tt.ids) || skipped != tt.skipped {
+				t.Fatalf("salvaged %v, skipped %d; want %v, %d", ids, skipped, tt.ids, tt.skipped)
+			}
+			for i := range ids {
+				if ids[i] != tt.ids[i] {
+					t.Errorf("salvaged %v, want %v", ids, tt.ids)
+				}
+			}
+		})
+	}
+}
+
+func TestFsck(t *testing.T) {
+	dir := setupBackupStorage(t)
+	uid := models.NewUID()
+	feeds := "[\n" +
+		`  {"id": 1, "uid": "` + uid + `", "date": "2026-04-06", "type": "Bottle", "quantity": 90},` + "\n" +
+		`  {"id": 1, "uid": "` + uid + `", "date": "2026-04-06", "type": "Bottle", "quantity": -5},` + "\n" +
+		`  {"id": 2, "date": "2026-04-07", "ty`
+	if err := os.MkdirAll(filepath.Join(dir, "feeds"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "feeds", "2026-04.json"), []byte(feeds), 0600); err != nil {
+		t.Fatal(err)
+	}
+	audit := `{"time":"2026-04-06T08:00:00Z","action":"create","resource":"feeds","entity_id":1}` + "\n" + `{"time":"2026-04-06T09:0`
+	if err := os.WriteFile(filepath.Join(dir, auditFileName), []byte(audit), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFeeds(); err == nil || !strings.Contains(err.Error(), "fsck") {
+		t.Fatalf("expected a parse error pointing at fsck, got %v", err)
+	}
+
+	// A check changes nothing
+	report, err := Fsck(false)
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if report.Healthy() || report.Backup != "" {
+		t.Fatalf("expected problems and no backup, got %+v", report)
+	}
+	files := map[string]FsckFile{}
+	for _, f := range report.Files {
+		files[f.File] = f
+	}
+	month := files["feeds/2026-04.json"]
+	problems := strings.Join(month.Problems, "\n")
+	for _, want := range []string{"1 unreadable", "duplicate id", "duplicate uid", "negative quantity"} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("feeds/2026-04.json problems %q lack %q", problems, want)
+		}
+	}
+	if month.Records != 2 || month.Dropped != 1 || month.Repaired {
+		t.Errorf("unexpected feeds/2026-04.json report: %+v", month)
+	}
+	if f := files["feeds/index.json"]; len(f.Problems) != 1 || !strings.Contains(f.Problems[0], "missing") {
+		t.Errorf("expected the missing index to be reported, got %+v", f)
+	}
+	if f := files[auditFileName]; f.Records != 1 || f.Dropped != 1 {
+		t.Errorf("unexpected audit report: %+v", f)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "feeds", "2026-04.json")); string(data) != feeds {
+		t.Error("a check without repair rewrote feeds/2026-04.json")
+	}
+
+	// Repair backs up first, then salvages and renumbers
+	if report, err = Fsck(true); err != nil {
+		t.Fatalf("Fsck(repair) failed: %v", err)
+	}
+	if report.Backup == "" {
+		t.Fatal("expected a pre-repair backup")
+	}
+	if _, contents, err := readBackup(filepath.Join(dir, backupDirName, report.Backup)); err != nil || string(contents["feeds/2026-04.json"]) != feeds {
+		t.Errorf("pre-repair backup does not hold the original feeds/2026-04.json (%v)", err)
+	}
+	loaded, err := LoadFeeds()
+	if err != nil || len(loaded) != 2 {
+		t.Fatalf("LoadFeeds after repair = %+v, %v", loaded, err)
+	}
+	if loaded[0].ID == loaded[1].ID || loaded[0].UID == loaded[1].UID {
+		t.Errorf("duplicates survived the repair: %+v", loaded)
+	}
+	if loaded[1].Quantity != -5 {
+		t.Errorf("repair must not change values, quantity is %g", loaded[1].Quantity)
+	}
+	if entries, err := LoadAudit(models.AuditFilter{}); err != nil || len(entries) != 1 {
+		t.Errorf("LoadAudit after repair = %d entries, %v", len(entries), err)
+	}
+
+	// Only the value problem is left, and there is nothing more to repair
+	if report, err = Fsck(true); err != nil || report.Backup != "" {
+		t.Errorf("second repair = %+v, %v; want no backup", report, err)
+	}
+}
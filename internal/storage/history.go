@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+// LoadHistory returns every revision of an entry, oldest first, with the fields
+// each one changed. Deleted entries keep their history. Empty if the entry is
+// unknown. The entry and its audit trail are read under sm.mu, so no write can
+// land between the two.
+func LoadHistory(resource string, id int) ([]models.Revision, error) {
+	if !isEntryResource(resource) {
+		return nil, fmt.Errorf("unknown resource %q", resource)
+	}
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	current, _, err := findEntry[json.RawMessage](sm, resource, id)
+	if err != nil {
+		return nil, err
+	}
+	trail, err := sm.loadAudit(models.AuditFilter{Resource: resource, EntityID: id})
+	if err != nil {
+		return nil, err
+	}
+	return models.Revisions(trail, current), nil
+}
+
+// LoadAsOf reconstructs a resource's entries as they were at instant t by
+// replaying the audit log, in ID (logging) order. An entry with no record of
+// its creation is only known to exist from the log's first record on, so
+// before the log begins (or, with no log, before now) there are none. The
+// entries and the log are read under sm.mu.
+func LoadAsOf[T any](resource string, t time.Time) ([]T, error) {
+	if !isEntryResource(resource) {
+		return nil, fmt.Errorf("unknown resource %q", resource)
+	}
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	items, err := loadEntries(sm, resource, "", "", rawKey)
+	if err != nil {
+		sm.mu.Unlock()
+		return nil, err
+	}
+	audit, err := sm.loadAudit(models.AuditFilter{Resource: resource})
+	if err != nil {
+		sm.mu.Unlock()
+		return nil, err
+	}
+	since, err := sm.auditStart()
+	sm.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[int]json.RawMessage, len(items))
+	for _, raw := range items {
+		id, _ := rawKey(raw)
+		current[id] = raw
+	}
+	trails := map[int][]models.AuditEntry{}
+	for _, e := range audit {
+		trails[e.EntityID] = append(trails[e.EntityID], e)
+	}
+	ids := make([]int, 0, len(current)+len(trails))
+	for id := range current {
+		ids = append(ids, id)
+	}
+	for id := range trails {
+		if _, ok := current[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	entries := []T{}
+	for _, id := range ids {
+		raw := models.StateAt(trails[id], current[id], t, since)
+		if raw == nil {
+			continue
+		}
+		var entry T
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse %s %d from the audit log: %w", resource, id, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+func TestLoadHistoryAndAsOf(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+	ctx := WithActor(context.Background(), Actor{Name: "Asha"})
+
+	if err := SaveDiaper(ctx, &models.DiaperEntry{Date: "2026-04-06", Type: "Wet"}); err != nil {
+		t.Fatalf("SaveDiaper failed: %v", err)
+	}
+	afterCreate := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := UpdateDiaper(ctx, 1, 0, &models.DiaperEntry{Date: "2026-04-06", Type: "Dirty"}); err != nil {
+		t.Fatalf("UpdateDiaper failed: %v", err)
+	}
+	if err := SaveDiaper(ctx, &models.DiaperEntry{Date: "2026-04-06", Type: "Mixed"}); err != nil {
+		t.Fatalf("SaveDiaper failed: %v", err)
+	}
+	afterSecond := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := DeleteDiaper(ctx, 2, 0); err != nil {
+		t.Fatalf("DeleteDiaper failed: %v", err)
+	}
+
+	revs, err := LoadHistory(models.ResourceDiapers, 1)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(revs) != 2 || revs[1].Action != models.AuditUpdate || revs[1].Changes[0].Field != "type" {
+		t.Errorf("unexpected history: %+v", revs)
+	}
+	if revs, _ := LoadHistory(models.ResourceDiapers, 2); len(revs) != 2 || revs[1].Action != models.AuditDelete {
+		t.Errorf("deleted entry should keep its history, got %+v", revs)
+	}
+
+	then, err := LoadAsOf[models.DiaperEntry](models.ResourceDiapers, afterCreate)
+	if err != nil {
+		t.Fatalf("LoadAsOf failed: %v", err)
+	}
+	if len(then) != 1 || then[0].Type != "Wet" {
+		t.Errorf("as of after the create = %+v, want the original Wet entry", then)
+	}
+	then, _ = LoadAsOf[models.DiaperEntry](models.ResourceDiapers, afterSecond)
+	if len(then) != 2 || then[0].Type != "Dirty" || then[1].ID != 2 {
+		t.Errorf("as of before the delete = %+v, want both entries", then)
+	}
+	if then, _ := LoadAsOf[models.DiaperEntry](models.ResourceDiapers, afterCreate.Add(-time.Hour)); len(then) != 0 {
+		t.Errorf("as of before any entry = %+v, want none", then)
+	}
+}
+
+func TestLoadAsOfBeforeTheAuditLog(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+	ctx := context.Background()
+
+	// Two entries logged before there was an audit log
+	legacy := []models.DiaperEntry{{ID: 1, Date: "2026-04-06", Type: "Wet"}, {ID: 2, Date: "2026-04-06", Type: "Dirty"}}
+	if err := saveJSON(sm, "diapers/2026-04.json", legacy); err != nil {
+		t.Fatal(err)
+	}
+	beforeLog := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := DeleteDiaper(ctx, 2, 0); err != nil {
+		t.Fatalf("DeleteDiaper failed: %v", err)
+	}
+	afterDelete := time.Now()
+
+	if then, err := LoadAsOf[models.DiaperEntry](models.ResourceDiapers, beforeLog); err != nil || len(then) != 0 {
+		t.Errorf("as of before the audit log = %+v, %v; want none", then, err)
+	}
+	if then, _ := LoadAsOf[models.DiaperEntry](models.ResourceDiapers, afterDelete); len(then) != 1 || then[0].ID != 1 {
+		t.Errorf("as of after the delete = %+v, want entry 1", then)
+	}
+	if revs, err := LoadHistory(models.ResourceDiapers, 1); err != nil || len(revs) != 1 || revs[0].Action != models.RevisionInitial {
+		t.Errorf("LoadHistory of an entry with no trail = %+v, %v", revs, err)
+	}
+}
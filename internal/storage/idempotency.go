@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+// idempotencyFileName holds the keys of client-keyed writes already applied.
+const idempotencyFileName = "idempotency.json"
+
+// ErrKeyReused is returned when an idempotency key is sent again with a
+// different operation than the one it was first used for.
+var ErrKeyReused = errors.New("idempotency key was already used for a different operation")
+
+// idempotencyKeyCtx is the context key of the request key set by WithIdempotencyKey.
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey returns ctx carrying rec, the Idempotency-Key of the
+// request being served. Entry creates, updates and restores, token creates,
+// batches and imports made with it record the key in the same journaled write
+// as their changes, so a crash between the two can't let a retry apply them
+// again. The response is added afterwards by SaveIdempotencyKey.
+func WithIdempotencyKey(ctx context.Context, rec models.IdempotencyKey) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, rec)
+}
+
+// sameKey reports whether k is the record of key sent by actor.
+func sameKey(k models.IdempotencyKey, actor, key string) bool {
+	return k.Key == key && k.Actor == actor
+}
+
+// findKey returns the record of an idempotency key sent by actor. The caller holds sm.mu.
+func (sm *StorageManager) findKey(actor, key string) (models.IdempotencyKey, bool, error) {
+	keys, err := loadJSON[models.IdempotencyKey](sm, idempotencyFileName)
+	if err != nil {
+		return models.IdempotencyKey{}, false, err
+	}
+	for _, k := range keys {
+		if sameKey(k, actor, key) {
+			return k, true, nil
+		}
+	}
+	return models.IdempotencyKey{}, false, nil
+}
+
+// rememberKey records an applied idempotency key. The caller holds sm.mu.
+func (sm *StorageManager) rememberKey(rec models.IdempotencyKey) error {
+	keys, err := loadJSON[models.IdempotencyKey](sm, idempotencyFileName)
+	if err != nil {
+		return fmt.Errorf("refusing to save over unreadable %s: %w", idempotencyFileName, err)
+	}
+	rec.Time = time.Now().UTC()
+	keys = append(keys, rec)
+	return saveJSON(sm, idempotencyFileName, keys)
+}
+
+// rememberRequest records the Idempotency-Key ctx carries, if any, as having
+// applied action to an entry of resource. The caller holds sm.mu and has a
+// batch running, so the key lands with the write or not at all.
+func (sm *StorageManager) rememberRequest(ctx context.Context, action, resource string, id int) error {
+	rec, ok := ctx.Value(idempotencyKeyCtx{}).(models.IdempotencyKey)
+	if !ok {
+		return nil
+	}
+	if _, found, err := sm.findKey(rec.Actor, rec.Key); err != nil {
+		return err
+	} else if found {
+		return fmt.Errorf("%w: %q", ErrKeyReused, rec.Key)
+	}
+	rec.Action, rec.Resource, rec.ID = action, resource, id
+	return sm.rememberKey(rec)
+}
+
+// keyed runs write, which applies action to an entry of resource and returns
+// its ID. When ctx carries an Idempotency-Key, write and the key's record run
+// as one batch. The caller holds sm.mu.
+func (sm *StorageManager) keyed(ctx context.Context, action, resource string, write func() (int, error)) error {
+	if _, ok := ctx.Value(idempotencyKeyCtx{}).(models.IdempotencyKey); !ok {
+		_, err := write()
+		return err
+	}
+	if sm.closed {
+		return ErrClosed
+	}
+	if err := sm.beginBatch(); err != nil {
+		return err
+	}
+	id, err := write()
+	if err == nil {
+		err = sm.rememberRequest(ctx, action, resource, id)
+	}
+	if err != nil {
+		if rerr := sm.recoverBatch(); rerr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rerr)
+		}
+		return err
+	}
+	return sm.endBatch()
+}
+
+// LoadIdempotencyKey returns the record of an earlier write made with key by actor.
+func LoadIdempotencyKey(actor, key string) (models.IdempotencyKey, bool, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return models.IdempotencyKey{}, false, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.findKey(actor, key)
+}
+
+// SaveIdempotencyKey records the response to a request sent with an
+// Idempotency-Key header: it completes the record the request's write made
+// (see WithIdempotencyKey), or adds one for a request that wrote nothing
+// keyed. A key already recorded with a response, or for another request, is
+// ErrKeyReused.
+func SaveIdempotencyKey(rec models.IdempotencyKey) error {
+	sm, err := getStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	keys, err := loadJSON[models.IdempotencyKey](sm, idempotencyFileName)
+	if err != nil {
+		return fmt.Errorf("refusing to save over unreadable %s: %w", idempotencyFileName, err)
+	}
+	for i, k := range keys {
+		if !sameKey(k, rec.Actor, rec.Key) {
+			continue
+		}
+		if k.Status != 0 || k.Request != rec.Request || k.Digest != rec.Digest {
+			return fmt.Errorf("%w: %q", ErrKeyReused, rec.Key)
+		}
+		keys[i].Status, keys[i].Response, keys[i].Withheld = rec.Status, rec.Response, rec.Withheld
+		return saveJSON(sm, idempotencyFileName, keys)
+	}
+	return sm.rememberKey(rec)
+}
+
+// PurgeIdempotencyKeys forgets keys recorded longer ago than retention, returning
+// how many were dropped. A retried write after that is applied again. A zero
+// retention keeps everything.
+func PurgeIdempotencyKeys(retention time.Duration) (int, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	sm, err := getStorage()
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	keys, err := loadJSON[models.IdempotencyKey](sm, idempotencyFileName)
+	if err != nil {
+		return 0, fmt.Errorf("refusing to purge unreadable %s: %w", idempotencyFileName, err)
+	}
+	cutoff := time.Now().Add(-retention)
+	kept := make([]models.IdempotencyKey, 0, len(keys))
+	for _, k := range keys {
+		if !k.Time.Before(cutoff) {
+			kept = append(kept, k)
+		}
+	}
+	if len(kept) == len(keys) {
+		return 0, nil
+	}
+	return len(keys) - len(kept), saveJSON(sm, idempotencyFileName, kept)
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+
+	rec := models.IdempotencyKey{Key: "k1", Actor: "user:1", Request: "POST /api/feeds", Status: 201, Response: []byte(`{"id":1}`)}
+	if err := SaveIdempotencyKey(rec); err != nil {
+		t.Fatalf("SaveIdempotencyKey failed: %v", err)
+	}
+	if err := SaveIdempotencyKey(rec); !errors.Is(err, ErrKeyReused) {
+		t.Errorf("saving k1 twice: expected ErrKeyReused, got %v", err)
+	}
+	got, ok, err := LoadIdempotencyKey("user:1", "k1")
+	if err != nil || !ok || got.Status != 201 || !strings.Contains(string(got.Response), `"id": 1`) || got.Time.IsZero() {
+		t.Errorf("LoadIdempotencyKey(k1) = %+v, %v, %v", got, ok, err)
+	}
+	if _, ok, _ := LoadIdempotencyKey("user:1", "k2"); ok {
+		t.Error("expected k2 to be unknown")
+	}
+	// Keys are scoped by who sent them
+	if _, ok, _ := LoadIdempotencyKey("user:2", "k1"); ok {
+		t.Error("expected another user's k1 to be unknown")
+	}
+	other := rec
+	other.Actor = "user:2"
+	if err := SaveIdempotencyKey(other); err != nil {
+		t.Errorf("another user saving k1: %v", err)
+	}
+
+	if n, err := PurgeIdempotencyKeys(0); err != nil || n != 0 {
+		t.Errorf("PurgeIdempotencyKeys(0) = %d, %v; want 0 (keep forever)", n, err)
+	}
+	if n, err := PurgeIdempotencyKeys(time.Hour); err != nil || n != 0 {
+		t.Errorf("PurgeIdempotencyKeys(1h) = %d, %v; want 0 for a fresh key", n, err)
+	}
+	time.Sleep(time.Millisecond)
+	if n, err := PurgeIdempotencyKeys(time.Nanosecond); err != nil || n != 2 {
+		t.Errorf("PurgeIdempotencyKeys(1ns) = %d, %v; want 2", n, err)
+	}
+	if _, ok, _ := LoadIdempotencyKey("user:1", "k1"); ok {
+		t.Error("expected k1 to be forgotten after purge")
+	}
+}
+
+func TestKeyedWrite(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+
+	rec := models.IdempotencyKey{Key: "k1", Actor: "user:1", Request: "POST /api/feeds", Digest: "abc"}
+	ctx := WithIdempotencyKey(context.Background(), rec)
+
+	// A write that fails leaves the key free
+	if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, UID: "not-a-ulid"}); !errors.Is(err, ErrInvalidUID) {
+		t.Fatalf("expected ErrInvalidUID, got %v", err)
+	}
+	if _, ok, _ := LoadIdempotencyKey("user:1", "k1"); ok {
+		t.Error("a failed write recorded its key")
+	}
+
+	// The key is recorded with the entry, before any response
+	feed := &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Quantity: 90}
+	if err := SaveFeed(ctx, feed); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	got, ok, err := LoadIdempotencyKey("user:1", "k1")
+	if err != nil || !ok || got.Action != models.AuditCreate || got.Resource != models.ResourceFeeds || got.ID != feed.ID || got.Status != 0 {
+		t.Fatalf("key after the write = %+v, %v, %v", got, ok, err)
+	}
+	if _, err := os.Stat(filepath.Join(sm.dataDir, batchJournalFileName)); !os.IsNotExist(err) {
+		t.Errorf("journal left behind: %v", err)
+	}
+	if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle}); !errors.Is(err, ErrKeyReused) {
+		t.Errorf("second write with the key: expected ErrKeyReused, got %v", err)
+	}
+	if feeds, _ := LoadFeeds(); len(feeds) != 1 {
+		t.Errorf("expected one feed, got %d", len(feeds))
+	}
+
+	// The response completes the record; a different request can't
+	bad := rec
+	bad.Digest = "def"
+	if err := SaveIdempotencyKey(bad); !errors.Is(err, ErrKeyReused) {
+		t.Errorf("completing with another body: expected ErrKeyReused, got %v", err)
+	}
+	rec.Status, rec.Response = 201, []byte(`{"id":1}`)
+	if err := SaveIdempotencyKey(rec); err != nil {
+		t.Fatalf("SaveIdempotencyKey failed: %v", err)
+	}
+	if got, _, _ := LoadIdempotencyKey("user:1", "k1"); got.Status != 201 || got.ID != feed.ID {
+		t.Errorf("completed key = %+v", got)
+	}
+	if err := SaveIdempotencyKey(rec); !errors.Is(err, ErrKeyReused) {
+		t.Errorf("completing twice: expected ErrKeyReused, got %v", err)
+	}
+}
@@ -0,0 +1,474 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+// Entries are stored one file per resource and calendar month of their date,
+// e.g. feeds/2026-10.json, so logging a feed rewrites this month's file rather
+// than the whole history. Each resource directory also holds index.json, which
+// maps every live entry's ID to its month and every UID, trashed entries'
+// included, to its ID, and holds the next ID to give out: an ID is never given
+// twice, even once its entry is purged and only its audit trail is left, an
+// entry is found by ID without opening the other months, and a UID is checked
+// or resolved without opening any.
+//
+// A write that touches a month and the index orders the two so that a crash
+// in between leaves, at worst, an index ID with no entry (skipped by readers)
+// or a stale copy of a moved entry in a month the index doesn't point it at
+// (ignored by readers). fsck reports and removes both.
+
+const (
+	partitionIndexName = "index.json"
+	undatedPartition   = "undated" // Entries whose date isn't YYYY-MM-DD
+)
+
+// legacyEntryFiles are the single files each resource's entries were kept in
+// before schema 2 split them by month. Only migrations and fsck read them.
+var legacyEntryFiles = map[string]string{
+	models.ResourceFeeds:   "feeds.json",
+	models.ResourceSleep:   "sleep.json",
+	models.ResourceGrowth:  "growth.json",
+	models.ResourceDiapers: "diapers.json",
+}
+
+// isEntryResource reports whether resource names an entry type (and so a partition directory).
+func isEntryResource(resource string) bool {
+	_, ok := legacyEntryFiles[resource]
+	return ok
+}
+
+// partitionOf returns the month partition an entry dated date belongs in.
+func partitionOf(date string) string {
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		return undatedPartition
+	}
+	return date[:7]
+}
+
+// partitionFile is the data file name of one month of a resource, e.g. feeds/2026-10.json.
+func partitionFile(resource, part string) string {
+	return resource + "/" + part + ".json"
+}
+
+// indexFile is the data file name of a resource's partition index.
+func indexFile(resource string) string {
+	return resource + "/" + partitionIndexName
+}
+
+// Key functions give the ID and date of each entry type.
+func feedKey(e models.FeedEntry) (int, string)     { return e.ID, e.Date }
+func sleepKey(e models.SleepEntry) (int, string)   { return e.ID, e.Date }
+func growthKey(e models.GrowthEntry) (int, string) { return e.ID, e.Date }
+func diaperKey(e models.DiaperEntry) (int, string) { return e.ID, e.Date }
+
+// rawKey reads the ID and date of an undecoded entry. One whose ID can't be
+// read gets 0, which no index holds.
+func rawKey(raw json.RawMessage) (int, string) {
+	var key struct {
+		ID   int    `json:"id"`
+		Date string `json:"date"`
+	}
+	json.Unmarshal(raw, &key)
+	return key.ID, key.Date
+}
+
+// rawUID reads the UID of an undecoded entry, "" if it has none.
+func rawUID(raw json.RawMessage) string {
+	var key struct {
+		UID string `json:"uid"`
+	}
+	json.Unmarshal(raw, &key)
+	return key.UID
+}
+
+// partitionIndex is a resource's index.json.
+type partitionIndex struct {
+	NextID int            `json:"next_id"` // Above every ID ever given out, purged ones included
+	Months map[int]string `json:"months"`  // Live entry ID -> the month partition holding it
+	UIDs   map[string]int `json:"uids"`    // UID -> ID of every entry, live or trashed
+}
+
+// newPartitionIndex returns an empty index.
+func newPartitionIndex() *partitionIndex {
+	return &partitionIndex{Months: map[int]string{}, UIDs: map[string]int{}}
+}
+
+// newID gives out the next ID.
+func (idx *partitionIndex) newID() int {
+	id := max(idx.NextID, nextID(idx.ids()))
+	idx.NextID = id + 1
+	return id
+}
+
+// ids returns the indexed IDs.
+func (idx *partitionIndex) ids() []int {
+	ids := make([]int, 0, len(idx.Months))
+	for id := range idx.Months {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// partitions returns the months holding entries, oldest first, limited to
+// those that can hold dates in the inclusive YYYY-MM-DD range from..to ("" leaves
+// an end open). Undated entries are only included when the range is open.
+func (idx *partitionIndex) partitions(from, to string) []string {
+	seen := map[string]bool{}
+	var parts []string
+	for _, part := range idx.Months {
+		if seen[part] {
+			continue
+		}
+		seen[part] = true
+		if part == undatedPartition {
+			if from != "" || to != "" {
+				continue
+			}
+		} else if (from != "" && part < partitionOf(from)) || (to != "" && part > partitionOf(to)) {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+	return parts
+}
+
+// loadIndex returns a resource's partition index. Without an index file (a new
+// directory, or one fsck hasn't repaired yet) it is rebuilt from the
+// partitions, the trash and the audit log; one missing its next ID, months or
+// UIDs was cut short and is an error, for fsck to repair.
+func (sm *StorageManager) loadIndex(resource string) (*partitionIndex, error) {
+	name := indexFile(resource)
+	data, err := sm.readData(name)
+	if os.IsNotExist(err) {
+		return sm.rebuildIndex(resource)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	idx := &partitionIndex{}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("failed to parse %s (the fsck command can salvage it): %w", name, err)
+	}
+	if idx.NextID == 0 || idx.Months == nil || idx.UIDs == nil {
+		return nil, fmt.Errorf("failed to parse %s (the fsck command can salvage it): incomplete index", name)
+	}
+	return idx, nil
+}
+
+// saveIndex writes a resource's partition index.
+func (sm *StorageManager) saveIndex(resource string, idx *partitionIndex) error {
+	name := indexFile(resource)
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	return sm.writeData(name, data)
+}
+
+// partitionFiles lists the months a resource has files for, oldest first.
+func (sm *StorageManager) partitionFiles(resource string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(sm.dataDir, resource))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", resource, err)
+	}
+	var parts []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || name == partitionIndexName || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		parts = append(parts, strings.TrimSuffix(name, ".json"))
+	}
+	return parts, nil
+}
+
+// rebuildIndex indexes every entry in a resource's partitions, and the UIDs of
+// those in its trash. An ID found in two months is given the later one.
+func (sm *StorageManager) rebuildIndex(resource string) (*partitionIndex, error) {
+	parts, err := sm.partitionFiles(resource)
+	if err != nil {
+		return nil, err
+	}
+	idx := newPartitionIndex()
+	for _, part := range parts {
+		entries, err := loadJSON[json.RawMessage](sm, partitionFile(resource, part))
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range entries {
+			if id, _ := rawKey(raw); id > 0 {
+				idx.Months[id] = part
+				if uid := rawUID(raw); uid != "" {
+					idx.UIDs[uid] = id
+				}
+			}
+		}
+	}
+	if err := sm.indexTrash(resource, idx, len(parts) > 0); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// indexTrash adds the UIDs of a resource's trashed entries to an index of its
+// live ones and sets the next ID above every ID live, trashed or in the audit
+// log, which keeps those of purged entries. The log is only read when the
+// resource has entry files or trash. The caller holds sm.mu.
+func (sm *StorageManager) indexTrash(resource string, idx *partitionIndex, hasFiles bool) error {
+	trash, err := loadJSON[models.TrashItem](sm, trashFileName(resource))
+	if err != nil {
+		return err
+	}
+	last := 0
+	for _, t := range trash {
+		if uid := rawUID(t.Entry); uid != "" {
+			idx.UIDs[uid] = t.ID
+		}
+		last = max(last, t.ID)
+	}
+	if hasFiles || len(trash) > 0 {
+		audited, err := sm.lastAuditedID(resource)
+		if err != nil {
+			return err
+		}
+		last = max(last, audited)
+	}
+	idx.NextID = max(last+1, nextID(idx.ids()))
+	return nil
+}
+
+// lastAuditedID returns the highest entry ID of a resource in the audit log,
+// 0 if there is none. The caller holds sm.mu.
+func (sm *StorageManager) lastAuditedID(resource string) (int, error) {
+	records, err := sm.loadAudit(models.AuditFilter{Resource: resource})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find %s's highest ID: %w", resource, err)
+	}
+	last := 0
+	for _, r := range records {
+		last = max(last, r.EntityID)
+	}
+	return last, nil
+}
+
+// loadEntries returns a resource's entries in ID (logging) order, reading only
+// the months that can hold dates in the inclusive YYYY-MM-DD range from..to.
+// The range picks files; callers still filter the entries by date. The caller
+// holds sm.mu: the index and the months must be read as one.
+func loadEntries[T any](sm *StorageManager, resource, from, to string, keyOf func(T) (int, string)) ([]T, error) {
+	idx, err := sm.loadIndex(resource)
+	if err != nil {
+		return nil, err
+	}
+	entries := []T{}
+	for _, part := range idx.partitions(from, to) {
+		items, err := loadJSON[T](sm, partitionFile(resource, part))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range items {
+			if id, _ := keyOf(e); idx.Months[id] == part {
+				entries = append(entries, e)
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, _ := keyOf(entries[i])
+		b, _ := keyOf(entries[j])
+		return a < b
+	})
+	return entries, nil
+}
+
+// locateEntry loads a resource's index and the month the index puts an entry
+// in, and returns the entry's position there, -1 if there is no such entry.
+// The caller holds sm.mu.
+func locateEntry[T any](sm *StorageManager, resource string, id int, keyOf func(T) (int, string)) (*partitionIndex, []T, int, error) {
+	idx, err := sm.loadIndex(resource)
+	if err != nil {
+		return nil, nil, -1, err
+	}
+	part, ok := idx.Months[id]
+	if !ok {
+		return idx, nil, -1, nil
+	}
+	entries, err := loadJSON[T](sm, partitionFile(resource, part))
+	if err != nil {
+		return nil, nil, -1, err
+	}
+	i := slices.IndexFunc(entries, func(e T) bool {
+		eid, _ := keyOf(e)
+		return eid == id
+	})
+	return idx, entries, i, nil
+}
+
+// placeOf returns where an entry with the given ID goes among a month's
+// entries, which are kept newest (highest ID) first so that the recent ones a
+// page or lookup usually wants are read first.
+func placeOf[T any](entries []T, id int, keyOf func(T) (int, string)) int {
+	for i, e := range entries {
+		if eid, _ := keyOf(e); eid < id {
+			return i
+		}
+	}
+	return len(entries)
+}
+
+// insertEntry adds an entry to the month of its date, at its place (see
+// placeOf), and to the index. The index is saved first, so a crash in between
+// leaves an ID with no entry rather than an entry the index doesn't know. The
+// caller holds sm.mu.
+func insertEntry[T any](sm *StorageManager, resource string, idx *partitionIndex, entry T, keyOf func(T) (int, string)) error {
+	id, date := keyOf(entry)
+	part := partitionOf(date)
+	name := partitionFile(resource, part)
+	entries, err := loadJSON[T](sm, name)
+	if err != nil {
+		return fmt.Errorf("refusing to save over unreadable data file: %w", err)
+	}
+	entries = slices.Insert(entries, placeOf(entries, id, keyOf), entry)
+	idx.Months[id] = part
+	if err := sm.saveIndex(resource, idx); err != nil {
+		return err
+	}
+	return saveJSON(sm, name, entries)
+}
+
+// storeEntry replaces entries[i], an entry's copy in the month the index puts
+// it in, with entry. If entry's date falls in another month it moves: the new
+// month is written, then the index, then the old month, so a crash leaves the
+// index pointing at one whole copy. The caller holds sm.mu.
+func storeEntry[T any](sm *StorageManager, resource string, idx *partitionIndex, entries []T, i int, entry T, keyOf func(T) (int, string)) error {
+	id, date := keyOf(entry)
+	from := idx.Months[id]
+	if partitionOf(date) == from {
+		entries[i] = entry
+		return saveJSON(sm, partitionFile(resource, from), entries)
+	}
+	to := partitionOf(date)
+	moved, err := loadJSON[T](sm, partitionFile(resource, to))
+	if err != nil {
+		return fmt.Errorf("refusing to move an entry into unreadable data file: %w", err)
+	}
+	if err := saveJSON(sm, partitionFile(resource, to), slices.Insert(moved, placeOf(moved, id, keyOf), entry)); err != nil {
+		return err
+	}
+	idx.Months[id] = to
+	if err := sm.saveIndex(resource, idx); err != nil {
+		return err
+	}
+	return saveJSON(sm, partitionFile(resource, from), slices.Delete(entries, i, i+1))
+}
+
+// dropEntry removes entries[i] from its month, then from the index's months.
+// Its UID stays indexed, as the entry goes to the trash. The caller holds sm.mu.
+func dropEntry[T any](sm *StorageManager, resource string, idx *partitionIndex, entries []T, i int, keyOf func(T) (int, string)) error {
+	id, _ := keyOf(entries[i])
+	part := idx.Months[id]
+	if err := saveJSON(sm, partitionFile(resource, part), slices.Delete(entries, i, i+1)); err != nil {
+		return err
+	}
+	delete(idx.Months, id)
+	return sm.saveIndex(resource, idx)
+}
+
+// partitionEntries is schema migration 2. It splits each resource's single
+// entry file into month partitions and an index of their months, every UID,
+// trashed entries' included, and the next ID, then removes the single file,
+// and returns how many entries it moved. Run again after a crash, it rebuilds
+// the partitions from the single file, which is only removed at the end.
+// The caller holds sm.mu.
+func (sm *StorageManager) partitionEntries() (int, error) {
+	moved := 0
+	for _, resource := range models.AllResources {
+		legacy := legacyEntryFiles[resource]
+		if _, err := os.Stat(filepath.Join(sm.dataDir, legacy)); os.IsNotExist(err) {
+			continue
+		}
+		entries, err := loadJSON[json.RawMessage](sm, legacy)
+		if err != nil {
+			return moved, err
+		}
+		// Anything already here is from a run that was cut short
+		if err := os.RemoveAll(filepath.Join(sm.dataDir, resource)); err != nil {
+			return moved, fmt.Errorf("failed to clear %s: %w", resource, err)
+		}
+		months := map[string][]json.RawMessage{}
+		idx := newPartitionIndex()
+		for _, raw := range entries {
+			id, date := rawKey(raw)
+			part := partitionOf(date)
+			months[part] = append(months[part], raw)
+			idx.Months[id] = part
+			if uid := rawUID(raw); uid != "" {
+				idx.UIDs[uid] = id
+			}
+		}
+		if err := sm.indexTrash(resource, idx, true); err != nil {
+			return moved, err
+		}
+		for part, items := range months {
+			sort.SliceStable(items, func(i, j int) bool { // Newest first, see placeOf
+				a, _ := rawKey(items[i])
+				b, _ := rawKey(items[j])
+				return a > b
+			})
+			if err := saveJSON(sm, partitionFile(resource, part), items); err != nil {
+				return moved, err
+			}
+		}
+		if err := sm.saveIndex(resource, idx); err != nil {
+			return moved, err
+		}
+		if err := os.Remove(filepath.Join(sm.dataDir, legacy)); err != nil {
+			return moved, fmt.Errorf("failed to remove %s: %w", legacy, err)
+		}
+		moved += len(entries)
+	}
+	return moved, nil
+}
+
+// listDataFiles returns the regular files of a data directory, its top level
+// and the entry partitions, as slash-separated names relative to it, sorted.
+func listDataFiles(dir string) ([]string, error) {
+	top, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data directory: %w", err)
+	}
+	var names []string
+	for _, e := range top {
+		if e.Type().IsRegular() {
+			names = append(names, e.Name())
+			continue
+		}
+		if !e.IsDir() || !isEntryResource(e.Name()) {
+			continue
+		}
+		sub, err := os.ReadDir(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", e.Name(), err)
+		}
+		for _, f := range sub {
+			if f.Type().IsRegular() {
+				names = append(names, e.Name()+"/"+f.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"babytracker/internal/models"
+)
+
+func feedIDs(feeds []models.FeedEntry) string {
+	var ids []int
+	for _, f := range feeds {
+		ids = append(ids, f.ID)
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestPartitionedEntries(t *testing.T) {
+	dir := setupBackupStorage(t)
+	ctx := context.Background()
+	for _, date := range []string{"2026-04-30", "2026-05-01", "2026-04-29"} {
+		if err := SaveFeed(ctx, &models.FeedEntry{Date: date, Type: models.FeedTypeBottle}); err != nil {
+			t.Fatalf("SaveFeed failed: %v", err)
+		}
+	}
+	sm, _ := getStorage()
+	april, _ := loadJSON[models.FeedEntry](sm, "feeds/2026-04.json")
+	may, _ := loadJSON[models.FeedEntry](sm, "feeds/2026-05.json")
+	if feedIDs(april) != "[3 1]" || feedIDs(may) != "[2]" {
+		t.Errorf("April holds %s and May %s; want [3 1] and [2], newest first", feedIDs(april), feedIDs(may))
+	}
+	if feeds, _ := LoadFeeds(); feedIDs(feeds) != "[1 2 3]" {
+		t.Errorf("LoadFeeds = %s, want ID order", feedIDs(feeds))
+	}
+
+	// Changing the date moves the entry, and the index with it
+	moved := &models.FeedEntry{Date: "2026-05-02", Type: models.FeedTypeBottle}
+	if err := UpdateFeed(ctx, 1, 0, moved); err != nil {
+		t.Fatalf("UpdateFeed failed: %v", err)
+	}
+	april, _ = loadJSON[models.FeedEntry](sm, "feeds/2026-04.json")
+	may, _ = loadJSON[models.FeedEntry](sm, "feeds/2026-05.json")
+	if feedIDs(april) != "[3]" || feedIDs(may) != "[2 1]" {
+		t.Errorf("after the move April holds %s and May %s; want [3] and [2 1]", feedIDs(april), feedIDs(may))
+	}
+	if idx, _ := sm.loadIndex(models.ResourceFeeds); idx.Months[1] != "2026-05" {
+		t.Errorf("index has entry 1 in %q, want 2026-05", idx.Months[1])
+	}
+	if f, found, err := FindEntry[models.FeedEntry](models.ResourceFeeds, 1); err != nil || !found || f.Date != "2026-05-02" {
+		t.Errorf("FindEntry(1) = %+v, %v, %v", f, found, err)
+	}
+
+	// Deleting frees nothing: the next ID still follows the trashed one
+	if err := DeleteFeed(ctx, 3, 0); err != nil {
+		t.Fatalf("DeleteFeed failed: %v", err)
+	}
+	if idx, _ := sm.loadIndex(models.ResourceFeeds); len(idx.Months) != 2 {
+		t.Errorf("index after delete = %v", idx)
+	}
+	feed := &models.FeedEntry{Date: "2026-04-29", Type: models.FeedTypeBottle}
+	if err := SaveFeed(ctx, feed); err != nil || feed.ID != 4 {
+		t.Errorf("SaveFeed after delete gave ID %d, %v; want 4", feed.ID, err)
+	}
+
+	// Only the months a date range reaches are read
+	if err := os.WriteFile(filepath.Join(dir, "feeds", "2026-05.json"), []byte(`[{"id": 2, "da`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	page, total, err := QueryEntries[models.FeedEntry](models.ResourceFeeds, models.EntryQuery{From: "2026-04-01", To: "2026-04-30"})
+	if err != nil || total != 1 || page[0].ID != 4 {
+		t.Errorf("April query = %+v, %d, %v", page, total, err)
+	}
+	if _, _, err := QueryEntries[models.FeedEntry](models.ResourceFeeds, models.EntryQuery{}); err == nil {
+		t.Error("expected the damaged May file to fail an open query")
+	}
+}
+
+func TestReadsDuringMove(t *testing.T) {
+	setupBackupStorage(t)
+	ctx := context.Background()
+	if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-30", Type: models.FeedTypeBottle}); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	// Move the entry back and forth between two months while reading it
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer close(done)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			date := []string{"2026-05-01", "2026-04-30"}[i%2]
+			if err := UpdateFeed(ctx, 1, 0, &models.FeedEntry{Date: date, Type: models.FeedTypeBottle}); err != nil {
+				t.Errorf("UpdateFeed failed: %v", err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 2000; i++ {
+		if _, found, err := FindEntry[models.FeedEntry](models.ResourceFeeds, 1); err != nil || !found {
+			t.Fatalf("FindEntry during a move = %v, %v", found, err)
+		}
+		if _, total, err := QueryEntries[models.FeedEntry](models.ResourceFeeds, models.EntryQuery{}); err != nil || total != 1 {
+			t.Fatalf("QueryEntries during a move = %d, %v", total, err)
+		}
+	}
+}
+
+func TestPartitionIndexRecovery(t *testing.T) {
+	dir := setupBackupStorage(t)
+	ctx := context.Background()
+	for _, date := range []string{"2026-04-06", "2026-05-06"} {
+		if err := SaveFeed(ctx, &models.FeedEntry{Date: date, Type: models.FeedTypeBottle}); err != nil {
+			t.Fatalf("SaveFeed failed: %v", err)
+		}
+	}
+	sm, _ := getStorage()
+
+	// A move cut short leaves a stale copy in the old month, which readers skip
+	stale := []models.FeedEntry{{ID: 1, Date: "2026-04-06"}, {ID: 2, Date: "2026-04-07"}}
+	if err := saveJSON(sm, "feeds/2026-04.json", stale); err != nil {
+		t.Fatal(err)
+	}
+	if feeds, _ := LoadFeeds(); feedIDs(feeds) != "[1 2]" || feeds[1].Date != "2026-05-06" {
+		t.Errorf("LoadFeeds with a stale copy = %+v", feeds)
+	}
+	report, err := Fsck(true)
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	var problems []string
+	for _, f := range report.Files {
+		problems = append(problems, f.Problems...)
+	}
+	if !strings.Contains(strings.Join(problems, "\n"), "stale copy") {
+		t.Errorf("fsck did not report the stale copy: %q", problems)
+	}
+	if april, _ := loadJSON[models.FeedEntry](sm, "feeds/2026-04.json"); feedIDs(april) != "[1]" {
+		t.Errorf("April after repair holds %s, want [1]", feedIDs(april))
+	}
+
+	// Without an index the partitions are scanned for one
+	if err := os.Remove(filepath.Join(dir, "feeds", "index.json")); err != nil {
+		t.Fatal(err)
+	}
+	if feeds, _ := LoadFeeds(); feedIDs(feeds) != "[1 2]" {
+		t.Errorf("LoadFeeds without an index = %s", feedIDs(feeds))
+	}
+	feed := &models.FeedEntry{Date: "2026-05-07", Type: models.FeedTypeBottle}
+	if err := SaveFeed(ctx, feed); err != nil || feed.ID != 3 {
+		t.Errorf("SaveFeed without an index gave ID %d, %v; want 3", feed.ID, err)
+	}
+
+	// A save cut short between the index and the month leaves an ID with no
+	// entry, which a page drops from the index when it meets it
+	idx, err := sm.loadIndex(models.ResourceFeeds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.Months[idx.newID()] = "2026-05"
+	if err := sm.saveIndex(models.ResourceFeeds, idx); err != nil {
+		t.Fatal(err)
+	}
+	page, total, err := QueryEntries[models.FeedEntry](models.ResourceFeeds, models.EntryQuery{Limit: 2})
+	if err != nil || feedIDs(page) != "[3 2]" || total != 3 {
+		t.Errorf("page with a dangling ID = %s of %d, %v; want [3 2] of 3", feedIDs(page), total, err)
+	}
+	if idx, _ := sm.loadIndex(models.ResourceFeeds); len(idx.Months) != 3 || idx.NextID != 5 {
+		t.Errorf("index after the page = %+v, want the dangling ID 4 dropped and not given out again", idx)
+	}
+}
+
+func TestPartitionEntriesMigration(t *testing.T) {
+	sm := setupTestStorage(t)
+	legacy := []models.SleepEntry{
+		{ID: 1, Date: "2026-03-31", Type: models.SleepTypeNight},
+		{ID: 2, Date: "2026-04-01", Type: models.SleepTypeNap},
+		{ID: 3, Date: "yesterday", Type: models.SleepTypeNap},
+	}
+	if err := saveJSON(sm, "sleep.json", legacy); err != nil {
+		t.Fatal(err)
+	}
+	// Left by a run that was cut short
+	if err := saveJSON(sm, "sleep/2026-03.json", legacy[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := sm.partitionEntries(); err != nil || n != 3 {
+		t.Fatalf("partitionEntries() = %d, %v; want 3", n, err)
+	}
+	for name, want := range map[string]string{"sleep/2026-03.json": "[1]", "sleep/2026-04.json": "[2]", "sleep/undated.json": "[3]"} {
+		items, err := loadJSON[models.SleepEntry](sm, name)
+		var ids []int
+		for _, e := range items {
+			ids = append(ids, e.ID)
+		}
+		if err != nil || fmt.Sprint(ids) != want {
+			t.Errorf("%s holds %v (%v), want %s", name, ids, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(sm.dataDir, "sleep.json")); !os.IsNotExist(err) {
+		t.Errorf("expected sleep.json to be removed, got %v", err)
+	}
+	entries, err := loadEntries(sm, models.ResourceSleep, "", "", sleepKey)
+	if err != nil || len(entries) != 3 {
+		t.Errorf("loadEntries after migration = %+v, %v", entries, err)
+	}
+	// A bounded range leaves undated entries out
+	if entries, _ := loadEntries(sm, models.ResourceSleep, "2026-04-01", "", sleepKey); len(entries) != 1 || entries[0].ID != 2 {
+		t.Errorf("loadEntries from April = %+v", entries)
+	}
+	if n, err := sm.partitionEntries(); err != nil || n != 0 {
+		t.Errorf("second partitionEntries() = %d, %v; want 0", n, err)
+	}
+}
+
+func TestPartitionIndexComplete(t *testing.T) {
+	dir := setupBackupStorage(t)
+	sm, err := getStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	live, trashed := models.NewUID(), models.NewUID()
+	if err := saveJSON(sm, "diapers.json", []models.DiaperEntry{{ID: 2, UID: live, Date: "2026-04-06", Type: models.DiaperTypeWet}}); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(models.DiaperEntry{ID: 3, UID: trashed, Date: "2026-04-05", Type: models.DiaperTypeDirty})
+	if err := saveJSON(sm, "diapers.trash.json", []models.TrashItem{{Resource: models.ResourceDiapers, ID: 3, Entry: raw}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The migration writes the whole index, trashed UIDs and the next ID included
+	if n, err := sm.partitionEntries(); err != nil || n != 1 {
+		t.Fatalf("partitionEntries() = %d, %v; want 1", n, err)
+	}
+	var idx partitionIndex
+	data, _ := sm.readData("diapers/index.json")
+	if err := json.Unmarshal(data, &idx); err != nil || idx.NextID != 4 || idx.Months[2] != "2026-04" || idx.UIDs[live] != 2 || idx.UIDs[trashed] != 3 {
+		t.Fatalf("index after the migration = %+v, %v", idx, err)
+	}
+
+	// An index cut short is reported, not rebuilt behind fsck's back
+	for _, partial := range []string{`{"2": "2026-04"}`, `{"next_id": 4, "months": {"2": "2026-04"}}`} {
+		if err := os.WriteFile(filepath.Join(dir, "diapers", "index.json"), []byte(partial), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadDiapers(); err == nil || !strings.Contains(err.Error(), "fsck") {
+			t.Errorf("LoadDiapers with index %s: expected a parse error pointing at fsck, got %v", partial, err)
+		}
+	}
+	report, err := Fsck(true)
+	if err != nil {
+		t.Fatalf("Fsck(repair) failed: %v", err)
+	}
+	for _, f := range report.Files {
+		if f.File == "diapers/index.json" && len(f.Problems) == 0 {
+			t.Errorf("fsck found nothing wrong with the partial index")
+		}
+	}
+	if diapers, err := LoadDiapers(); err != nil || len(diapers) != 1 || diapers[0].ID != 2 {
+		t.Errorf("LoadDiapers after repair = %+v, %v", diapers, err)
+	}
+	if id, ok, err := ResolveUID(models.ResourceDiapers, trashed); err != nil || !ok || id != 3 {
+		t.Errorf("ResolveUID(trashed) after repair = %d, %v, %v; want 3", id, ok, err)
+	}
+}
@@ -0,0 +1,311 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"babytracker/internal/models"
+)
+
+// errStopStream ends streamJSON early without reporting an error.
+var errStopStream = errors.New("stop")
+
+// streamJSON decodes a data file's array one element at a time, handing each
+// to fn undecoded, so a caller can look at a few fields of every entry without
+// building all of them. fn returns errStopStream to end the scan early. A
+// plaintext file is read straight from disk; an encrypted one has to be
+// decrypted whole first. A missing file is an empty array.
+func streamJSON(sm *StorageManager, filename string, fn func(raw json.RawMessage) error) error {
+	var r io.Reader
+	f, err := os.Open(filepath.Join(sm.dataDir, filename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	defer f.Close()
+	br := bufio.NewReaderSize(f, 64<<10)
+	if head, _ := br.Peek(len(encMagic)); isEncrypted(head) {
+		data, err := sm.readData(filename)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		r = bytes.NewReader(data)
+	} else {
+		r = br
+	}
+
+	parseErr := func(err error) error {
+		return fmt.Errorf("failed to parse %s (the fsck command can salvage it): %w", filename, err)
+	}
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return parseErr(io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return parseErr(err)
+	}
+	if tok == nil {
+		return nil // saveJSON writes an empty slice that was never allocated as null
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return parseErr(fmt.Errorf("expected an array, found %v", tok))
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return parseErr(err)
+		}
+		if err := fn(raw); err == errStopStream {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return parseErr(err)
+	}
+	return nil
+}
+
+// QueryEntries returns a page of a resource's entries, newest (last logged)
+// first, and how many entries match in all. Everything is read under sm.mu, so
+// an entry moving between months isn't missed.
+//
+// An unfiltered page is picked from the index, which holds every ID and so
+// the total: only the months holding the page's entries are read, each, newest
+// first, only as far as the oldest of them (see pageFromIndex). A filtered query reads every month
+// its date range can reach, decoding only each entry's ID, date and caregiver
+// and holding only as many raw entries as the page needs; the page alone is
+// fully decoded.
+func QueryEntries[T any](resource string, q models.EntryQuery) ([]T, int, error) {
+	if !isEntryResource(resource) {
+		return nil, 0, fmt.Errorf("unknown resource %q", resource)
+	}
+	sm, err := getStorage()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	idx, err := sm.loadIndex(resource)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !q.Filtered() {
+		return pageFromIndex[T](sm, resource, idx, q.Offset, q.Limit)
+	}
+	// The newest Offset+Limit matches by ID, in a min-heap
+	window := 0
+	if q.Limit > 0 {
+		window = q.Offset + q.Limit
+	}
+	kept := &matchHeap{}
+	total := 0
+	for _, part := range idx.partitions(q.From, q.To) {
+		filename := partitionFile(resource, part)
+		err := streamJSON(sm, filename, func(raw json.RawMessage) error {
+			var key struct {
+				ID       int    `json:"id"`
+				Date     string `json:"date"`
+				LoggedBy string `json:"logged_by"`
+			}
+			if err := json.Unmarshal(raw, &key); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", filename, err)
+			}
+			if idx.Months[key.ID] != part || !q.Matches(key.Date, key.LoggedBy) {
+				return nil
+			}
+			total++
+			if window == 0 || kept.Len() < window {
+				heap.Push(kept, match{key.ID, raw})
+			} else if key.ID > (*kept)[0].id {
+				(*kept)[0] = match{key.ID, raw}
+				heap.Fix(kept, 0)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	newest := *kept
+	sort.Slice(newest, func(i, j int) bool { return newest[i].id > newest[j].id })
+	page := []T{}
+	for i := q.Offset; i < len(newest) && (q.Limit == 0 || len(page) < q.Limit); i++ {
+		var item T
+		if err := json.Unmarshal(newest[i].raw, &item); err != nil {
+			return nil, 0, fmt.Errorf("failed to parse %s entry %d: %w", resource, newest[i].id, err)
+		}
+		page = append(page, item)
+	}
+	return page, total, nil
+}
+
+// pageFromIndex returns the page of an unfiltered query: the index gives the
+// newest IDs and their months, and each month, kept newest first, is read only
+// until the page's entries in it are found, which for the first pages is its
+// head (an encrypted month is still decrypted whole, but decoded only that
+// far). An indexed ID a crash left without an entry is met when its month is
+// read to the end without it: it is dropped from the index's months, so
+// neither the page nor the total counts it, and the page is picked again (its
+// UID, which may belong to a trashed copy, is left to fsck). The caller holds
+// sm.mu.
+func pageFromIndex[T any](sm *StorageManager, resource string, idx *partitionIndex, offset, limit int) ([]T, int, error) {
+	for {
+		ids := idx.ids()
+		sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+		total := len(ids)
+		ids = ids[min(offset, total):]
+		if limit > 0 && len(ids) > limit {
+			ids = ids[:limit]
+		}
+		found, err := readIDs(sm, resource, idx, ids)
+		if err != nil {
+			return nil, 0, err
+		}
+		dangling := false
+		for _, id := range ids {
+			if _, ok := found[id]; !ok {
+				delete(idx.Months, id)
+				dangling = true
+			}
+		}
+		if dangling {
+			if err := sm.saveIndex(resource, idx); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		page := make([]T, 0, len(ids))
+		for _, id := range ids {
+			var item T
+			if err := json.Unmarshal(found[id], &item); err != nil {
+				return nil, 0, fmt.Errorf("failed to parse %s entry %d: %w", resource, id, err)
+			}
+			page = append(page, item)
+		}
+		return page, total, nil
+	}
+}
+
+// readIDs reads the entries with the given IDs, undecoded, from the months the
+// index puts them in, each month only until its share is found. An ID missing
+// from the result has no entry. The caller holds sm.mu.
+func readIDs(sm *StorageManager, resource string, idx *partitionIndex, ids []int) (map[int]json.RawMessage, error) {
+	wanted := map[string]map[int]bool{}
+	for _, id := range ids {
+		part := idx.Months[id]
+		if wanted[part] == nil {
+			wanted[part] = map[int]bool{}
+		}
+		wanted[part][id] = true
+	}
+	found := make(map[int]json.RawMessage, len(ids))
+	for part, want := range wanted {
+		filename := partitionFile(resource, part)
+		left := len(want)
+		err := streamJSON(sm, filename, func(raw json.RawMessage) error {
+			var key struct {
+				ID int `json:"id"`
+			}
+			if err := json.Unmarshal(raw, &key); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", filename, err)
+			}
+			if !want[key.ID] || found[key.ID] != nil {
+				return nil
+			}
+			found[key.ID] = raw
+			if left--; left == 0 {
+				return errStopStream
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// match is an entry QueryEntries may return, not yet decoded.
+type match struct {
+	id  int
+	raw json.RawMessage
+}
+
+// matchHeap is a min-heap of matches by ID, so the oldest is the one replaced.
+type matchHeap []match
+
+func (h matchHeap) Len() int           { return len(h) }
+func (h matchHeap) Less(i, j int) bool { return h[i].id < h[j].id }
+func (h matchHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *matchHeap) Push(x any)        { *h = append(*h, x.(match)) }
+func (h *matchHeap) Pop() any {
+	old := *h
+	m := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return m
+}
+
+// FindEntry returns the entry with the given ID, reading only the month the
+// index puts it in, and that only as far as the entry. The index and month are
+// read under sm.mu, so a concurrent move can't leave the index pointing at a
+// month the entry has already left.
+func FindEntry[T any](resource string, id int) (T, bool, error) {
+	var found T
+	if !isEntryResource(resource) {
+		return found, false, fmt.Errorf("unknown resource %q", resource)
+	}
+	sm, err := getStorage()
+	if err != nil {
+		return found, false, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return findEntry[T](sm, resource, id)
+}
+
+// findEntry is FindEntry for a caller that holds sm.mu.
+func findEntry[T any](sm *StorageManager, resource string, id int) (T, bool, error) {
+	var found T
+	idx, err := sm.loadIndex(resource)
+	if err != nil {
+		return found, false, err
+	}
+	part, ok := idx.Months[id]
+	if !ok {
+		return found, false, nil
+	}
+	filename := partitionFile(resource, part)
+	hit := false
+	err = streamJSON(sm, filename, func(raw json.RawMessage) error {
+		var key struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &key); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", filename, err)
+		}
+		if key.ID != id {
+			return nil
+		}
+		if err := json.Unmarshal(raw, &found); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", filename, err)
+		}
+		hit = true
+		return errStopStream
+	})
+	return found, hit, err
+}
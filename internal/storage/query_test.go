@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"babytracker/internal/models"
+)
+
+func TestQueryEntries(t *testing.T) {
+	setupBackupStorage(t)
+	ctx := context.Background()
+	for day := 1; day <= 6; day++ {
+		by := "Asha"
+		if day%2 == 0 {
+			by = "Ravi"
+		}
+		feed := &models.FeedEntry{Date: fmt.Sprintf("2026-04-%02d", day), Type: models.FeedTypeBottle, Quantity: 90, LoggedBy: by}
+		if err := SaveFeed(ctx, feed); err != nil {
+			t.Fatalf("SaveFeed failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		q     models.EntryQuery
+		ids   []int
+		total int
+	}{
+		{"all", models.EntryQuery{}, []int{6, 5, 4, 3, 2, 1}, 6},
+		{"first page", models.EntryQuery{Limit: 2}, []int{6, 5}, 6},
+		{"second page", models.EntryQuery{Limit: 2, Offset: 2}, []int{4, 3}, 6},
+		{"offset without limit", models.EntryQuery{Offset: 4}, []int{2, 1}, 6},
+		{"past the end", models.EntryQuery{Limit: 2, Offset: 10}, nil, 6},
+		{"date range", models.EntryQuery{From: "2026-04-02", To: "2026-04-04"}, []int{4, 3, 2}, 3},
+		{"from only, paged", models.EntryQuery{From: "2026-04-03", Limit: 1, Offset: 1}, []int{5}, 4},
+		{"caregiver", models.EntryQuery{LoggedBy: "ravi", Limit: 2}, []int{6, 4}, 3},
+		{"no match", models.EntryQuery{To: "2026-03-31"}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, total, err := QueryEntries[models.FeedEntry](models.ResourceFeeds, tt.q)
+			if err != nil {
+				t.Fatalf("QueryEntries failed: %v", err)
+			}
+			var ids []int
+			for _, f := range page {
+				ids = append(ids, f.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.ids) || total != tt.total {
+				t.Errorf("got %v of %d, want %v of %d", ids, total, tt.ids, tt.total)
+			}
+		})
+	}
+}
+
+func TestQueryEntriesReadsOnlyThePage(t *testing.T) {
+	dir := setupBackupStorage(t)
+	ctx := context.Background()
+	for _, date := range []string{"2026-03-31", "2026-04-01", "2026-04-02"} {
+		if err := SaveFeed(ctx, &models.FeedEntry{Date: date, Type: models.FeedTypeBottle}); err != nil {
+			t.Fatalf("SaveFeed failed: %v", err)
+		}
+	}
+	// March holds no entry of the first page, so its damage goes unread
+	if err := os.WriteFile(filepath.Join(dir, "feeds", "2026-03.json"), []byte(`[{"id": 1, "da`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	page, total, err := QueryEntries[models.FeedEntry](models.ResourceFeeds, models.EntryQuery{Limit: 2})
+	if err != nil || total != 3 || len(page) != 2 || page[0].ID != 3 || page[1].ID != 2 {
+		t.Errorf("first page = %+v, %d, %v", page, total, err)
+	}
+	if _, _, err := QueryEntries[models.FeedEntry](models.ResourceFeeds, models.EntryQuery{Limit: 2, Offset: 2}); err == nil {
+		t.Error("expected the page holding entry 1 to read the damaged month")
+	}
+	if _, _, err := QueryEntries[models.FeedEntry](models.ResourceFeeds, models.EntryQuery{From: "2026-03-01", Limit: 2}); err == nil {
+		t.Error("expected a filtered query to scan the damaged month")
+	}
+}
+
+func TestQueryEntriesFileStates(t *testing.T) {
+	dir := setupBackupStorage(t)
+	// A missing file and an empty one saved as null are both no entries
+	if page, total, err := QueryEntries[models.SleepEntry](models.ResourceSleep, models.EntryQuery{}); err != nil || len(page) != 0 || total != 0 {
+		t.Errorf("missing file = %v, %d, %v", page, total, err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "sleep"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sleep", "2026-04.json"), []byte("null"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if page, total, err := QueryEntries[models.SleepEntry](models.ResourceSleep, models.EntryQuery{}); err != nil || len(page) != 0 || total != 0 {
+		t.Errorf("null file = %v, %d, %v", page, total, err)
+	}
+	// A damaged file fails as loadJSON does, pointing at fsck
+	if err := os.WriteFile(filepath.Join(dir, "sleep", "2026-04.json"), []byte(`[{"id": 1}, {"id": 2, "da`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := QueryEntries[models.SleepEntry](models.ResourceSleep, models.EntryQuery{}); err == nil || !strings.Contains(err.Error(), "fsck") {
+		t.Errorf("damaged file: got %v, want a parse error pointing at fsck", err)
+	}
+	if _, _, err := QueryEntries[models.SleepEntry]("naps", models.EntryQuery{}); err == nil {
+		t.Error("expected an error for an unknown resource")
+	}
+}
+
+func TestQueryEncryptedEntries(t *testing.T) {
+	setupEncryptedStorage(t)
+	ctx := context.Background()
+	if err := SaveDiaper(ctx, &models.DiaperEntry{Date: "2026-04-06", Type: models.DiaperTypeWet}); err != nil {
+		t.Fatalf("SaveDiaper failed: %v", err)
+	}
+	if _, err := EncryptDataDir("secret"); err != nil {
+		t.Fatalf("EncryptDataDir failed: %v", err)
+	}
+	page, total, err := QueryEntries[models.DiaperEntry](models.ResourceDiapers, models.EntryQuery{Limit: 10})
+	if err != nil || total != 1 || len(page) != 1 || page[0].Type != models.DiaperTypeWet {
+		t.Errorf("encrypted query = %+v, %d, %v", page, total, err)
+	}
+	if d, found, err := FindEntry[models.DiaperEntry](models.ResourceDiapers, 1); err != nil || !found || d.Date != "2026-04-06" {
+		t.Errorf("encrypted find = %+v, %v, %v", d, found, err)
+	}
+}
+
+func TestFindEntry(t *testing.T) {
+	dir := setupBackupStorage(t)
+	ctx := context.Background()
+	for _, d := range []int{90, 120} {
+		if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Quantity: float64(d)}); err != nil {
+			t.Fatalf("SaveFeed failed: %v", err)
+		}
+	}
+	feed, found, err := FindEntry[models.FeedEntry](models.ResourceFeeds, 2)
+	if err != nil || !found || feed.Quantity != 120 {
+		t.Errorf("FindEntry(2) = %+v, %v, %v", feed, found, err)
+	}
+	if _, found, err := FindEntry[models.FeedEntry](models.ResourceFeeds, 3); err != nil || found {
+		t.Errorf("FindEntry(3) = %v, %v; want not found", found, err)
+	}
+
+	// The scan stops at the match, so damage past it goes unread
+	data, err := os.ReadFile(filepath.Join(dir, "feeds", "2026-04.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	damaged := strings.TrimSuffix(strings.TrimSpace(string(data)), "]") + `, {"id": 3, "da`
+	if err := os.WriteFile(filepath.Join(dir, "feeds", "2026-04.json"), []byte(damaged), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err := FindEntry[models.FeedEntry](models.ResourceFeeds, 1); err != nil || !found {
+		t.Errorf("FindEntry(1) before the damage = %v, %v", found, err)
+	}
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// schemaFileName records which migrations the data directory has been through.
+// It is never encrypted, so a newer schema is refused before the passphrase is asked for.
+const schemaFileName = "schema.json"
+
+// backupDirName holds the backup archives (see backup.go).
+const backupDirName = "backups"
+
+// ErrSchemaTooNew is returned when the data directory was written by a newer
+// build than this one. Opening it could silently drop fields this build doesn't know.
+var ErrSchemaTooNew = errors.New("data directory was written by a newer version of Baby Tracker")
+
+// migration upgrades the data files from version-1 to version. Migrations must
+// be safe to run again: one cut short by a crash is rerun from the start.
+type migration struct {
+	version int
+	name    string
+	run     func(sm *StorageManager) (changed int, err error) // The caller holds sm.mu
+}
+
+// migrations is the ordered registry of schema changes. Append new ones with the
+// next version number; never edit or reorder one that has shipped.
+var migrations = []migration{
+	{1, "give entries a UID", (*StorageManager).backfillUIDs},
+	{2, "split entry files by month", (*StorageManager).partitionEntries},
+}
+
+// SchemaVersion is the data directory schema this build writes.
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// schemaFile is the contents of schemaFileName.
+type schemaFile struct {
+	Version    int       `json:"version"`
+	MigratedAt time.Time `json:"migrated_at"`
+}
+
+// MigrationResult reports one migration run by Migrate.
+type MigrationResult struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Changed int    `json:"changed"` // Records the migration rewrote
+}
+
+// Options adjust how Init opens the data directory.
+type Options struct {
+	// ManualMigrations leaves pending migrations for an explicit Migrate call
+	// instead of running them on open (or on Unlock, for an encrypted directory).
+	ManualMigrations bool
+}
+
+// readSchema returns the data directory's schema version, 0 when it predates schema.json.
+func (sm *StorageManager) readSchema() (int, error) {
+	data, err := os.ReadFile(filepath.Join(sm.dataDir, schemaFileName))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", schemaFileName, err)
+	}
+	var s schemaFile
+	if err := json.Unmarshal(data, &s); err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", schemaFileName, err)
+	}
+	return s.Version, nil
+}
+
+// writeSchema records that the data directory is at version.
+func (sm *StorageManager) writeSchema(version int) error {
+	data, err := json.MarshalIndent(schemaFile{Version: version, MigratedAt: time.Now().UTC()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", schemaFileName, err)
+	}
+	return writeFileAtomic(filepath.Join(sm.dataDir, schemaFileName), data)
+}
+
+// checkSchema refuses a data directory from a newer build.
+func (sm *StorageManager) checkSchema() error {
+	version, err := sm.readSchema()
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion() {
+		return fmt.Errorf("%w (schema %d; this build supports up to %d)", ErrSchemaTooNew, version, SchemaVersion())
+	}
+	return nil
+}
+
+// pendingMigrations returns the migrations after version, in order.
+func pendingMigrations(version int) []migration {
+	var pending []migration
+	for _, m := range migrations {
+		if m.version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// migrate brings the data directory up to SchemaVersion, backing it up first
+// when anything is pending. The caller holds sm.mu, or is opening the directory.
+func (sm *StorageManager) migrate() ([]MigrationResult, error) {
+	if sm.closed {
+		return nil, ErrClosed
+	}
+	from, err := sm.readSchema()
+	if err != nil {
+		return nil, err
+	}
+	pending := pendingMigrations(from)
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	if _, err := sm.writeBackup(fmt.Sprintf("pre-migration-v%d", from)); err != nil {
+		return nil, fmt.Errorf("refusing to migrate without a backup: %w", err)
+	}
+	var results []MigrationResult
+	for _, m := range pending {
+		n, err := m.run(sm)
+		if err != nil {
+			return results, fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		// Recorded after each step, so a crash resumes at the one that was cut short
+		if err := sm.writeSchema(m.version); err != nil {
+			return results, err
+		}
+		results = append(results, MigrationResult{Version: m.version, Name: m.name, Changed: n})
+	}
+	return results, nil
+}
+
+// dryRunMigrations runs the pending migrations against a scratch copy of the
+// data directory and reports what they would change. The caller holds sm.mu.
+func (sm *StorageManager) dryRunMigrations() ([]MigrationResult, error) {
+	from, err := sm.readSchema()
+	if err != nil {
+		return nil, err
+	}
+	pending := pendingMigrations(from)
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	scratch, err := os.MkdirTemp("", "babytracker-migrate-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+	if _, err := copyDataFiles(sm.dataDir, scratch); err != nil {
+		return nil, err
+	}
+	copySM := &StorageManager{dataDir: scratch, encrypted: sm.encrypted, aead: sm.aead}
+	var results []MigrationResult
+	for _, m := range pending {
+		n, err := m.run(copySM)
+		if err != nil {
+			return results, fmt.Errorf("migration %d (%s) would fail: %w", m.version, m.name, err)
+		}
+		results = append(results, MigrationResult{Version: m.version, Name: m.name, Changed: n})
+	}
+	return results, nil
+}
+
+// Migrate runs the pending schema migrations, after backing up the data
+// directory, and reports each one. With dryRun it only reports what they would
+// change, working on a scratch copy. Only needed with Options.ManualMigrations;
+// otherwise Init and Unlock migrate.
+func Migrate(dryRun bool) ([]MigrationResult, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.encrypted && sm.aead == nil {
+		return nil, ErrLocked
+	}
+	if dryRun {
+		return sm.dryRunMigrations()
+	}
+	return sm.migrate()
+}
+
+// AppliedMigrations returns the migrations Init or Unlock ran, for logging.
+func AppliedMigrations() []MigrationResult {
+	sm, err := getStorage()
+	if err != nil {
+		return nil
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.migrated
+}
+
+// copyDataFiles copies the data files of one directory (see listDataFiles)
+// into another, created as needed, skipping leftover temp files. It returns how
+// many files it copied. Dry runs use it to make their scratch copy.
+func copyDataFiles(from, to string) (int, error) {
+	names, err := listDataFiles(from)
+	if err != nil {
+		return 0, err
+	}
+	copied := 0
+	for _, name := range names {
+		if strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(from, name))
+		if err != nil {
+			return copied, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		dst := filepath.Join(to, name)
+		if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+			return copied, fmt.Errorf("failed to create %s: %w", filepath.Dir(dst), err)
+		}
+		if err := os.WriteFile(dst, data, 0600); err != nil {
+			return copied, fmt.Errorf("failed to copy %s: %w", name, err)
+		}
+		copied++
+	}
+	return copied, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"babytracker/internal/models"
+)
+
+func TestMigrationsOnOpen(t *testing.T) {
+	// A fresh directory is stamped with the current schema; there is nothing to back up
+	fresh := t.TempDir()
+	sm, err := NewStorageManagerWithDir(fresh)
+	if err != nil {
+		t.Fatalf("NewStorageManagerWithDir failed: %v", err)
+	}
+	if v, _ := sm.readSchema(); v != SchemaVersion() {
+		t.Errorf("fresh directory at schema %d, want %d", v, SchemaVersion())
+	}
+	if _, err := os.Stat(filepath.Join(fresh, backupDirName)); !os.IsNotExist(err) {
+		t.Errorf("expected no backup of an empty directory, got %v", err)
+	}
+
+	// A directory from before schema.json is backed up, then migrated
+	old := t.TempDir()
+	legacy := `[{"id": 1, "date": "2026-04-06", "type": "Wet", "notes": ""}]`
+	if err := os.WriteFile(filepath.Join(old, "diapers.json"), []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sm, err = NewStorageManagerWithDir(old)
+	if err != nil {
+		t.Fatalf("NewStorageManagerWithDir failed: %v", err)
+	}
+	if len(sm.migrated) != 2 || sm.migrated[0].Version != 1 || sm.migrated[0].Changed != 1 || sm.migrated[1].Changed != 1 {
+		t.Errorf("unexpected migrations: %+v", sm.migrated)
+	}
+	diapers, _ := loadJSON[models.DiaperEntry](sm, "diapers/2026-04.json")
+	if len(diapers) != 1 || diapers[0].UID == "" {
+		t.Errorf("expected migration 1 to give the diaper a UID and 2 to file it under April: %+v", diapers)
+	}
+	if _, err := os.Stat(filepath.Join(old, "diapers.json")); !os.IsNotExist(err) {
+		t.Errorf("expected migration 2 to remove diapers.json, got %v", err)
+	}
+	backups, _ := filepath.Glob(filepath.Join(old, backupDirName, "pre-migration-v0-*"+backupSuffix))
+	if len(backups) != 1 {
+		t.Fatalf("expected one pre-migration backup, got %v", backups)
+	}
+	_, contents, err := readBackup(backups[0])
+	if err != nil {
+		t.Fatalf("readBackup failed: %v", err)
+	}
+	if data := contents["diapers.json"]; string(data) != legacy {
+		t.Errorf("backup differs from the original: %s", data)
+	}
+
+	// Reopening runs nothing
+	if sm, err = NewStorageManagerWithDir(old); err != nil || len(sm.migrated) != 0 {
+		t.Errorf("reopen: migrated %+v, %v; want nothing", sm.migrated, err)
+	}
+}
+
+func TestNewerSchemaRefused(t *testing.T) {
+	dir := t.TempDir()
+	sm := &StorageManager{dataDir: dir}
+	if err := sm.writeSchema(SchemaVersion() + 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStorageManagerWithDir(dir); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `[{"id": 1, "date": "2026-04-06", "type": "Bottle", "quantity": 90}]`
+	if err := os.WriteFile(filepath.Join(dir, "feeds.json"), []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sm, err := newStorageManager(dir, Options{ManualMigrations: true})
+	if err != nil {
+		t.Fatalf("newStorageManager failed: %v", err)
+	}
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+
+	results, err := Migrate(true)
+	if err != nil || len(results) != 2 || results[0].Changed != 1 || results[1].Changed != 1 {
+		t.Fatalf("Migrate(dry run) = %+v, %v; want two migrations changing 1 record each", results, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "feeds.json")); string(data) != legacy {
+		t.Errorf("dry run changed feeds.json: %s", data)
+	}
+	if v, _ := sm.readSchema(); v != 0 {
+		t.Errorf("dry run moved the schema to %d", v)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "feeds")); !os.IsNotExist(err) {
+		t.Errorf("dry run partitioned feeds: %v", err)
+	}
+
+	if results, err = Migrate(false); err != nil || len(results) != 2 {
+		t.Fatalf("Migrate = %+v, %v", results, err)
+	}
+	if v, _ := sm.readSchema(); v != SchemaVersion() {
+		t.Errorf("schema %d after Migrate, want %d", v, SchemaVersion())
+	}
+	if results, err = Migrate(true); err != nil || len(results) != 0 {
+		t.Errorf("nothing should be pending, got %+v, %v", results, err)
+	}
+}
@@ -2,7 +2,11 @@
 package storage
 
 import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,8 +17,15 @@ import (
 
 // StorageManager handles all data persistence operations.
 type StorageManager struct {
-	dataDir string
-	mu      sync.Mutex
+	dataDir   string
+	mu        sync.Mutex
+	encrypted bool          // keyfile.json present: files are written encrypted
+	aead      cipher.AEAD   // Data key cipher, set by Unlock
+	closed    bool          // Set by Close; further writes are refused
+	journal   *batchJournal // The running batch, which records each file before its first write
+
+	manualMigrations bool              // Options.ManualMigrations: leave pending migrations to Migrate
+	migrated         []MigrationResult // Migrations run on open or unlock, for the caller to report
 }
 
 // NewStorageManager creates a storage manager with the default data directory (~/.babytracker).
@@ -28,10 +39,40 @@ func NewStorageManager() (*StorageManager, error) {
 
 // NewStorageManagerWithDir creates a storage manager using the given directory.
 func NewStorageManagerWithDir(dataDir string) (*StorageManager, error) {
+	return newStorageManager(dataDir, Options{})
+}
+
+// newStorageManager opens dataDir: it checks the directory is writable, rolls
+// back an interrupted batch, refuses a newer schema and, unless the directory
+// is encrypted (see unlock) or opts says otherwise, runs pending migrations.
+func newStorageManager(dataDir string, opts Options) (*StorageManager, error) {
 	if err := os.MkdirAll(dataDir, 0700); err != nil {
 		return nil, fmt.Errorf("failed to create data directory: %w", err)
 	}
-	return &StorageManager{dataDir: dataDir}, nil
+	// Found now rather than at the first save, which would fail mid-request
+	probe, err := os.CreateTemp(dataDir, ".babytracker-write-check-*")
+	if err != nil {
+		return nil, fmt.Errorf("data directory %s is not writable: %w", dataDir, err)
+	}
+	probe.Close()
+	if err := os.Remove(probe.Name()); err != nil {
+		return nil, fmt.Errorf("failed to remove %s: %w", probe.Name(), err)
+	}
+	_, err = os.Stat(filepath.Join(dataDir, keyFileName))
+	sm := &StorageManager{dataDir: dataDir, encrypted: err == nil, manualMigrations: opts.ManualMigrations}
+	// A batch cut short by a crash is undone before anything reads the files
+	if err := sm.recoverBatch(); err != nil {
+		return nil, err
+	}
+	if err := sm.checkSchema(); err != nil {
+		return nil, err
+	}
+	if !sm.encrypted && !sm.manualMigrations {
+		if sm.migrated, err = sm.migrate(); err != nil {
+			return nil, err
+		}
+	}
+	return sm, nil
 }
 
 var (
@@ -44,7 +85,12 @@ var (
 // Call this early in main() before any Save/Load calls.
 // If never called, the default (~/.babytracker) is used.
 func Init(dataDir string) error {
-	sm, err := NewStorageManagerWithDir(dataDir)
+	return InitWithOptions(dataDir, Options{})
+}
+
+// InitWithOptions is Init with control over when migrations run.
+func InitWithOptions(dataDir string, opts Options) error {
+	sm, err := newStorageManager(dataDir, opts)
 	if err != nil {
 		return err
 	}
@@ -66,37 +112,85 @@ func getStorage() (*StorageManager, error) {
 	return globalStorage, nil
 }
 
+// ErrClosed is returned by writes after Close.
+var ErrClosed = errors.New("storage is closed")
+
+// Close waits for any in-progress write to finish, refuses further writes and
+// syncs the data directory so completed renames survive a power cut.
+// Call it once the API server has drained or the desktop window has closed.
+func Close() error {
+	sm := globalStorage
+	if sm == nil {
+		return nil
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.closed {
+		return nil
+	}
+	sm.closed = true
+	dir, err := os.Open(sm.dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open data directory: %w", err)
+	}
+	defer dir.Close()
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync data directory: %w", err)
+	}
+	return nil
+}
+
 // --- Generic JSON helpers ---
 
 func loadJSON[T any](sm *StorageManager, filename string) ([]T, error) {
-	filePath := filepath.Join(sm.dataDir, filename)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	data, err := sm.readData(filename)
+	if os.IsNotExist(err) {
 		return []T{}, nil
 	}
-	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
 	}
 	var items []T
 	if err := json.Unmarshal(data, &items); err != nil {
-		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+		return nil, fmt.Errorf("failed to parse %s (the fsck command can salvage it): %w", filename, err)
 	}
 	return items, nil
 }
 
 func saveJSON[T any](sm *StorageManager, filename string, items []T) error {
-	filePath := filepath.Join(sm.dataDir, filename)
 	data, err := json.MarshalIndent(items, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal %s: %w", filename, err)
 	}
 	// Atomic write: temp file + rename prevents corruption on crash (FINDING-25)
-	tmpPath := filePath + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
-		return fmt.Errorf("failed to write %s: %w", filename, err)
+	return sm.writeData(filename, data)
+}
+
+// writeFileAtomic writes via a temp file + rename so a crash never leaves a torn file (FINDING-25).
+// The temp file is synced before the rename so the new contents are on disk when it lands.
+// A missing parent directory, such as a resource's partition directory, is created.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync %s: %w", filepath.Base(path), err)
 	}
-	if err := os.Rename(tmpPath, filePath); err != nil {
-		return fmt.Errorf("failed to rename %s: %w", filename, err)
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", filepath.Base(path), err)
 	}
 	return nil
 }
@@ -112,26 +206,56 @@ func nextID(ids []int) int {
 	return max + 1
 }
 
+// ErrNotFound is returned by Update* and Delete* for an unknown ID.
+var ErrNotFound = errors.New("not found")
+
+// ErrVersionMismatch is returned by Update* and Delete* when the entry has been
+// changed since the caller read it.
+var ErrVersionMismatch = errors.New("entry has been changed since it was read")
+
+// checkVersion enforces an optimistic-concurrency precondition; 0 skips the check.
+func checkVersion(ifVersion, stored int) error {
+	if ifVersion != 0 && ifVersion != models.EntryVersion(stored) {
+		return fmt.Errorf("%w (version %d, expected %d)", ErrVersionMismatch, models.EntryVersion(stored), ifVersion)
+	}
+	return nil
+}
+
 // --- Feeds ---
 
-func SaveFeed(feed *models.FeedEntry) error {
+func SaveFeed(ctx context.Context, feed *models.FeedEntry) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	feeds, err := loadJSON[models.FeedEntry](sm, "feeds.json")
+	return sm.keyed(ctx, models.AuditCreate, models.ResourceFeeds, func() (int, error) {
+		err := sm.saveFeed(ctx, feed)
+		return feed.ID, err
+	})
+}
+
+// saveFeed is SaveFeed for a caller that holds sm.mu.
+func (sm *StorageManager) saveFeed(ctx context.Context, feed *models.FeedEntry) error {
+	idx, err := sm.loadIndex(models.ResourceFeeds)
 	if err != nil {
 		return fmt.Errorf("refusing to save over unreadable data file: %w", err)
 	}
-	ids := make([]int, len(feeds))
-	for i, f := range feeds {
-		ids[i] = f.ID
+	feed.SyncDate()
+	if err := feed.Canonicalize(); err != nil {
+		return err
+	}
+	id := idx.newID()
+	if err := idx.assignUID(&feed.UID, id); err != nil {
+		return err
+	}
+	feed.ID = id
+	feed.Version = 1
+	if err := insertEntry(sm, models.ResourceFeeds, idx, *feed, feedKey); err != nil {
+		return err
 	}
-	feed.ID = nextID(ids)
-	feeds = append(feeds, *feed)
-	return saveJSON(sm, "feeds.json", feeds)
+	return sm.record(ctx, models.AuditCreate, models.ResourceFeeds, feed.ID, nil, *feed)
 }
 
 func LoadFeeds() ([]models.FeedEntry, error) {
@@ -139,70 +263,117 @@ func LoadFeeds() ([]models.FeedEntry, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
-	return loadJSON[models.FeedEntry](sm, "feeds.json")
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return loadEntries(sm, models.ResourceFeeds, "", "", feedKey)
 }
 
-func UpdateFeed(id int, updated *models.FeedEntry) error {
+// UpdateFeed replaces an entry. A non-zero ifVersion must match its stored version.
+func UpdateFeed(ctx context.Context, id, ifVersion int, updated *models.FeedEntry) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	feeds, err := loadJSON[models.FeedEntry](sm, "feeds.json")
+	return sm.keyed(ctx, models.AuditUpdate, models.ResourceFeeds, func() (int, error) {
+		return id, sm.updateFeed(ctx, id, ifVersion, updated)
+	})
+}
+
+// updateFeed is UpdateFeed for a caller that holds sm.mu.
+func (sm *StorageManager) updateFeed(ctx context.Context, id, ifVersion int, updated *models.FeedEntry) error {
+	idx, feeds, i, err := locateEntry(sm, models.ResourceFeeds, id, feedKey)
 	if err != nil {
 		return fmt.Errorf("refusing to update over unreadable data file: %w", err)
 	}
-	for i, f := range feeds {
-		if f.ID == id {
-			updated.ID = id
-			feeds[i] = *updated
-			return saveJSON(sm, "feeds.json", feeds)
-		}
+	if i < 0 {
+		return fmt.Errorf("feed with ID %d %w", id, ErrNotFound)
+	}
+	f := feeds[i]
+	if err := checkVersion(ifVersion, f.Version); err != nil {
+		return err
+	}
+	updated.ID = id
+	updated.Version = models.EntryVersion(f.Version) + 1
+	updated.LoggedBy = f.LoggedBy // attribution belongs to whoever created the entry
+	updated.UID = f.UID
+	updated.SyncDate()
+	if err := updated.Canonicalize(); err != nil {
+		return err
+	}
+	if err := storeEntry(sm, models.ResourceFeeds, idx, feeds, i, *updated, feedKey); err != nil {
+		return err
 	}
-	return fmt.Errorf("feed with ID %d not found", id)
+	return sm.record(ctx, models.AuditUpdate, models.ResourceFeeds, id, f, *updated)
 }
 
-func DeleteFeed(id int) error {
+// DeleteFeed moves an entry to the trash, from where RestoreEntry can bring it back.
+// A non-zero ifVersion must match its stored version.
+func DeleteFeed(ctx context.Context, id, ifVersion int) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	feeds, err := loadJSON[models.FeedEntry](sm, "feeds.json")
+	return sm.deleteFeed(ctx, id, ifVersion)
+}
+
+// deleteFeed is DeleteFeed for a caller that holds sm.mu.
+func (sm *StorageManager) deleteFeed(ctx context.Context, id, ifVersion int) error {
+	idx, feeds, i, err := locateEntry(sm, models.ResourceFeeds, id, feedKey)
 	if err != nil {
 		return fmt.Errorf("refusing to delete from unreadable data file: %w", err)
 	}
-	for i, f := range feeds {
-		if f.ID == id {
-			feeds = append(feeds[:i], feeds[i+1:]...)
-			return saveJSON(sm, "feeds.json", feeds)
-		}
+	if i < 0 {
+		return fmt.Errorf("feed with ID %d %w", id, ErrNotFound)
+	}
+	f := feeds[i]
+	if err := checkVersion(ifVersion, f.Version); err != nil {
+		return err
 	}
-	return fmt.Errorf("feed with ID %d not found", id)
+	if err := sm.moveToTrash(ctx, models.ResourceFeeds, id, f); err != nil {
+		return err
+	}
+	if err := dropEntry(sm, models.ResourceFeeds, idx, feeds, i, feedKey); err != nil {
+		return err
+	}
+	return sm.record(ctx, models.AuditDelete, models.ResourceFeeds, id, f, nil)
 }
 
 // --- Sleep ---
 
-func SaveSleep(entry *models.SleepEntry) error {
+func SaveSleep(ctx context.Context, entry *models.SleepEntry) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	entries, err := loadJSON[models.SleepEntry](sm, "sleep.json")
+	return sm.keyed(ctx, models.AuditCreate, models.ResourceSleep, func() (int, error) {
+		err := sm.saveSleep(ctx, entry)
+		return entry.ID, err
+	})
+}
+
+// saveSleep is SaveSleep for a caller that holds sm.mu.
+func (sm *StorageManager) saveSleep(ctx context.Context, entry *models.SleepEntry) error {
+	idx, err := sm.loadIndex(models.ResourceSleep)
 	if err != nil {
 		return fmt.Errorf("refusing to save over unreadable data file: %w", err)
 	}
-	ids := make([]int, len(entries))
-	for i, e := range entries {
-		ids[i] = e.ID
+	entry.SyncDate()
+	id := idx.newID()
+	if err := idx.assignUID(&entry.UID, id); err != nil {
+		return err
+	}
+	entry.ID = id
+	entry.Version = 1
+	if err := insertEntry(sm, models.ResourceSleep, idx, *entry, sleepKey); err != nil {
+		return err
 	}
-	entry.ID = nextID(ids)
-	entries = append(entries, *entry)
-	return saveJSON(sm, "sleep.json", entries)
+	return sm.record(ctx, models.AuditCreate, models.ResourceSleep, entry.ID, nil, *entry)
 }
 
 func LoadSleep() ([]models.SleepEntry, error) {
@@ -210,70 +381,116 @@ func LoadSleep() ([]models.SleepEntry, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
-	return loadJSON[models.SleepEntry](sm, "sleep.json")
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return loadEntries(sm, models.ResourceSleep, "", "", sleepKey)
 }
 
-func UpdateSleep(id int, updated *models.SleepEntry) error {
+// UpdateSleep replaces an entry. A non-zero ifVersion must match its stored version.
+func UpdateSleep(ctx context.Context, id, ifVersion int, updated *models.SleepEntry) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	entries, err := loadJSON[models.SleepEntry](sm, "sleep.json")
+	return sm.keyed(ctx, models.AuditUpdate, models.ResourceSleep, func() (int, error) {
+		return id, sm.updateSleep(ctx, id, ifVersion, updated)
+	})
+}
+
+// updateSleep is UpdateSleep for a caller that holds sm.mu.
+func (sm *StorageManager) updateSleep(ctx context.Context, id, ifVersion int, updated *models.SleepEntry) error {
+	idx, entries, i, err := locateEntry(sm, models.ResourceSleep, id, sleepKey)
 	if err != nil {
 		return fmt.Errorf("refusing to update over unreadable data file: %w", err)
 	}
-	for i, e := range entries {
-		if e.ID == id {
-			updated.ID = id
-			entries[i] = *updated
-			return saveJSON(sm, "sleep.json", entries)
-		}
+	if i < 0 {
+		return fmt.Errorf("sleep entry with ID %d %w", id, ErrNotFound)
+	}
+	e := entries[i]
+	if err := checkVersion(ifVersion, e.Version); err != nil {
+		return err
+	}
+	updated.ID = id
+	updated.Version = models.EntryVersion(e.Version) + 1
+	updated.LoggedBy = e.LoggedBy // attribution belongs to whoever created the entry
+	updated.UID = e.UID
+	updated.SyncDate()
+	if err := storeEntry(sm, models.ResourceSleep, idx, entries, i, *updated, sleepKey); err != nil {
+		return err
 	}
-	return fmt.Errorf("sleep entry with ID %d not found", id)
+	return sm.record(ctx, models.AuditUpdate, models.ResourceSleep, id, e, *updated)
 }
 
-func DeleteSleep(id int) error {
+// DeleteSleep moves an entry to the trash, from where RestoreEntry can bring it back.
+// A non-zero ifVersion must match its stored version.
+func DeleteSleep(ctx context.Context, id, ifVersion int) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	entries, err := loadJSON[models.SleepEntry](sm, "sleep.json")
+	return sm.deleteSleep(ctx, id, ifVersion)
+}
+
+// deleteSleep is DeleteSleep for a caller that holds sm.mu.
+func (sm *StorageManager) deleteSleep(ctx context.Context, id, ifVersion int) error {
+	idx, entries, i, err := locateEntry(sm, models.ResourceSleep, id, sleepKey)
 	if err != nil {
 		return fmt.Errorf("refusing to delete from unreadable data file: %w", err)
 	}
-	for i, e := range entries {
-		if e.ID == id {
-			entries = append(entries[:i], entries[i+1:]...)
-			return saveJSON(sm, "sleep.json", entries)
-		}
+	if i < 0 {
+		return fmt.Errorf("sleep entry with ID %d %w", id, ErrNotFound)
+	}
+	e := entries[i]
+	if err := checkVersion(ifVersion, e.Version); err != nil {
+		return err
+	}
+	if err := sm.moveToTrash(ctx, models.ResourceSleep, id, e); err != nil {
+		return err
 	}
-	return fmt.Errorf("sleep entry with ID %d not found", id)
+	if err := dropEntry(sm, models.ResourceSleep, idx, entries, i, sleepKey); err != nil {
+		return err
+	}
+	return sm.record(ctx, models.AuditDelete, models.ResourceSleep, id, e, nil)
 }
 
 // --- Growth ---
 
-func SaveGrowth(entry *models.GrowthEntry) error {
+func SaveGrowth(ctx context.Context, entry *models.GrowthEntry) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	entries, err := loadJSON[models.GrowthEntry](sm, "growth.json")
+	return sm.keyed(ctx, models.AuditCreate, models.ResourceGrowth, func() (int, error) {
+		err := sm.saveGrowth(ctx, entry)
+		return entry.ID, err
+	})
+}
+
+// saveGrowth is SaveGrowth for a caller that holds sm.mu.
+func (sm *StorageManager) saveGrowth(ctx context.Context, entry *models.GrowthEntry) error {
+	idx, err := sm.loadIndex(models.ResourceGrowth)
 	if err != nil {
 		return fmt.Errorf("refusing to save over unreadable data file: %w", err)
 	}
-	ids := make([]int, len(entries))
-	for i, e := range entries {
-		ids[i] = e.ID
+	if err := entry.Canonicalize(); err != nil {
+		return err
+	}
+	id := idx.newID()
+	if err := idx.assignUID(&entry.UID, id); err != nil {
+		return err
+	}
+	entry.ID = id
+	entry.Version = 1
+	if err := insertEntry(sm, models.ResourceGrowth, idx, *entry, growthKey); err != nil {
+		return err
 	}
-	entry.ID = nextID(ids)
-	entries = append(entries, *entry)
-	return saveJSON(sm, "growth.json", entries)
+	return sm.record(ctx, models.AuditCreate, models.ResourceGrowth, entry.ID, nil, *entry)
 }
 
 func LoadGrowth() ([]models.GrowthEntry, error) {
@@ -281,70 +498,116 @@ func LoadGrowth() ([]models.GrowthEntry, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
-	return loadJSON[models.GrowthEntry](sm, "growth.json")
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return loadEntries(sm, models.ResourceGrowth, "", "", growthKey)
 }
 
-func UpdateGrowth(id int, updated *models.GrowthEntry) error {
+// UpdateGrowth replaces an entry. A non-zero ifVersion must match its stored version.
+func UpdateGrowth(ctx context.Context, id, ifVersion int, updated *models.GrowthEntry) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	entries, err := loadJSON[models.GrowthEntry](sm, "growth.json")
+	return sm.keyed(ctx, models.AuditUpdate, models.ResourceGrowth, func() (int, error) {
+		return id, sm.updateGrowth(ctx, id, ifVersion, updated)
+	})
+}
+
+// updateGrowth is UpdateGrowth for a caller that holds sm.mu.
+func (sm *StorageManager) updateGrowth(ctx context.Context, id, ifVersion int, updated *models.GrowthEntry) error {
+	idx, entries, i, err := locateEntry(sm, models.ResourceGrowth, id, growthKey)
 	if err != nil {
 		return fmt.Errorf("refusing to update over unreadable data file: %w", err)
 	}
-	for i, e := range entries {
-		if e.ID == id {
-			updated.ID = id
-			entries[i] = *updated
-			return saveJSON(sm, "growth.json", entries)
-		}
+	if i < 0 {
+		return fmt.Errorf("growth entry with ID %d %w", id, ErrNotFound)
+	}
+	e := entries[i]
+	if err := checkVersion(ifVersion, e.Version); err != nil {
+		return err
+	}
+	updated.ID = id
+	updated.Version = models.EntryVersion(e.Version) + 1
+	updated.LoggedBy = e.LoggedBy // attribution belongs to whoever created the entry
+	updated.UID = e.UID
+	if err := updated.Canonicalize(); err != nil {
+		return err
 	}
-	return fmt.Errorf("growth entry with ID %d not found", id)
+	if err := storeEntry(sm, models.ResourceGrowth, idx, entries, i, *updated, growthKey); err != nil {
+		return err
+	}
+	return sm.record(ctx, models.AuditUpdate, models.ResourceGrowth, id, e, *updated)
 }
 
-func DeleteGrowth(id int) error {
+// DeleteGrowth moves an entry to the trash, from where RestoreEntry can bring it back.
+// A non-zero ifVersion must match its stored version.
+func DeleteGrowth(ctx context.Context, id, ifVersion int) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	entries, err := loadJSON[models.GrowthEntry](sm, "growth.json")
+	return sm.deleteGrowth(ctx, id, ifVersion)
+}
+
+// deleteGrowth is DeleteGrowth for a caller that holds sm.mu.
+func (sm *StorageManager) deleteGrowth(ctx context.Context, id, ifVersion int) error {
+	idx, entries, i, err := locateEntry(sm, models.ResourceGrowth, id, growthKey)
 	if err != nil {
 		return fmt.Errorf("refusing to delete from unreadable data file: %w", err)
 	}
-	for i, e := range entries {
-		if e.ID == id {
-			entries = append(entries[:i], entries[i+1:]...)
-			return saveJSON(sm, "growth.json", entries)
-		}
+	if i < 0 {
+		return fmt.Errorf("growth entry with ID %d %w", id, ErrNotFound)
+	}
+	e := entries[i]
+	if err := checkVersion(ifVersion, e.Version); err != nil {
+		return err
 	}
-	return fmt.Errorf("growth entry with ID %d not found", id)
+	if err := sm.moveToTrash(ctx, models.ResourceGrowth, id, e); err != nil {
+		return err
+	}
+	if err := dropEntry(sm, models.ResourceGrowth, idx, entries, i, growthKey); err != nil {
+		return err
+	}
+	return sm.record(ctx, models.AuditDelete, models.ResourceGrowth, id, e, nil)
 }
 
 // --- Diapers ---
 
-func SaveDiaper(entry *models.DiaperEntry) error {
+func SaveDiaper(ctx context.Context, entry *models.DiaperEntry) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	entries, err := loadJSON[models.DiaperEntry](sm, "diapers.json")
+	return sm.keyed(ctx, models.AuditCreate, models.ResourceDiapers, func() (int, error) {
+		err := sm.saveDiaper(ctx, entry)
+		return entry.ID, err
+	})
+}
+
+// saveDiaper is SaveDiaper for a caller that holds sm.mu.
+func (sm *StorageManager) saveDiaper(ctx context.Context, entry *models.DiaperEntry) error {
+	idx, err := sm.loadIndex(models.ResourceDiapers)
 	if err != nil {
 		return fmt.Errorf("refusing to save over unreadable data file: %w", err)
 	}
-	ids := make([]int, len(entries))
-	for i, e := range entries {
-		ids[i] = e.ID
+	entry.SyncDate()
+	id := idx.newID()
+	if err := idx.assignUID(&entry.UID, id); err != nil {
+		return err
+	}
+	entry.ID = id
+	entry.Version = 1
+	if err := insertEntry(sm, models.ResourceDiapers, idx, *entry, diaperKey); err != nil {
+		return err
 	}
-	entry.ID = nextID(ids)
-	entries = append(entries, *entry)
-	return saveJSON(sm, "diapers.json", entries)
+	return sm.record(ctx, models.AuditCreate, models.ResourceDiapers, entry.ID, nil, *entry)
 }
 
 func LoadDiapers() ([]models.DiaperEntry, error) {
@@ -352,48 +615,377 @@ func LoadDiapers() ([]models.DiaperEntry, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
-	return loadJSON[models.DiaperEntry](sm, "diapers.json")
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return loadEntries(sm, models.ResourceDiapers, "", "", diaperKey)
 }
 
-func UpdateDiaper(id int, updated *models.DiaperEntry) error {
+// UpdateDiaper replaces an entry. A non-zero ifVersion must match its stored version.
+func UpdateDiaper(ctx context.Context, id, ifVersion int, updated *models.DiaperEntry) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	entries, err := loadJSON[models.DiaperEntry](sm, "diapers.json")
+	return sm.keyed(ctx, models.AuditUpdate, models.ResourceDiapers, func() (int, error) {
+		return id, sm.updateDiaper(ctx, id, ifVersion, updated)
+	})
+}
+
+// updateDiaper is UpdateDiaper for a caller that holds sm.mu.
+func (sm *StorageManager) updateDiaper(ctx context.Context, id, ifVersion int, updated *models.DiaperEntry) error {
+	idx, entries, i, err := locateEntry(sm, models.ResourceDiapers, id, diaperKey)
 	if err != nil {
 		return fmt.Errorf("refusing to update over unreadable data file: %w", err)
 	}
+	if i < 0 {
+		return fmt.Errorf("diaper entry with ID %d %w", id, ErrNotFound)
+	}
+	e := entries[i]
+	if err := checkVersion(ifVersion, e.Version); err != nil {
+		return err
+	}
+	updated.ID = id
+	updated.Version = models.EntryVersion(e.Version) + 1
+	updated.LoggedBy = e.LoggedBy // attribution belongs to whoever created the entry
+	updated.UID = e.UID
+	updated.SyncDate()
+	if err := storeEntry(sm, models.ResourceDiapers, idx, entries, i, *updated, diaperKey); err != nil {
+		return err
+	}
+	return sm.record(ctx, models.AuditUpdate, models.ResourceDiapers, id, e, *updated)
+}
+
+// DeleteDiaper moves an entry to the trash, from where RestoreEntry can bring it back.
+// A non-zero ifVersion must match its stored version.
+func DeleteDiaper(ctx context.Context, id, ifVersion int) error {
+	sm, err := getStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.deleteDiaper(ctx, id, ifVersion)
+}
+
+// deleteDiaper is DeleteDiaper for a caller that holds sm.mu.
+func (sm *StorageManager) deleteDiaper(ctx context.Context, id, ifVersion int) error {
+	idx, entries, i, err := locateEntry(sm, models.ResourceDiapers, id, diaperKey)
+	if err != nil {
+		return fmt.Errorf("refusing to delete from unreadable data file: %w", err)
+	}
+	if i < 0 {
+		return fmt.Errorf("diaper entry with ID %d %w", id, ErrNotFound)
+	}
+	e := entries[i]
+	if err := checkVersion(ifVersion, e.Version); err != nil {
+		return err
+	}
+	if err := sm.moveToTrash(ctx, models.ResourceDiapers, id, e); err != nil {
+		return err
+	}
+	if err := dropEntry(sm, models.ResourceDiapers, idx, entries, i, diaperKey); err != nil {
+		return err
+	}
+	return sm.record(ctx, models.AuditDelete, models.ResourceDiapers, id, e, nil)
+}
+
+// --- Caregivers ---
+
+// SaveCaregiver registers a new caregiver. Names must be unique (case-insensitive).
+func SaveCaregiver(ctx context.Context, c *models.Caregiver) error {
+	sm, err := getStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	entries, err := loadJSON[models.Caregiver](sm, "caregivers.json")
+	if err != nil {
+		return fmt.Errorf("refusing to save over unreadable data file: %w", err)
+	}
+	ids := make([]int, len(entries))
 	for i, e := range entries {
-		if e.ID == id {
-			updated.ID = id
-			entries[i] = *updated
-			return saveJSON(sm, "diapers.json", entries)
+		if models.SameName(e.Name, c.Name) {
+			return fmt.Errorf("caregiver %q already exists", c.Name)
+		}
+		ids[i] = e.ID
+	}
+	c.ID = nextID(ids)
+	entries = append(entries, *c)
+	if err := saveJSON(sm, "caregivers.json", entries); err != nil {
+		return err
+	}
+	return sm.record(ctx, models.AuditCreate, models.ResourceCaregivers, c.ID, nil, *c)
+}
+
+func LoadCaregivers() ([]models.Caregiver, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return loadJSON[models.Caregiver](sm, "caregivers.json")
+}
+
+// FindCaregiver returns the registered caregiver with the given name (case-insensitive).
+func FindCaregiver(name string) (*models.Caregiver, error) {
+	entries, err := LoadCaregivers()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if models.SameName(e.Name, name) {
+			return &e, nil
 		}
 	}
-	return fmt.Errorf("diaper entry with ID %d not found", id)
+	return nil, fmt.Errorf("caregiver %q not found", name)
 }
 
-func DeleteDiaper(id int) error {
+// DeleteCaregiver removes a caregiver from the registry.
+// Entries they logged keep their LoggedBy name.
+func DeleteCaregiver(ctx context.Context, id int) error {
 	sm, err := getStorage()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	entries, err := loadJSON[models.DiaperEntry](sm, "diapers.json")
+	entries, err := loadJSON[models.Caregiver](sm, "caregivers.json")
 	if err != nil {
 		return fmt.Errorf("refusing to delete from unreadable data file: %w", err)
 	}
 	for i, e := range entries {
 		if e.ID == id {
 			entries = append(entries[:i], entries[i+1:]...)
-			return saveJSON(sm, "diapers.json", entries)
+			if err := saveJSON(sm, "caregivers.json", entries); err != nil {
+				return err
+			}
+			return sm.record(ctx, models.AuditDelete, models.ResourceCaregivers, id, e, nil)
+		}
+	}
+	return fmt.Errorf("caregiver with ID %d not found", id)
+}
+
+// --- Users & tokens ---
+
+// SaveUser creates an account. Names must be unique (case-insensitive).
+func SaveUser(ctx context.Context, u *models.User) error {
+	sm, err := getStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	users, err := loadJSON[models.User](sm, "users.json")
+	if err != nil {
+		return fmt.Errorf("refusing to save over unreadable data file: %w", err)
+	}
+	ids := make([]int, len(users))
+	for i, e := range users {
+		if models.SameName(e.Name, u.Name) {
+			return fmt.Errorf("user %q already exists", u.Name)
+		}
+		ids[i] = e.ID
+	}
+	u.ID = nextID(ids)
+	users = append(users, *u)
+	if err := saveJSON(sm, "users.json", users); err != nil {
+		return err
+	}
+	return sm.record(ctx, models.AuditCreate, models.ResourceUsers, u.ID, nil, *u)
+}
+
+func LoadUsers() ([]models.User, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return loadJSON[models.User](sm, "users.json")
+}
+
+// FindUser returns the account with the given ID.
+func FindUser(id int) (*models.User, error) {
+	users, err := LoadUsers()
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if u.ID == id {
+			return &u, nil
 		}
 	}
-	return fmt.Errorf("diaper entry with ID %d not found", id)
+	return nil, fmt.Errorf("user with ID %d not found", id)
+}
+
+// DeleteUser removes an account and revokes all of its tokens.
+func DeleteUser(ctx context.Context, id int) error {
+	sm, err := getStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	users, err := loadJSON[models.User](sm, "users.json")
+	if err != nil {
+		return fmt.Errorf("refusing to delete from unreadable data file: %w", err)
+	}
+	tokens, err := loadJSON[models.APIToken](sm, "tokens.json")
+	if err != nil {
+		return fmt.Errorf("refusing to delete from unreadable data file: %w", err)
+	}
+	for i, u := range users {
+		if u.ID != id {
+			continue
+		}
+		kept := make([]models.APIToken, 0, len(tokens))
+		var revoked []models.APIToken
+		for _, t := range tokens {
+			if t.UserID != id {
+				kept = append(kept, t)
+			} else {
+				revoked = append(revoked, t)
+			}
+		}
+		// Revoke tokens first so a failed second write can't leave a deleted user's token valid
+		if err := saveJSON(sm, "tokens.json", kept); err != nil {
+			return err
+		}
+		for _, t := range revoked {
+			if err := sm.record(ctx, models.AuditDelete, models.ResourceTokens, t.ID, t.Redacted(), nil); err != nil {
+				return err
+			}
+		}
+		users = append(users[:i], users[i+1:]...)
+		if err := saveJSON(sm, "users.json", users); err != nil {
+			return err
+		}
+		return sm.record(ctx, models.AuditDelete, models.ResourceUsers, id, u, nil)
+	}
+	return fmt.Errorf("user with ID %d not found", id)
+}
+
+// SaveToken stores a new token for an existing user.
+func SaveToken(ctx context.Context, t *models.APIToken) error {
+	sm, err := getStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.keyed(ctx, models.AuditCreate, models.ResourceTokens, func() (int, error) {
+		err := sm.saveToken(ctx, t)
+		return t.ID, err
+	})
+}
+
+// saveToken is SaveToken for a caller that holds sm.mu.
+func (sm *StorageManager) saveToken(ctx context.Context, t *models.APIToken) error {
+	tokens, err := loadJSON[models.APIToken](sm, "tokens.json")
+	if err != nil {
+		return fmt.Errorf("refusing to save over unreadable data file: %w", err)
+	}
+	ids := make([]int, len(tokens))
+	for i, e := range tokens {
+		ids[i] = e.ID
+	}
+	t.ID = nextID(ids)
+	tokens = append(tokens, *t)
+	if err := saveJSON(sm, "tokens.json", tokens); err != nil {
+		return err
+	}
+	return sm.record(ctx, models.AuditCreate, models.ResourceTokens, t.ID, nil, t.Redacted())
+}
+
+func LoadTokens() ([]models.APIToken, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return loadJSON[models.APIToken](sm, "tokens.json")
+}
+
+// DeleteToken revokes a token.
+func DeleteToken(ctx context.Context, id int) error {
+	sm, err := getStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	tokens, err := loadJSON[models.APIToken](sm, "tokens.json")
+	if err != nil {
+		return fmt.Errorf("refusing to delete from unreadable data file: %w", err)
+	}
+	for i, t := range tokens {
+		if t.ID == id {
+			tokens = append(tokens[:i], tokens[i+1:]...)
+			if err := saveJSON(sm, "tokens.json", tokens); err != nil {
+				return err
+			}
+			return sm.record(ctx, models.AuditDelete, models.ResourceTokens, id, t.Redacted(), nil)
+		}
+	}
+	return fmt.Errorf("token with ID %d not found", id)
+}
+
+// --- Secrets ---
+
+// LoadOrCreateSecret returns the random key stored in the named file in the data
+// directory, generating size bytes on first use. Keys never leave the data directory.
+func LoadOrCreateSecret(name string, size int) ([]byte, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	key, found, err := sm.loadSecret(name, size)
+	if err != nil || found {
+		return key, err
+	}
+	key = make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate %s: %w", name, err)
+	}
+	if err := sm.writeData(name, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// LoadSecret is LoadOrCreateSecret for readers that have no use for a new key,
+// such as checking a signature: it reports whether the key exists instead of
+// creating it.
+func LoadSecret(name string, size int) ([]byte, bool, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.loadSecret(name, size)
+}
+
+// loadSecret reads a key file of size bytes. The caller holds sm.mu.
+func (sm *StorageManager) loadSecret(name string, size int) ([]byte, bool, error) {
+	key, err := sm.readData(name)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(key) != size {
+		return nil, false, fmt.Errorf("%s has unexpected length %d", name, len(key))
+	}
+	return key, true, nil
 }
 
 // GetDataDirectory returns the directory where data files are stored.
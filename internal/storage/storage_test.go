@@ -1,9 +1,13 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
+	_ "time/tzdata"
 
 	"babytracker/internal/models"
 )
@@ -41,7 +45,7 @@ func TestFeedRoundTrip(t *testing.T) {
 		Notes:    "test feed",
 	}
 
-	if err := SaveFeed(feed); err != nil {
+	if err := SaveFeed(context.Background(), feed); err != nil {
 		t.Fatalf("SaveFeed failed: %v", err)
 	}
 	if feed.ID != 1 {
@@ -76,7 +80,7 @@ func TestSleepRoundTrip(t *testing.T) {
 		Notes:   "test nap",
 	}
 
-	if err := SaveSleep(entry); err != nil {
+	if err := SaveSleep(context.Background(), entry); err != nil {
 		t.Fatalf("SaveSleep failed: %v", err)
 	}
 	if entry.ID != 1 {
@@ -108,7 +112,7 @@ func TestGrowthRoundTrip(t *testing.T) {
 		HeadCircumference: 36.0,
 	}
 
-	if err := SaveGrowth(entry); err != nil {
+	if err := SaveGrowth(context.Background(), entry); err != nil {
 		t.Fatalf("SaveGrowth failed: %v", err)
 	}
 	if entry.ID != 1 {
@@ -140,7 +144,7 @@ func TestDiaperRoundTrip(t *testing.T) {
 		Notes: "test change",
 	}
 
-	if err := SaveDiaper(entry); err != nil {
+	if err := SaveDiaper(context.Background(), entry); err != nil {
 		t.Fatalf("SaveDiaper failed: %v", err)
 	}
 	if entry.ID != 1 {
@@ -174,6 +178,20 @@ func TestLoadFromEmptyDir(t *testing.T) {
 	}
 }
 
+func TestOpenUnwritableDir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0700) })
+	if _, err := NewStorageManagerWithDir(dir); err == nil || !strings.Contains(err.Error(), "not writable") {
+		t.Errorf("expected an unwritable data directory error, got %v", err)
+	}
+}
+
 func TestMultipleSavesIncrementID(t *testing.T) {
 	sm := setupTestStorage(t)
 	origGlobal := globalStorage
@@ -182,7 +200,7 @@ func TestMultipleSavesIncrementID(t *testing.T) {
 
 	for i := 1; i <= 3; i++ {
 		feed := &models.FeedEntry{Date: "2025-06-22", Type: models.FeedTypeBottle}
-		if err := SaveFeed(feed); err != nil {
+		if err := SaveFeed(context.Background(), feed); err != nil {
 			t.Fatalf("SaveFeed %d failed: %v", i, err)
 		}
 		if feed.ID != i {
@@ -213,3 +231,280 @@ func TestGetDataDirectory(t *testing.T) {
 		t.Errorf("data directory does not exist: %s", dir)
 	}
 }
+
+func TestSaveDerivesDateFromTime(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	models.SetLocation(loc)
+	defer models.SetLocation(nil)
+
+	// 02:00 UTC on the 23rd is still the 22nd in New York
+	feed := &models.FeedEntry{
+		Date: "2025-06-23",
+		Time: models.FlexTime{Time: time.Date(2025, 6, 23, 2, 0, 0, 0, time.UTC)},
+		Type: models.FeedTypeBottle,
+	}
+	if err := SaveFeed(context.Background(), feed); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	feeds, err := LoadFeeds()
+	if err != nil {
+		t.Fatalf("LoadFeeds failed: %v", err)
+	}
+	if feeds[0].Date != "2025-06-22" {
+		t.Errorf("expected date 2025-06-22, got %s", feeds[0].Date)
+	}
+}
+
+func TestCaregiverRegistry(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+
+	if err := SaveCaregiver(context.Background(), &models.Caregiver{Name: "Asha", Relation: "Mum"}); err != nil {
+		t.Fatalf("SaveCaregiver failed: %v", err)
+	}
+	if err := SaveCaregiver(context.Background(), &models.Caregiver{Name: " asha "}); err == nil {
+		t.Error("expected duplicate caregiver name to be rejected")
+	}
+	c, err := FindCaregiver("ASHA")
+	if err != nil {
+		t.Fatalf("FindCaregiver failed: %v", err)
+	}
+	if c.Name != "Asha" || c.ID != 1 {
+		t.Errorf("found %+v, want Asha with ID 1", c)
+	}
+	if err := DeleteCaregiver(context.Background(), c.ID); err != nil {
+		t.Fatalf("DeleteCaregiver failed: %v", err)
+	}
+	if _, err := FindCaregiver("Asha"); err == nil {
+		t.Error("expected caregiver to be gone after delete")
+	}
+}
+
+func TestUpdateKeepsLoggedBy(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+
+	if err := SaveDiaper(context.Background(), &models.DiaperEntry{Date: "2026-04-06", Type: models.DiaperTypeWet, LoggedBy: "Asha"}); err != nil {
+		t.Fatalf("SaveDiaper failed: %v", err)
+	}
+	if err := UpdateDiaper(context.Background(), 1, 0, &models.DiaperEntry{Date: "2026-04-06", Type: models.DiaperTypeDirty, LoggedBy: "Ravi"}); err != nil {
+		t.Fatalf("UpdateDiaper failed: %v", err)
+	}
+	diapers, err := LoadDiapers()
+	if err != nil {
+		t.Fatalf("LoadDiapers failed: %v", err)
+	}
+	if diapers[0].LoggedBy != "Asha" {
+		t.Errorf("LoggedBy = %q, want Asha", diapers[0].LoggedBy)
+	}
+}
+
+func TestDeleteUserRevokesTokens(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+
+	u := &models.User{Name: "Asha", Role: models.RoleCaregiver}
+	if err := SaveUser(context.Background(), u); err != nil {
+		t.Fatalf("SaveUser failed: %v", err)
+	}
+	if err := SaveUser(context.Background(), &models.User{Name: "asha", Role: models.RoleViewer}); err == nil {
+		t.Error("expected duplicate user name to be rejected")
+	}
+	if err := SaveToken(context.Background(), &models.APIToken{UserID: u.ID, Hash: "abc"}); err != nil {
+		t.Fatalf("SaveToken failed: %v", err)
+	}
+	if err := DeleteUser(context.Background(), u.ID); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	tokens, err := LoadTokens()
+	if err != nil {
+		t.Fatalf("LoadTokens failed: %v", err)
+	}
+	if len(tokens) != 0 {
+		t.Errorf("expected tokens revoked with the user, got %d", len(tokens))
+	}
+}
+
+func TestCloseWaitsForWritesAndRefusesNew(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+
+	// Simulate a save in progress holding the lock
+	sm.mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		if err := Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Close returned while a write was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	sm.mu.Unlock()
+	<-done
+
+	if err := SaveFeed(context.Background(), &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle}); !errors.Is(err, ErrClosed) {
+		t.Errorf("SaveFeed after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestUpdateAndDeleteCheckVersion(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+	ctx := context.Background()
+
+	entry := &models.SleepEntry{Date: "2026-04-06", Type: models.SleepTypeNap, Duration: 45}
+	if err := SaveSleep(ctx, entry); err != nil {
+		t.Fatalf("SaveSleep failed: %v", err)
+	}
+	if entry.Version != 1 {
+		t.Errorf("new entry version = %d, want 1", entry.Version)
+	}
+	first := &models.SleepEntry{Date: "2026-04-06", Type: models.SleepTypeNap, Duration: 60}
+	if err := UpdateSleep(ctx, entry.ID, 1, first); err != nil {
+		t.Fatalf("UpdateSleep failed: %v", err)
+	}
+	if first.Version != 2 {
+		t.Errorf("updated entry version = %d, want 2", first.Version)
+	}
+	// A second writer still holding version 1 loses
+	stale := &models.SleepEntry{Date: "2026-04-06", Type: models.SleepTypeNap, Duration: 30}
+	if err := UpdateSleep(ctx, entry.ID, 1, stale); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("stale update: got %v, want ErrVersionMismatch", err)
+	}
+	if err := DeleteSleep(ctx, entry.ID, 1); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("stale delete: got %v, want ErrVersionMismatch", err)
+	}
+	if err := DeleteSleep(ctx, entry.ID, 2); err != nil {
+		t.Errorf("delete at the current version failed: %v", err)
+	}
+}
+
+// benchFeeds is the bench target's volume for one resource.
+const benchFeeds = 10000
+
+// setupBenchStorage writes benchFeeds feeds, three a day, as the global storage.
+func setupBenchStorage(b *testing.B) {
+	b.Helper()
+	origGlobal := globalStorage
+	b.Cleanup(func() { globalStorage = origGlobal })
+	sm := &StorageManager{dataDir: b.TempDir()}
+	globalStorage = sm
+	start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	feeds := make([]models.FeedEntry, benchFeeds)
+	for i := range feeds {
+		at := start.Add(time.Duration(i) * 8 * time.Hour)
+		feeds[i] = models.FeedEntry{
+			ID: i + 1, UID: models.NewUID(), Version: 1,
+			Date: at.Format(time.DateOnly), Time: models.FlexTime{Time: at},
+			Type: models.FeedTypeBottle, Quantity: 90, LoggedBy: []string{"Asha", "Ravi"}[i%2],
+		}
+	}
+	if err := saveJSON(sm, legacyEntryFiles[models.ResourceFeeds], feeds); err != nil {
+		b.Fatalf("saveJSON failed: %v", err)
+	}
+	if _, err := sm.partitionEntries(); err != nil {
+		b.Fatalf("partitionEntries failed: %v", err)
+	}
+}
+
+// loadPage is the list path before streaming: load everything, filter, reverse, page.
+func loadPage(q models.EntryQuery) ([]models.FeedEntry, int, error) {
+	feeds, err := LoadFeeds()
+	if err != nil {
+		return nil, 0, err
+	}
+	matched := feeds[:0]
+	for _, f := range feeds {
+		if q.Matches(f.Date, f.LoggedBy) {
+			matched = append(matched, f)
+		}
+	}
+	for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+		matched[i], matched[j] = matched[j], matched[i]
+	}
+	total := len(matched)
+	if q.Offset >= total {
+		return nil, total, nil
+	}
+	matched = matched[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(matched) {
+		matched = matched[:q.Limit]
+	}
+	return matched, total, nil
+}
+
+func BenchmarkListFeeds(b *testing.B) {
+	queries := []struct {
+		name string
+		q    models.EntryQuery
+	}{
+		{"first page", models.EntryQuery{Limit: 10}}, // From the index, reading the head of one month
+		{"date range", models.EntryQuery{From: "2025-01-01", To: "2025-01-31", Limit: 10}},
+		{"caregiver", models.EntryQuery{LoggedBy: "Ravi", Limit: 10}}, // Scans every month
+	}
+	setupBenchStorage(b)
+	for _, tt := range queries {
+		b.Run(tt.name+"/load", func(b *testing.B) {
+			for b.Loop() {
+				if _, _, err := loadPage(tt.q); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(tt.name+"/stream", func(b *testing.B) {
+			for b.Loop() {
+				if _, _, err := QueryEntries[models.FeedEntry](models.ResourceFeeds, tt.q); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetFeed(b *testing.B) {
+	setupBenchStorage(b)
+	// A recent entry, near the head of its month as most lookups are
+	const id = benchFeeds - 5
+	b.Run("load", func(b *testing.B) {
+		for b.Loop() {
+			feeds, err := LoadFeeds()
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, f := range feeds {
+				if f.ID == id {
+					break
+				}
+			}
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		for b.Loop() {
+			if _, found, err := FindEntry[models.FeedEntry](models.ResourceFeeds, id); err != nil || !found {
+				b.Fatal(found, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+// Errors returned by RestoreEntry.
+var (
+	ErrNotInTrash      = errors.New("entry is not in the trash")
+	ErrRestoreConflict = errors.New("an entry with this ID already exists")
+)
+
+// trashFileName is the trash of a resource, e.g. feeds.trash.json.
+func trashFileName(resource string) string {
+	return resource + ".trash.json"
+}
+
+// moveToTrash keeps a copy of an entry that is about to be deleted.
+// The caller holds sm.mu and removes the entry from its data file afterwards,
+// so a crash in between leaves a duplicate rather than losing the entry.
+func (sm *StorageManager) moveToTrash(ctx context.Context, resource string, id int, entry any) error {
+	trash, err := loadJSON[models.TrashItem](sm, trashFileName(resource))
+	if err != nil {
+		return fmt.Errorf("refusing to delete with an unreadable trash: %w", err)
+	}
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trashed entry: %w", err)
+	}
+	trash = append(trash, models.TrashItem{
+		Resource:  resource,
+		ID:        id,
+		DeletedAt: time.Now().UTC(),
+		DeletedBy: ActorFrom(ctx).Name,
+		Entry:     raw,
+	})
+	return saveJSON(sm, trashFileName(resource), trash)
+}
+
+// LoadTrash returns the trashed entries of a resource, oldest deletion first.
+func LoadTrash(resource string) ([]models.TrashItem, error) {
+	sm, err := getStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return loadJSON[models.TrashItem](sm, trashFileName(resource))
+}
+
+// RestoreEntry moves a trashed entry back into its resource's data file.
+func RestoreEntry(ctx context.Context, resource string, id int) error {
+	sm, err := getStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.keyed(ctx, models.AuditRestore, resource, func() (int, error) {
+		switch resource {
+		case models.ResourceFeeds:
+			return id, restoreEntry(sm, ctx, resource, id, feedKey)
+		case models.ResourceSleep:
+			return id, restoreEntry(sm, ctx, resource, id, sleepKey)
+		case models.ResourceGrowth:
+			return id, restoreEntry(sm, ctx, resource, id, growthKey)
+		case models.ResourceDiapers:
+			return id, restoreEntry(sm, ctx, resource, id, diaperKey)
+		}
+		return id, fmt.Errorf("unknown resource %q", resource)
+	})
+}
+
+// restoreEntry puts a trashed entry back in the month of its date, at its
+// place in ID (logging) order, then drops it from the trash. The caller holds sm.mu.
+func restoreEntry[T any](sm *StorageManager, ctx context.Context, resource string, id int, keyOf func(T) (int, string)) error {
+	trash, err := loadJSON[models.TrashItem](sm, trashFileName(resource))
+	if err != nil {
+		return fmt.Errorf("refusing to restore from unreadable trash: %w", err)
+	}
+	k := slices.IndexFunc(trash, func(t models.TrashItem) bool { return t.ID == id })
+	if k < 0 {
+		return fmt.Errorf("%w: %s %d", ErrNotInTrash, resource, id)
+	}
+	var entry T
+	if err := json.Unmarshal(trash[k].Entry, &entry); err != nil {
+		return fmt.Errorf("failed to parse trashed entry: %w", err)
+	}
+	idx, _, i, err := locateEntry(sm, resource, id, keyOf)
+	if err != nil {
+		return fmt.Errorf("refusing to restore into unreadable data file: %w", err)
+	}
+	if i >= 0 {
+		return fmt.Errorf("%w: %s %d", ErrRestoreConflict, resource, id)
+	}
+	// Data file first: a crash before the trash is saved leaves a duplicate, not a loss
+	if err := insertEntry(sm, resource, idx, entry, keyOf); err != nil {
+		return err
+	}
+	trash = slices.Delete(trash, k, k+1)
+	if err := saveJSON(sm, trashFileName(resource), trash); err != nil {
+		return err
+	}
+	return sm.record(ctx, models.AuditRestore, resource, id, nil, entry)
+}
+
+// PurgeTrash permanently removes entries that have been in the trash for longer
+// than retention, returning how many were removed. A zero retention keeps everything.
+func PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	sm, err := getStorage()
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	cutoff := time.Now().Add(-retention)
+	purged := 0
+	for _, resource := range models.AllResources {
+		trash, err := loadJSON[models.TrashItem](sm, trashFileName(resource))
+		if err != nil {
+			return purged, fmt.Errorf("refusing to purge unreadable trash: %w", err)
+		}
+		kept := make([]models.TrashItem, 0, len(trash))
+		var expired []models.TrashItem
+		for _, t := range trash {
+			if t.DeletedAt.Before(cutoff) {
+				expired = append(expired, t)
+			} else {
+				kept = append(kept, t)
+			}
+		}
+		if len(expired) == 0 {
+			continue
+		}
+		if err := saveJSON(sm, trashFileName(resource), kept); err != nil {
+			return purged, err
+		}
+		// After the trash, so a crash in between leaves a UID that can't be reused
+		// rather than a trashed entry whose UID can
+		idx, err := sm.loadIndex(resource)
+		if err != nil {
+			return purged, err
+		}
+		for _, t := range expired {
+			if uid := rawUID(t.Entry); idx.UIDs[uid] == t.ID {
+				delete(idx.UIDs, uid)
+			}
+		}
+		if err := sm.saveIndex(resource, idx); err != nil {
+			return purged, err
+		}
+		for _, t := range expired {
+			if err := sm.record(ctx, models.AuditPurge, resource, t.ID, t.Entry, nil); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+func TestDeleteMovesToTrashAndRestores(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+	ctx := WithActor(context.Background(), Actor{Name: "Asha"})
+
+	for _, notes := range []string{"first", "second", "third"} {
+		if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Notes: notes}); err != nil {
+			t.Fatalf("SaveFeed failed: %v", err)
+		}
+	}
+	if err := DeleteFeed(ctx, 2, 0); err != nil {
+		t.Fatalf("DeleteFeed failed: %v", err)
+	}
+	if err := DeleteFeed(ctx, 3, 0); err != nil {
+		t.Fatalf("DeleteFeed failed: %v", err)
+	}
+	trash, err := LoadTrash(models.ResourceFeeds)
+	if err != nil {
+		t.Fatalf("LoadTrash failed: %v", err)
+	}
+	if len(trash) != 2 || trash[0].ID != 2 || trash[0].DeletedBy != "Asha" {
+		t.Fatalf("unexpected trash: %+v", trash)
+	}
+
+	// Trashed IDs are reserved so they can be restored
+	next := &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle}
+	if err := SaveFeed(ctx, next); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	if next.ID != 4 {
+		t.Errorf("new feed got ID %d, want 4 (2 and 3 are in the trash)", next.ID)
+	}
+
+	if err := RestoreEntry(ctx, models.ResourceFeeds, 2); err != nil {
+		t.Fatalf("RestoreEntry failed: %v", err)
+	}
+	feeds, err := LoadFeeds()
+	if err != nil {
+		t.Fatalf("LoadFeeds failed: %v", err)
+	}
+	var ids []int
+	for _, f := range feeds {
+		ids = append(ids, f.ID)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 4 || feeds[1].Notes != "second" {
+		t.Errorf("restored feeds = %v, want IDs [1 2 4] with #2 back in place", ids)
+	}
+	if err := RestoreEntry(ctx, models.ResourceFeeds, 2); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("second restore = %v, want ErrNotInTrash", err)
+	}
+
+	restores, err := LoadAudit(models.AuditFilter{Action: models.AuditRestore})
+	if err != nil || len(restores) != 1 {
+		t.Errorf("LoadAudit(restore) = %d entries, %v; want 1", len(restores), err)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+	ctx := context.Background()
+
+	if err := SaveDiaper(ctx, &models.DiaperEntry{Date: "2026-04-06", Type: models.DiaperTypeWet}); err != nil {
+		t.Fatalf("SaveDiaper failed: %v", err)
+	}
+	if err := DeleteDiaper(ctx, 1, 0); err != nil {
+		t.Fatalf("DeleteDiaper failed: %v", err)
+	}
+	if n, err := PurgeTrash(ctx, 0); err != nil || n != 0 {
+		t.Errorf("PurgeTrash(0) = %d, %v; want 0 (keep forever)", n, err)
+	}
+	if n, err := PurgeTrash(ctx, time.Hour); err != nil || n != 0 {
+		t.Errorf("PurgeTrash(1h) = %d, %v; want 0 for a fresh deletion", n, err)
+	}
+	time.Sleep(time.Millisecond)
+	if n, err := PurgeTrash(ctx, time.Nanosecond); err != nil || n != 1 {
+		t.Errorf("PurgeTrash(1ns) = %d, %v; want 1", n, err)
+	}
+	if trash, _ := LoadTrash(models.ResourceDiapers); len(trash) != 0 {
+		t.Errorf("expected an empty trash after purge, got %+v", trash)
+	}
+
+	// The purged ID is never given out again, even by an index rebuilt from
+	// the audit log
+	entry := &models.DiaperEntry{Date: "2026-04-06", Type: models.DiaperTypeDirty}
+	if err := SaveDiaper(ctx, entry); err != nil || entry.ID != 2 {
+		t.Errorf("SaveDiaper after purge gave ID %d, %v; want 2", entry.ID, err)
+	}
+	if err := os.Remove(filepath.Join(sm.dataDir, "diapers", "index.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteDiaper(ctx, 2, 0); err != nil {
+		t.Fatalf("DeleteDiaper failed: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if n, err := PurgeTrash(ctx, time.Nanosecond); err != nil || n != 1 {
+		t.Fatalf("PurgeTrash(1ns) = %d, %v; want 1", n, err)
+	}
+	if err := os.Remove(filepath.Join(sm.dataDir, "diapers", "index.json")); err != nil {
+		t.Fatal(err)
+	}
+	entry = &models.DiaperEntry{Date: "2026-04-06", Type: models.DiaperTypeWet}
+	if err := SaveDiaper(ctx, entry); err != nil || entry.ID != 3 {
+		t.Errorf("SaveDiaper with a rebuilt index gave ID %d, %v; want 3", entry.ID, err)
+	}
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+// Errors returned when saving an entry that brings its own UID.
+var (
+	ErrInvalidUID   = errors.New("uid is not a valid ULID")
+	ErrDuplicateUID = errors.New("an entry with this uid already exists")
+)
+
+// assignUID gives a new entry, whose ID is id, its UID: a fresh one when it has
+// none, or the one it brought (from an import or an offline client) if that is
+// valid and unused. Either way the UID is added to the index, which the
+// caller saves with the entry.
+func (idx *partitionIndex) assignUID(uid *string, id int) error {
+	if *uid == "" {
+		*uid = models.NewUID()
+	} else {
+		normalized, ok := models.NormalizeUID(*uid)
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrInvalidUID, *uid)
+		}
+		if _, taken := idx.UIDs[normalized]; taken {
+			return fmt.Errorf("%w: %s", ErrDuplicateUID, normalized)
+		}
+		*uid = normalized
+	}
+	idx.UIDs[*uid] = id
+	return nil
+}
+
+// ResolveUID returns the ID of the entry with the given UID, which may be in the trash.
+func ResolveUID(resource, uid string) (int, bool, error) {
+	if !isEntryResource(resource) {
+		return 0, false, fmt.Errorf("unknown resource %q", resource)
+	}
+	sm, err := getStorage()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	normalized, ok := models.NormalizeUID(uid)
+	if !ok {
+		return 0, false, nil
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	idx, err := sm.loadIndex(resource)
+	if err != nil {
+		return 0, false, err
+	}
+	id, ok := idx.UIDs[normalized]
+	return id, ok, nil
+}
+
+// backfillUIDs is schema migration 1. It gives every entry logged before UIDs
+// existed, trashed ones included, a UID stamped with when the entry happened,
+// so old and new entries sort together, and returns how many it changed.
+// No audit records are written: the entries' contents are unchanged. The caller holds sm.mu.
+func (sm *StorageManager) backfillUIDs() (int, error) {
+	total, err := backfillResourceUIDs(sm, models.ResourceFeeds, func(e *models.FeedEntry) (*string, time.Time) {
+		return &e.UID, entryTime(e.Time, e.Date)
+	})
+	if err == nil {
+		var n int
+		n, err = backfillResourceUIDs(sm, models.ResourceSleep, func(e *models.SleepEntry) (*string, time.Time) {
+			return &e.UID, entryTime(e.StartTime, e.Date)
+		})
+		total += n
+	}
+	if err == nil {
+		var n int
+		n, err = backfillResourceUIDs(sm, models.ResourceGrowth, func(e *models.GrowthEntry) (*string, time.Time) {
+			return &e.UID, entryTime(models.FlexTime{}, e.Date)
+		})
+		total += n
+	}
+	if err == nil {
+		var n int
+		n, err = backfillResourceUIDs(sm, models.ResourceDiapers, func(e *models.DiaperEntry) (*string, time.Time) {
+			return &e.UID, entryTime(e.Time, e.Date)
+		})
+		total += n
+	}
+	return total, err
+}
+
+// backfillResourceUIDs fills in missing UIDs in one resource's data file (the
+// single file of schema 1) and trash. uidOf returns the entry's UID field and
+// when the entry happened. The caller holds sm.mu.
+func backfillResourceUIDs[T any](sm *StorageManager, resource string, uidOf func(*T) (*string, time.Time)) (int, error) {
+	filename := legacyEntryFiles[resource]
+	entries, err := loadJSON[T](sm, filename)
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for i := range entries {
+		if uid, at := uidOf(&entries[i]); *uid == "" {
+			*uid = models.NewUIDAt(at)
+			changed++
+		}
+	}
+	if changed > 0 {
+		if err := saveJSON(sm, filename, entries); err != nil {
+			return 0, err
+		}
+	}
+
+	trash, err := loadJSON[models.TrashItem](sm, trashFileName(resource))
+	if err != nil {
+		return changed, err
+	}
+	trashChanged := 0
+	for i := range trash {
+		var entry T
+		if err := json.Unmarshal(trash[i].Entry, &entry); err != nil {
+			return changed, fmt.Errorf("failed to parse %s: %w", trashFileName(resource), err)
+		}
+		uid, at := uidOf(&entry)
+		if *uid != "" {
+			continue
+		}
+		*uid = models.NewUIDAt(at)
+		raw, err := json.Marshal(entry)
+		if err != nil {
+			return changed, fmt.Errorf("failed to marshal trashed entry: %w", err)
+		}
+		trash[i].Entry = raw
+		trashChanged++
+	}
+	if trashChanged > 0 {
+		if err := saveJSON(sm, trashFileName(resource), trash); err != nil {
+			return changed, err
+		}
+	}
+	return changed + trashChanged, nil
+}
+
+// entryTime is when an entry happened: its time if set, else the start of its date.
+func entryTime(t models.FlexTime, date string) time.Time {
+	if !t.IsZero() {
+		return t.Time
+	}
+	if start, _, err := models.DayBounds(date); err == nil {
+		return start
+	}
+	return time.Now()
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"babytracker/internal/models"
+)
+
+func TestEntryUIDs(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+	ctx := context.Background()
+
+	feed := &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Quantity: 90}
+	if err := SaveFeed(ctx, feed); err != nil {
+		t.Fatalf("SaveFeed failed: %v", err)
+	}
+	if _, ok := models.NormalizeUID(feed.UID); !ok {
+		t.Fatalf("expected SaveFeed to assign a UID, got %q", feed.UID)
+	}
+	if err := UpdateFeed(ctx, 1, 0, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, Quantity: 120, UID: models.NewUID()}); err != nil {
+		t.Fatalf("UpdateFeed failed: %v", err)
+	}
+	if feeds, _ := LoadFeeds(); feeds[0].UID != feed.UID {
+		t.Errorf("update changed the UID from %q to %q", feed.UID, feeds[0].UID)
+	}
+
+	// A UID brought by the client is kept if it is free, and stays taken while trashed
+	if err := DeleteFeed(ctx, 1, 0); err != nil {
+		t.Fatalf("DeleteFeed failed: %v", err)
+	}
+	if id, ok, err := ResolveUID(models.ResourceFeeds, feed.UID); err != nil || !ok || id != 1 {
+		t.Errorf("ResolveUID(trashed) = %d, %v, %v; want 1", id, ok, err)
+	}
+	err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, UID: feed.UID})
+	if !errors.Is(err, ErrDuplicateUID) {
+		t.Errorf("reusing a trashed UID: expected ErrDuplicateUID, got %v", err)
+	}
+	if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, UID: "not-a-ulid"}); !errors.Is(err, ErrInvalidUID) {
+		t.Errorf("expected ErrInvalidUID, got %v", err)
+	}
+
+	// Purging frees it
+	time.Sleep(time.Millisecond)
+	if n, err := PurgeTrash(ctx, time.Nanosecond); err != nil || n != 1 {
+		t.Fatalf("PurgeTrash = %d, %v; want 1", n, err)
+	}
+	if _, ok, err := ResolveUID(models.ResourceFeeds, feed.UID); err != nil || ok {
+		t.Errorf("ResolveUID(purged) = %v, %v; want not found", ok, err)
+	}
+	if err := SaveFeed(ctx, &models.FeedEntry{Date: "2026-04-06", Type: models.FeedTypeBottle, UID: feed.UID}); err != nil {
+		t.Errorf("reusing a purged UID: %v", err)
+	}
+}
+
+func TestBackfillUIDs(t *testing.T) {
+	sm := setupTestStorage(t)
+	origGlobal := globalStorage
+	globalStorage = sm
+	defer func() { globalStorage = origGlobal }()
+
+	// Entries written before UIDs existed, in the single files of schema 0
+	if err := saveJSON(sm, "sleep.json", []models.SleepEntry{
+		{ID: 1, Date: "2026-04-05", Type: models.SleepTypeNight},
+		{ID: 2, Date: "2026-04-06", Type: models.SleepTypeNap},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveJSON(sm, trashFileName(models.ResourceGrowth), []models.TrashItem{{
+		Resource: models.ResourceGrowth,
+		ID:       1,
+		Entry:    json.RawMessage(`{"id": 1, "date": "2026-04-01", "weight": 4.2}`),
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := sm.backfillUIDs()
+	if err != nil || n != 3 {
+		t.Fatalf("backfillUIDs() = %d, %v; want 3", n, err)
+	}
+	sleep, _ := loadJSON[models.SleepEntry](sm, "sleep.json")
+	if sleep[0].UID == "" || sleep[0].UID >= sleep[1].UID {
+		t.Errorf("expected UIDs ordered by date, got %q and %q", sleep[0].UID, sleep[1].UID)
+	}
+	trash, _ := LoadTrash(models.ResourceGrowth)
+	if id, ok, _ := ResolveUID(models.ResourceGrowth, trashedUID(t, trash[0])); !ok || id != 1 {
+		t.Errorf("expected the trashed growth entry to get a UID, got %s", trash[0].Entry)
+	}
+	if n, err := sm.backfillUIDs(); err != nil || n != 0 {
+		t.Errorf("second backfillUIDs() = %d, %v; want 0", n, err)
+	}
+}
+
+func trashedUID(t *testing.T, item models.TrashItem) string {
+	t.Helper()
+	var e struct {
+		UID string `json:"uid"`
+	}
+	if err := json.Unmarshal(item.Entry, &e); err != nil {
+		t.Fatal(err)
+	}
+	return e.UID
+}